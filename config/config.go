@@ -92,6 +92,7 @@ type ElasticSearch struct {
 	EnableSearchAfter  bool     `json:"enableSearchAfter"`
 	TrackTotalHits     bool     `json:"trackTotalHits"`
 	IndexTypes         []string
+	SearchIndices      []string `json:"searchIndices"` // additional indices that can be searched
 }
 
 // FragmentIndexName returns the name of the Fragment index.
@@ -99,6 +100,22 @@ func (es *ElasticSearch) FragmentIndexName() string {
 	return fmt.Sprintf("%s_frag", es.GetIndexName())
 }
 
+// IsSearchIndex returns if the index can be searched. The default index can
+// always be searched, other indices must be present in SearchIndices.
+func (es *ElasticSearch) IsSearchIndex(index string) bool {
+	if index == es.GetIndexName() {
+		return true
+	}
+
+	for _, allowed := range es.SearchIndices {
+		if strings.EqualFold(allowed, index) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // HasAuthentication returns if ElasticSearch has authentication enabled.
 func (es *ElasticSearch) HasAuthentication() bool {
 	return len(es.UserName) > 0 && len(es.Password) > 0
@@ -0,0 +1,115 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead
+
+// EADStats summarizes the structure of the clevels in a Cdsc.
+type EADStats struct {
+	LevelStats
+	// MaxDepth is the depth of the deepest clevel. The top-level clevels have depth 1.
+	MaxDepth int `json:"maxDepth"`
+	// Levels contains the LevelStats per Attrlevel. Clevels without a level attribute are counted as "".
+	Levels map[string]*LevelStats `json:"levels"`
+}
+
+// LevelStats contains the counts for a group of clevels.
+type LevelStats struct {
+	Nodes                  int `json:"nodes"`
+	MissingInventoryNumber int `json:"missingInventoryNumber"`
+	WithScopeContent       int `json:"withScopeContent"`
+	// MissingDid counts the clevels without a did element. These clevels cannot be converted to a Node.
+	MissingDid int `json:"missingDid"`
+}
+
+func (ls *LevelStats) add(c *Cc) error {
+	ls.Nodes++
+
+	if len(c.Cscopecontent) != 0 {
+		ls.WithScopeContent++
+	}
+
+	if len(c.Cdid) == 0 {
+		ls.MissingDid++
+		ls.MissingInventoryNumber++
+
+		return nil
+	}
+
+	_, inventoryID, err := c.GetCdid().NewNodeIDs()
+	if err != nil {
+		return err
+	}
+
+	if inventoryID == "" {
+		ls.MissingInventoryNumber++
+	}
+
+	return nil
+}
+
+// Analyze walks the clevels of the Cdsc and reports EADStats without converting
+// them to Nodes. It can be used as a dry run before calling NewNodeList.
+func (dsc *Cdsc) Analyze() (*EADStats, error) {
+	stats := &EADStats{
+		Levels: map[string]*LevelStats{},
+	}
+
+	if dsc == nil {
+		return stats, nil
+	}
+
+	for _, cc := range dsc.Numbered {
+		if err := stats.walk(cc, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, cc := range dsc.Cc {
+		if err := stats.walk(cc, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+func (stats *EADStats) walk(cl CLevel, depth int) error {
+	c := cl.GetCc()
+
+	if depth > stats.MaxDepth {
+		stats.MaxDepth = depth
+	}
+
+	level, ok := stats.Levels[c.Attrlevel]
+	if !ok {
+		level = &LevelStats{}
+		stats.Levels[c.Attrlevel] = level
+	}
+
+	if err := stats.add(c); err != nil {
+		return err
+	}
+
+	if err := level.add(c); err != nil {
+		return err
+	}
+
+	for _, nested := range cl.GetNested() {
+		if err := stats.walk(nested, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
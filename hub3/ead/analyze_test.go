@@ -0,0 +1,88 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead_test
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/delving/hub3/hub3/ead"
+)
+
+const analyzeDsc = `<dsc type="combined">
+  <c01 level="series">
+    <did><unitid type="series_code">A</unitid><unittitle>Series</unittitle></did>
+    <scopecontent><p>series scope</p></scopecontent>
+    <c02 level="file">
+      <did><unitid>1</unitid><unittitle>File 1</unittitle></did>
+      <c03 level="item">
+        <did><unittitle>Item without number</unittitle></did>
+        <scopecontent><p>item scope</p></scopecontent>
+      </c03>
+    </c02>
+    <c02 level="file">
+      <did><unittitle>File without number</unittitle></did>
+    </c02>
+  </c01>
+  <c01 level="series">
+    <scopecontent><p>series without did</p></scopecontent>
+  </c01>
+</dsc>`
+
+func TestCdsc_Analyze(t *testing.T) {
+	dsc := new(Cdsc)
+	if err := xml.Unmarshal([]byte(analyzeDsc), dsc); err != nil {
+		t.Fatalf("unable to parse dsc; %s", err)
+	}
+
+	got, err := dsc.Analyze()
+	if err != nil {
+		t.Fatalf("Cdsc.Analyze() unexpected error = %v", err)
+	}
+
+	want := &EADStats{
+		LevelStats: LevelStats{
+			Nodes:                  5,
+			MissingInventoryNumber: 3,
+			WithScopeContent:       3,
+			MissingDid:             1,
+		},
+		MaxDepth: 3,
+		Levels: map[string]*LevelStats{
+			"series": {Nodes: 2, MissingInventoryNumber: 1, WithScopeContent: 2, MissingDid: 1},
+			"file":   {Nodes: 2, MissingInventoryNumber: 1},
+			"item":   {Nodes: 1, MissingInventoryNumber: 1, WithScopeContent: 1},
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Cdsc.Analyze() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCdsc_Analyze_nil(t *testing.T) {
+	var dsc *Cdsc
+
+	got, err := dsc.Analyze()
+	if err != nil {
+		t.Fatalf("Cdsc.Analyze() unexpected error = %v", err)
+	}
+
+	if got.Nodes != 0 || got.MaxDepth != 0 {
+		t.Errorf("Cdsc.Analyze() of nil dsc = %+v, want empty stats", got)
+	}
+}
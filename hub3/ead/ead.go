@@ -78,6 +78,13 @@ type NodeConfig struct {
 	ProcessDigital          bool
 	m                       sync.Mutex
 	Tags                    []string
+	sourceRanges            []sourceRange
+	nodeIDs                 map[string]string
+	// RawText disables the whitespace normalization of the labels, date
+	// labels and inventory number of the Header. See Cdid.NewHeader.
+	RawText bool
+	// AddParentLabels sets the tree label of each ancestor on Node.ParentLabels.
+	AddParentLabels bool
 }
 
 func (cfg *NodeConfig) Labels() map[string]string {
@@ -215,6 +222,10 @@ func (nc *NodeCounter) GetCount() uint64 {
 
 // NewNodeList converts the Archival Description Level to a Nodelist
 // Nodelist is an optimized lossless Protocol Buffer container.
+//
+// Finding aids without subordinate components have no dsc or an empty one.
+// Then an empty NodeList and a count of zero are returned, so NewNodeList is
+// safe to call on a nil Cdsc.
 func (dsc *Cdsc) NewNodeList(cfg *NodeConfig) (*NodeList, uint64, error) {
 	defer func() {
 		if cfg.Nodes != nil {
@@ -266,19 +277,66 @@ func (dsc *Cdsc) NewNodeList(cfg *NodeConfig) (*NodeList, uint64, error) {
 	return nl, cfg.Counter.GetCount(), nil
 }
 
-// Sparse creates a sparse version of Header
+// Sparse creates a sparse version of Header.
+// DateAsLabel and the Label are kept, so the sparse Header reports the same
+// DateAsLabel as the full Header. The dates in the title are already part of
+// the Label.
 func (h *Header) Sparse() {
-	if h.DateAsLabel {
-		h.DateAsLabel = false
-		for _, date := range h.Date {
-			h.Label = append(h.Label, date.Label)
-		}
-	}
 	h.Date = nil
+	h.Titles = nil
 	h.ID = nil
 	h.Physdesc = ""
 }
 
+// SparseOptions configures which fields are kept by Node.Sparse.
+type SparseOptions struct {
+	// KeepCTag keeps the clevel tag, e.g. 'c01' or 'c03', so clients can
+	// render the indentation of the Node.
+	KeepCTag bool
+	// KeepParentLabels keeps the ParentLabels, e.g. for rendering breadcrumbs.
+	KeepParentLabels bool
+}
+
+// Sparse creates a sparse version of the Node and its nested Nodes for
+// lightweight payloads. The Header is made sparse and the CTag, parent labels,
+// material, phystech and source range are removed.
+func (n *Node) Sparse(opts SparseOptions) {
+	_ = n.Walk(func(n *Node) error {
+		n.sparse(opts)
+		return nil
+	})
+}
+
+// Sparse creates a sparse version of all the Nodes in the NodeList.
+// See Node.Sparse.
+func (nl *NodeList) Sparse(opts SparseOptions) {
+	_ = nl.Walk(func(n *Node) error {
+		n.sparse(opts)
+		return nil
+	})
+}
+
+func (n *Node) sparse(opts SparseOptions) {
+	if n.Header != nil {
+		n.Header.Sparse()
+	}
+
+	if !opts.KeepCTag {
+		n.CTag = ""
+	}
+
+	if !opts.KeepParentLabels {
+		n.ParentLabels = nil
+	}
+
+	n.Material = ""
+	n.Phystech = nil
+	n.SourceOffset = 0
+	n.SourceLength = 0
+	n.triples = nil
+	n.prose = nil
+}
+
 // GetPeriods return a list of human readable periods from the EAD unitDate
 func (h *Header) GetPeriods() []string {
 	periods := []string{}
@@ -361,8 +419,50 @@ func (nd *NodeDate) ValidDateNormal() error {
 	return nil
 }
 
-// NewHeader creates an Archival Header
+// NewHeader creates an Archival Header.
+//
+// The whitespace in the labels, date labels and inventory number is
+// normalized, i.e. runs of whitespace, like the newlines of pretty-printed
+// XML, are collapsed to a single space and leading and trailing whitespace
+// is removed. Use NewRawHeader to keep the text as it is in the EAD.
 func (cdid *Cdid) NewHeader() (*Header, error) {
+	header, err := cdid.NewRawHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	header.normalizeSpace()
+
+	return header, nil
+}
+
+// normalizeSpace normalizes the whitespace in the labels, date labels and
+// inventory number of the Header.
+func (h *Header) normalizeSpace() {
+	h.InventoryNumber = normalizeSpace(h.InventoryNumber)
+
+	for i, label := range h.Label {
+		h.Label[i] = normalizeSpace(label)
+	}
+
+	for _, title := range h.Titles {
+		title.Label = normalizeSpace(title.Label)
+	}
+
+	// the dates of the Titles are also part of Date
+	for _, date := range h.Date {
+		date.Label = normalizeSpace(date.Label)
+	}
+}
+
+// normalizeSpace collapses all runs of whitespace in s to a single space and
+// removes the leading and trailing whitespace.
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// NewRawHeader creates an Archival Header without normalizing the whitespace.
+func (cdid *Cdid) NewRawHeader() (*Header, error) {
 	header := &Header{
 		Genreform: config.Config.EAD.GenreFormDefault,
 	}
@@ -377,8 +477,7 @@ func (cdid *Cdid) NewHeader() (*Header, error) {
 	}
 
 	for _, label := range cdid.Cunittitle {
-		// todo interpolation of date and title is not correct at the moment.
-		dates := []string{}
+		title := &TitleWithDates{Label: label.Title()}
 
 		if len(label.Cunitdate) != 0 {
 			header.DateAsLabel = true
@@ -390,11 +489,12 @@ func (cdid *Cdid) NewHeader() (*Header, error) {
 				}
 
 				header.Date = append(header.Date, nodeDate)
-				dates = append(dates, nodeDate.Label)
+				title.Date = append(title.Date, nodeDate)
 			}
 		}
 
-		header.Label = append(header.Label, label.Title())
+		header.Label = append(header.Label, title.Label)
+		header.Titles = append(header.Titles, title)
 	}
 
 	for _, date := range cdid.Cunitdate {
@@ -447,6 +547,19 @@ func (n *Node) getPathID() string {
 	return fmt.Sprintf("%s", eadID)
 }
 
+// createID returns a stable identifier for the Node that does not depend on its
+// own inventory number. It hashes the spec of the finding aid, the path of the
+// parent, which is made up of the ancestor inventory numbers, and the Order of
+// the Node, so the identifiers are unique between finding aids.
+func (n *Node) createID(spec string, parentIDs []string) string {
+	branch := ""
+	if len(parentIDs) > 0 {
+		branch = parentIDs[len(parentIDs)-1]
+	}
+
+	return fragments.CreateHash(fmt.Sprintf("%s%s%s%s%d", spec, pathSep, branch, pathSep, n.Order))
+}
+
 func (cfg *NodeConfig) UpdatePath(node *Node, parentIDs []string) ([]string, error) {
 	cfg.m.Lock()
 	defer cfg.m.Unlock()
@@ -468,6 +581,22 @@ func (cfg *NodeConfig) UpdatePath(node *Node, parentIDs []string) ([]string, err
 
 	cfg.labels[node.Path] = node.Header.GetTreeLabel()
 
+	node.ID = node.createID(cfg.Spec, parentIDs)
+
+	if cfg.nodeIDs == nil {
+		cfg.nodeIDs = make(map[string]string)
+	}
+
+	cfg.nodeIDs[node.Path] = node.ID
+
+	for _, parentID := range parentIDs {
+		node.ParentNodeIDs = append(node.ParentNodeIDs, cfg.nodeIDs[parentID])
+
+		if cfg.AddParentLabels {
+			node.ParentLabels = append(node.ParentLabels, cfg.labels[parentID])
+		}
+	}
+
 	ids := append(parentIDs, node.Path)
 
 	return ids, nil
@@ -488,7 +617,14 @@ func NewNode(cl CLevel, parentIDs []string, cfg *NodeConfig) (*Node, error) {
 		Order:     cfg.Counter.GetCount(),
 	}
 
-	header, err := c.GetCdid().NewHeader()
+	cfg.setSourceRange(node)
+
+	newHeader := c.GetCdid().NewHeader
+	if cfg.RawText {
+		newHeader = c.GetCdid().NewRawHeader
+	}
+
+	header, err := newHeader()
 	if err != nil {
 		return nil, err
 	}
@@ -528,6 +664,14 @@ func NewNode(cl CLevel, parentIDs []string, cfg *NodeConfig) (*Node, error) {
 		node.Phystech = append(node.Phystech, sanitizeXMLAsString(p.Raw))
 	}
 
+	for _, sc := range c.GetScopeContent() {
+		node.prose = append(node.prose, sc.Raw)
+	}
+
+	for _, bh := range c.GetBioghist() {
+		node.prose = append(node.prose, bh.Raw)
+	}
+
 	// check valid date
 	for _, d := range node.Header.Date {
 		if validErr := d.ValidDateNormal(); validErr != nil {
@@ -24,6 +24,7 @@ import (
 	"testing"
 
 	. "github.com/delving/hub3/hub3/ead"
+	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -473,3 +474,165 @@ func TestNodeDate_ValidDateNormal(t *testing.T) {
 		})
 	}
 }
+
+func TestHeader_Sparse_dateAsLabel(t *testing.T) {
+	tests := []struct {
+		name            string
+		fname           string
+		wantDateAsLabel bool
+	}{
+		{"date in unittitle", "ead.diddate.xml", true},
+		{"date next to unittitle", "ead.diddate2.xml", false},
+		{"without date", "ead.did.xml", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			did := new(Cdid)
+			if err := parseUtil(did, tt.fname); err != nil {
+				t.Fatalf("parseUtil() unexpected error; %s", err)
+			}
+
+			full, err := did.NewHeader()
+			if err != nil {
+				t.Fatalf("NewHeader() unexpected error; %s", err)
+			}
+
+			sparse, err := did.NewHeader()
+			if err != nil {
+				t.Fatalf("NewHeader() unexpected error; %s", err)
+			}
+
+			sparse.Sparse()
+
+			if full.DateAsLabel != tt.wantDateAsLabel {
+				t.Errorf("full DateAsLabel = %t, want %t", full.DateAsLabel, tt.wantDateAsLabel)
+			}
+
+			if sparse.DateAsLabel != full.DateAsLabel {
+				t.Errorf("sparse DateAsLabel = %t, want %t", sparse.DateAsLabel, full.DateAsLabel)
+			}
+
+			if diff := cmp.Diff(full.Label, sparse.Label); diff != "" {
+				t.Errorf("sparse Label mismatch (-want +got):\n%s", diff)
+			}
+
+			if sparse.Date != nil {
+				t.Errorf("sparse Date = %v, want nil", sparse.Date)
+			}
+		})
+	}
+}
+
+func TestCdid_NewHeader_titles(t *testing.T) {
+	did := new(Cdid)
+	if err := parseUtil(did, "ead.didtitles.xml"); err != nil {
+		t.Fatalf("parseUtil() unexpected error; %s", err)
+	}
+
+	header, err := did.NewHeader()
+	if err != nil {
+		t.Fatalf("NewHeader() unexpected error; %s", err)
+	}
+
+	date := func(normal, label string) *NodeDate {
+		return &NodeDate{Calendar: "gregorian", Era: "ce", Normal: normal, Label: label}
+	}
+
+	want := []*TitleWithDates{
+		{
+			Label: "Verslagen van de commissie 1920-1925",
+			Date:  []*NodeDate{date("1920/1925", "1920-1925")},
+		},
+		{
+			Label: "Jaarverslagen 1921 en 1923",
+			Date:  []*NodeDate{date("1921", "1921"), date("1923", "1923")},
+		},
+	}
+
+	if diff := cmp.Diff(want, header.Titles); diff != "" {
+		t.Errorf("NewHeader() Titles mismatch (-want +got):\n%s", diff)
+	}
+
+	// the flat fields are kept for compatibility
+	wantLabel := []string{want[0].Label, want[1].Label}
+	if diff := cmp.Diff(wantLabel, header.Label); diff != "" {
+		t.Errorf("NewHeader() Label mismatch (-want +got):\n%s", diff)
+	}
+
+	wantDate := []*NodeDate{
+		want[0].Date[0], want[1].Date[0], want[1].Date[1],
+		date("1920/1930", "1920-1930"),
+	}
+	if diff := cmp.Diff(wantDate, header.Date); diff != "" {
+		t.Errorf("NewHeader() Date mismatch (-want +got):\n%s", diff)
+	}
+
+	if !header.DateAsLabel {
+		t.Error("NewHeader() DateAsLabel = false, want true")
+	}
+}
+
+func TestNodeConfig_RawText(t *testing.T) {
+	tests := []struct {
+		name          string
+		rawText       bool
+		wantLabel     string
+		wantDateLabel string
+		wantInventory string
+	}{
+		{
+			"normalized",
+			false,
+			"Stukken betreffende de aankoop van het pand 1901 - 1903",
+			"1901 - 1903",
+			"12",
+		},
+		{
+			"raw",
+			true,
+			"Stukken betreffende de aankoop\n                van   het pand 1901 -\n                1903",
+			"1901 -\n                1903",
+			"  12 ",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			dsc := new(Cdsc)
+			if err := parseUtil(dsc, "ead.whitespace.xml"); err != nil {
+				t.Fatalf("parseUtil() unexpected error; %s", err)
+			}
+
+			cfg := NewNodeConfig(context.Background())
+			cfg.RawText = tt.rawText
+
+			nl, _, err := dsc.NewNodeList(cfg)
+			if err != nil {
+				t.Fatalf("NewNodeList() unexpected error; %s", err)
+			}
+
+			header := nl.Nodes[0].Header
+
+			if diff := cmp.Diff([]string{tt.wantLabel}, header.Label); diff != "" {
+				t.Errorf("Label mismatch (-want +got):\n%s", diff)
+			}
+
+			if got := header.Titles[0].Label; got != tt.wantLabel {
+				t.Errorf("Titles[0].Label = %q, want %q", got, tt.wantLabel)
+			}
+
+			if got := header.Date[0].Label; got != tt.wantDateLabel {
+				t.Errorf("Date[0].Label = %q, want %q", got, tt.wantDateLabel)
+			}
+
+			if header.InventoryNumber != tt.wantInventory {
+				t.Errorf("InventoryNumber = %q, want %q", header.InventoryNumber, tt.wantInventory)
+			}
+		})
+	}
+}
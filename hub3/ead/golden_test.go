@@ -0,0 +1,120 @@
+// Copyright 2017 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/delving/hub3/hub3/ead"
+	"github.com/google/go-cmp/cmp"
+)
+
+// update regenerates the .golden files, e.g. 'go test ./hub3/ead -run TestGolden -update'
+var update = flag.Bool("update", false, "update the .golden files of TestGolden")
+
+// TestGolden converts each .xml fixture in testdata/golden and compares the
+// full and sparse NodeList with the checked-in .golden files.
+func TestGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "golden", "*.xml"))
+	if err != nil {
+		t.Fatalf("unable to list fixtures; %s", err)
+	}
+
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures found in testdata/golden")
+	}
+
+	modes := []struct {
+		name   string
+		sparse bool
+	}{
+		{"full", false},
+		{"sparse", true},
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		name := strings.TrimSuffix(filepath.Base(fixture), ".xml")
+
+		for _, mode := range modes {
+			mode := mode
+
+			t.Run(name+"/"+mode.name, func(t *testing.T) {
+				got := convertFixture(t, fixture, mode.sparse)
+
+				golden := strings.TrimSuffix(fixture, ".xml") + "." + mode.name + ".golden"
+
+				if *update {
+					if err := ioutil.WriteFile(golden, got, 0o600); err != nil {
+						t.Fatalf("unable to update %s; %s", golden, err)
+					}
+				}
+
+				want, err := ioutil.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("unable to read %s, run with -update to create it; %s", golden, err)
+				}
+
+				if diff := cmp.Diff(string(want), string(got)); diff != "" {
+					t.Errorf("%s mismatch, run with -update when the change is expected (-want +got):\n%s", golden, diff)
+				}
+			})
+		}
+	}
+}
+
+// convertFixture converts the dsc of the EAD fixture to a NodeList that is
+// encoded as indented JSON.
+func convertFixture(t *testing.T, fixture string, sparse bool) []byte {
+	t.Helper()
+
+	src, err := ioutil.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("unable to read fixture; %s", err)
+	}
+
+	doc := new(ead.Cead)
+	if err = xml.Unmarshal(src, doc); err != nil {
+		t.Fatalf("unable to parse fixture; %s", err)
+	}
+
+	nl, _, err := doc.Carchdesc.Cdsc.NewNodeList(ead.NewNodeConfig(context.Background()))
+	if err != nil {
+		t.Fatalf("unable to convert fixture; %s", err)
+	}
+
+	if sparse {
+		nl.Sparse(ead.SparseOptions{})
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(nl); err != nil {
+		t.Fatalf("unable to encode NodeList; %s", err)
+	}
+
+	return buf.Bytes()
+}
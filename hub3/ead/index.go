@@ -0,0 +1,151 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead
+
+import (
+	"context"
+	"fmt"
+
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// defaultIndexBatchSize is the number of Nodes that IndexNodes sends per bulk request.
+const defaultIndexBatchSize = 500
+
+// IndexNodesOption configures IndexNodes.
+type IndexNodesOption func(cfg *indexNodesConfig)
+
+type indexNodesConfig struct {
+	batchSize int
+	refresh   string
+}
+
+// WithBatchSize sets the number of Nodes that are sent in a single bulk request.
+// The default is 500. Values smaller than 1 are ignored.
+func WithBatchSize(size int) IndexNodesOption {
+	return func(cfg *indexNodesConfig) {
+		if size > 0 {
+			cfg.batchSize = size
+		}
+	}
+}
+
+// WithRefresh sets the refresh parameter of each bulk request, i.e. 'true',
+// 'false' or 'wait_for'. By default the index is not refreshed.
+func WithRefresh(refresh string) IndexNodesOption {
+	return func(cfg *indexNodesConfig) {
+		cfg.refresh = refresh
+	}
+}
+
+// NodeIndexError is a Node that could not be indexed.
+type NodeIndexError struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// BulkIndexError is returned by IndexNodes when some of the Nodes could not be
+// indexed. The other Nodes are indexed.
+type BulkIndexError struct {
+	Indexed int              `json:"indexed"`
+	Failed  []NodeIndexError `json:"failed"`
+}
+
+func (e *BulkIndexError) Error() string {
+	return fmt.Sprintf("unable to index %d of %d nodes", len(e.Failed), e.Indexed+len(e.Failed))
+}
+
+// IndexNodes indexes the Nodes of the finding aid with the spec in batches
+// with the ElasticSearch bulk API. Each Node is stored as a document with the
+// spec and its stable ID, or its Path when the ID is empty, as document ID, so
+// multiple finding aids can be stored in the same index. The nested Nodes are
+// not indexed, so use NodeList.Flatten to index the whole tree. The Nodes can
+// be reassembled with BuildTree.
+//
+// A *BulkIndexError is returned when some of the Nodes failed to index.
+// Other errors stop the indexing and are returned directly.
+func IndexNodes(ctx context.Context, client *elastic.Client, index, spec string, nodes []*Node, options ...IndexNodesOption) error {
+	if spec == "" {
+		return fmt.Errorf("spec is required to index nodes")
+	}
+
+	cfg := &indexNodesConfig{
+		batchSize: defaultIndexBatchSize,
+	}
+
+	for _, option := range options {
+		option(cfg)
+	}
+
+	bulkErr := &BulkIndexError{}
+
+	for start := 0; start < len(nodes); start += cfg.batchSize {
+		end := start + cfg.batchSize
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+
+		bulk := client.Bulk().Index(index)
+		if cfg.refresh != "" {
+			bulk = bulk.Refresh(cfg.refresh)
+		}
+
+		for _, n := range nodes[start:end] {
+			bulk.Add(elastic.NewBulkIndexRequest().Id(nodeDocID(spec, n)).Doc(indexedNode(n)))
+		}
+
+		res, err := bulk.Do(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to index nodes %d to %d; %w", start, end, err)
+		}
+
+		for _, item := range res.Items {
+			for _, result := range item {
+				if result.Error == nil {
+					bulkErr.Indexed++
+					continue
+				}
+
+				bulkErr.Failed = append(bulkErr.Failed, NodeIndexError{
+					ID:     result.Id,
+					Type:   result.Error.Type,
+					Reason: result.Error.Reason,
+				})
+			}
+		}
+	}
+
+	if len(bulkErr.Failed) != 0 {
+		return bulkErr
+	}
+
+	return nil
+}
+
+// nodeDocID returns the ElasticSearch document ID of the Node in the finding
+// aid with the spec.
+func nodeDocID(spec string, n *Node) string {
+	id, _ := n.treeIDs()
+	return fmt.Sprintf("%s_%s", spec, id)
+}
+
+// indexedNode returns a copy of the Node without its nested Nodes.
+func indexedNode(n *Node) *Node {
+	doc := *n
+	doc.Nodes = nil
+
+	return &doc
+}
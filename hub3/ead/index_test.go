@@ -0,0 +1,132 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/elastictest"
+	"github.com/matryer/is"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+func TestIndexNodes(t *testing.T) {
+	is := is.New(t)
+
+	client, requests, ids := newMockBulkClient(t, "NL-A-1_2")
+
+	nodes := []*Node{
+		{ID: "1", Order: 1, Nodes: []*Node{{ID: "2", Order: 2}}},
+		{ID: "2", Order: 2, ParentNodeIDs: []string{"1"}},
+		{Path: "3", Order: 3},
+	}
+
+	err := IndexNodes(context.Background(), client, "nodes", "NL-A-1", nodes, WithBatchSize(2), WithRefresh("wait_for"))
+
+	var bulkErr *BulkIndexError
+	is.True(errors.As(err, &bulkErr))
+	is.Equal(bulkErr.Indexed, 2)
+	is.Equal(bulkErr.Failed, []NodeIndexError{{ID: "NL-A-1_2", Type: "mapper_parsing_exception", Reason: "failed to parse"}})
+	is.Equal(err.Error(), "unable to index 1 of 3 nodes")
+
+	is.Equal(*requests, 2)
+	is.Equal(*ids, []string{"NL-A-1_1", "NL-A-1_2", "NL-A-1_3"})
+
+	// the nested nodes of the input are not changed
+	is.Equal(len(nodes[0].Nodes), 1)
+
+	err = IndexNodes(context.Background(), client, "nodes", "", nodes)
+	is.True(err != nil)
+	is.Equal(*requests, 2)
+}
+
+func TestIndexNodes_findingAids(t *testing.T) {
+	is := is.New(t)
+
+	client, _, ids := newMockBulkClient(t, "")
+
+	for _, spec := range []string{"NL-A-1", "NL-B-2"} {
+		cfg := NewNodeConfig(context.Background())
+		cfg.Spec = spec
+
+		nodes := []*Node{{Order: 1, Header: &Header{}}}
+		_, err := cfg.UpdatePath(nodes[0], nil)
+		is.NoErr(err)
+
+		is.NoErr(IndexNodes(context.Background(), client, "nodes", spec, nodes, WithRefresh("wait_for")))
+	}
+
+	is.Equal(len(*ids), 2)
+	is.True((*ids)[0] != (*ids)[1]) // the finding aids don't overwrite each other
+}
+
+// newMockBulkClient returns an elastic.Client for a bulk API that records the
+// document IDs and the number of requests. The document with failID fails.
+func newMockBulkClient(t *testing.T, failID string) (client *elastic.Client, requests *int, ids *[]string) {
+	t.Helper()
+
+	requests, ids = new(int), &[]string{}
+
+	client = elastictest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		if r.URL.Path != "/nodes/_bulk" || r.URL.Query().Get("refresh") != "wait_for" {
+			t.Errorf("unexpected bulk request %s", r.URL)
+		}
+
+		items := []string{}
+		scanner := bufio.NewScanner(r.Body)
+
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				t.Errorf("unable to decode bulk action; %s", err)
+			}
+
+			id := action["index"]["_id"]
+			*ids = append(*ids, id)
+
+			// skip the document
+			if !scanner.Scan() {
+				t.Errorf("bulk action %s has no document", id)
+			}
+
+			var doc Node
+			if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil || len(doc.Nodes) != 0 {
+				t.Errorf("nested nodes must not be indexed; %v", err)
+			}
+
+			status, failure := 201, ""
+			if id == failID {
+				status = 400
+				failure = `,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}`
+			}
+
+			items = append(items, fmt.Sprintf(`{"index":{"_index":"nodes","_id":%q,"status":%d%s}}`, id, status, failure))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	}))
+
+	return client, requests, ids
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead_test
+
+import (
+	"context"
+	"encoding/xml"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/delving/hub3/hub3/ead"
+)
+
+// blankInventoryDsc has clevels without an inventory number at every level.
+const blankInventoryDsc = `<dsc>
+  <c01 level="series">
+    <did><unittitle>Series</unittitle></did>
+    <c02 level="file">
+      <did><unittitle>File</unittitle></did>
+      <c03 level="item"><did><unittitle>Item 1</unittitle></did></c03>
+      <c03 level="item"><did><unittitle>Item 2</unittitle></did></c03>
+    </c02>
+    <c02 level="file">
+      <did><unitid>12</unitid><unittitle>Numbered file</unittitle></did>
+      <c03 level="item"><did><unittitle>Item 3</unittitle></did></c03>
+    </c02>
+  </c01>
+</dsc>`
+
+func nodeIDs(t *testing.T, spec string) (ids []string, parents map[string][]string) {
+	is := is.New(t)
+
+	dsc := new(ead.Cdsc)
+	is.NoErr(xml.Unmarshal([]byte(blankInventoryDsc), dsc))
+
+	cfg := ead.NewNodeConfig(context.Background())
+	cfg.Spec = spec
+
+	nl, _, err := dsc.NewNodeList(cfg)
+	is.NoErr(err)
+
+	parents = map[string][]string{}
+
+	var walk func(level []*ead.Node)
+	walk = func(level []*ead.Node) {
+		for _, n := range level {
+			ids = append(ids, n.ID)
+			parents[n.ID] = n.ParentNodeIDs
+			walk(n.Nodes)
+		}
+	}
+
+	walk(nl.Nodes)
+
+	return ids, parents
+}
+
+func TestNode_ID(t *testing.T) {
+	is := is.New(t)
+
+	ids, parents := nodeIDs(t, "NL-A-1")
+	is.Equal(len(ids), 6)
+
+	unique := map[string]bool{}
+
+	for _, id := range ids {
+		is.True(id != "")    // every node has an id
+		is.True(!unique[id]) // ids are unique
+		unique[id] = true
+
+		for _, parentID := range parents[id] {
+			is.True(parentID != "") // no empty segments in the parent chain
+		}
+	}
+
+	// the parent chain of "Item 1" is series > file
+	is.Equal(parents[ids[2]], []string{ids[0], ids[1]})
+
+	// the ids are stable between conversions
+	again, _ := nodeIDs(t, "NL-A-1")
+	is.Equal(ids, again)
+
+	// the ids are unique between finding aids
+	other, _ := nodeIDs(t, "NL-B-2")
+	for i := range ids {
+		is.True(ids[i] != other[i])
+	}
+}
@@ -15,7 +15,13 @@
 package ead
 
 import (
+	"bytes"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/delving/hub3/config"
@@ -25,6 +31,9 @@ import (
 
 const FragmentGraphDocType = "ead"
 
+// ErrOrphanedNode is returned by BuildTree when the parent of a Node is missing.
+var ErrOrphanedNode = errors.New("parent node not found")
+
 const CLevelLeader = "@"
 
 // Node holds all the clevel information.
@@ -44,7 +53,21 @@ type Node struct {
 	AccessRestrictYear string
 	Material           string
 	Phystech           []string
-	triples            []*r.Triple
+	// ID is a stable identifier that is unique within the finding aid, also
+	// when the Node or its ancestors have no inventory number.
+	ID string
+	// ParentNodeIDs contains the ID of each ancestor, starting with the top-level clevel.
+	ParentNodeIDs []string
+	// ParentLabels contains the tree label of each ancestor, starting with the
+	// top-level clevel. It is only set when NodeConfig.AddParentLabels is used.
+	ParentLabels []string
+	// SourceOffset and SourceLength are the byte range of the clevel in the
+	// source XML. They are only set when NodeConfig.TrackSourceOffsets is used.
+	SourceOffset int64
+	SourceLength int64
+	triples      []*r.Triple
+	// prose contains the raw scopecontent and bioghist of the clevel.
+	prose [][]byte
 }
 
 type NodeList struct {
@@ -54,19 +77,32 @@ type NodeList struct {
 }
 
 type Header struct {
-	Type             string
-	InventoryNumber  string
-	ID               []*NodeID
-	Label            []string
-	Date             []*NodeDate
-	Physdesc         string
-	Physloc          string
+	Type            string
+	InventoryNumber string
+	ID              []*NodeID
+	Label           []string
+	Date            []*NodeDate
+	Physdesc        string
+	Physloc         string
+	// DateAsLabel is true when the unittitle contains one or more unitdates.
+	// Their text is part of the Label, so it must not be rendered again. The
+	// dates are also stored in Date, except in the sparse Header.
 	DateAsLabel      bool
 	HasDigitalObject bool
 	DaoLink          string
 	AltRender        string
 	Genreform        string
 	Attridentifier   string
+	// Titles contains each unittitle in document order grouped with the
+	// unitdates that are nested in it. Label and Date contain the same
+	// information in flat form. Titles is removed from the sparse Header.
+	Titles []*TitleWithDates
+}
+
+// TitleWithDates is a unittitle with its inline unitdates.
+type TitleWithDates struct {
+	Label string
+	Date  []*NodeDate
 }
 type NodeDate struct {
 	Calendar string
@@ -87,6 +123,121 @@ func newSubject(cfg *NodeConfig, id string) string {
 		config.Config.RDF.BaseURL, config.Config.OrgID, cfg.Spec, id)
 }
 
+// Walk calls fn for the Node and then depth-first for each of its nested
+// Nodes. Siblings are visited in Order sequence. The walk stops at the first
+// error returned by fn and returns it.
+func (n *Node) Walk(fn func(n *Node) error) error {
+	if err := fn(n); err != nil {
+		return err
+	}
+
+	return walkNodes(n.Nodes, fn)
+}
+
+// Walk calls Node.Walk for each top-level Node in Order sequence.
+// The walk stops at the first error returned by fn and returns it.
+func (nl *NodeList) Walk(fn func(n *Node) error) error {
+	return walkNodes(nl.Nodes, fn)
+}
+
+// Flatten returns all the Nodes of the NodeList in the depth-first order of Walk.
+func (nl *NodeList) Flatten() []*Node {
+	nodes := []*Node{}
+
+	_ = nl.Walk(func(n *Node) error {
+		nodes = append(nodes, n)
+		return nil
+	})
+
+	return nodes
+}
+
+// BuildTree reassembles the nested NodeList from a flat slice of Nodes, e.g.
+// when they are read back from the index. It is the inverse of Flatten.
+//
+// The Nodes are linked by their ID and the last of their ParentNodeIDs. Nodes
+// without an ID are linked by their Path and the last of their ParentIDs.
+// Siblings are sorted by Order. The nested Nodes of the given Nodes are
+// replaced.
+//
+// An ErrOrphanedNode error is returned when the parent of a Node is not in
+// nodes.
+func BuildTree(nodes []*Node) (*NodeList, error) {
+	byID := make(map[string]*Node, len(nodes))
+
+	for _, n := range nodes {
+		id, _ := n.treeIDs()
+		if _, ok := byID[id]; ok {
+			return nil, fmt.Errorf("duplicate node id %s", id)
+		}
+
+		byID[id] = n
+		n.Nodes = nil
+	}
+
+	ordered := make([]*Node, len(nodes))
+	copy(ordered, nodes)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Order < ordered[j].Order
+	})
+
+	nl := &NodeList{}
+
+	for _, n := range ordered {
+		id, parentID := n.treeIDs()
+		if parentID == "" {
+			nl.Nodes = append(nl.Nodes, n)
+			continue
+		}
+
+		parent, ok := byID[parentID]
+		if !ok {
+			return nil, fmt.Errorf("node %s has parent %s; %w", id, parentID, ErrOrphanedNode)
+		}
+
+		parent.Nodes = append(parent.Nodes, n)
+	}
+
+	return nl, nil
+}
+
+// treeIDs returns the identifier of the Node and of its parent that are used
+// by BuildTree. The parentID is empty for top-level Nodes.
+func (n *Node) treeIDs() (id, parentID string) {
+	if n.ID != "" {
+		if len(n.ParentNodeIDs) != 0 {
+			parentID = n.ParentNodeIDs[len(n.ParentNodeIDs)-1]
+		}
+
+		return n.ID, parentID
+	}
+
+	if len(n.ParentIDs) != 0 {
+		parentID = n.ParentIDs[len(n.ParentIDs)-1]
+	}
+
+	return n.Path, parentID
+}
+
+// walkNodes walks the nodes sorted by Order without changing the order of the slice.
+func walkNodes(nodes []*Node, fn func(n *Node) error) error {
+	ordered := make([]*Node, len(nodes))
+	copy(ordered, nodes)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Order < ordered[j].Order
+	})
+
+	for _, n := range ordered {
+		if err := n.Walk(fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // getFirstBranch returs the first parent of the current node
 func (n *Node) getFirstBranch() string {
 	parents := strings.Split(n.Path, pathSep)
@@ -221,6 +372,72 @@ func CreateTree(cfg *NodeConfig, n *Node, hubID string, id string) *fragments.Tr
 	return tree
 }
 
+// inlineElements are the EAD elements that do not separate words in FullText.
+var inlineElements = map[string]bool{
+	"abbr": true, "corpname": true, "date": true, "emph": true, "expan": true,
+	"extref": true, "famname": true, "function": true, "genreform": true,
+	"geogname": true, "name": true, "num": true, "occupation": true,
+	"persname": true, "ref": true, "subject": true, "title": true,
+}
+
+// FullText returns the plain text of the scopecontent, bioghist and access
+// restriction of the Node as a single string for full-text search. The tags
+// are removed, HTML entities are decoded and whitespace is collapsed.
+func (n *Node) FullText() string {
+	var sb strings.Builder
+
+	for _, raw := range n.prose {
+		sb.WriteString(plainText(raw))
+		sb.WriteString(" ")
+	}
+
+	sb.WriteString(html.UnescapeString(n.AccessRestrict))
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// plainText returns the character data of the raw XML. Block elements are
+// separated by whitespace. When the XML cannot be parsed the sanitized XML is
+// returned instead.
+func plainText(raw []byte) string {
+	dec := xml.NewDecoder(io.MultiReader(
+		strings.NewReader("<root>"),
+		bytes.NewReader(raw),
+		strings.NewReader("</root>"),
+	))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var sb strings.Builder
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return html.UnescapeString(sanitizeXMLAsString(raw))
+		}
+
+		switch elem := tok.(type) {
+		case xml.CharData:
+			sb.Write(elem)
+		case xml.StartElement:
+			if !inlineElements[elem.Name.Local] {
+				sb.WriteString(" ")
+			}
+		case xml.EndElement:
+			if !inlineElements[elem.Name.Local] {
+				sb.WriteString(" ")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
 // GetSubject creates subject URI for the parent Node
 // the header itself is an anonymous BlankNode
 func (n *Node) GetSubject(cfg *NodeConfig) string {
@@ -16,6 +16,8 @@ package ead_test
 
 import (
 	"context"
+	"encoding/xml"
+	"errors"
 	"testing"
 
 	"github.com/matryer/is"
@@ -236,3 +238,258 @@ func TestParseNonNumbered(t *testing.T) {
 	is.True(processed == uint64(641))
 	is.True(nl != nil)
 }
+
+func TestNode_FullText(t *testing.T) {
+	is := is.New(t)
+
+	c := new(Cc)
+	err := xml.Unmarshal([]byte(`<c level="file">
+		<did><unittitle>Notulen</unittitle></did>
+		<scopecontent>
+			<head>Inhoud</head>
+			<p>Bevat <emph render="italic">notulen</emph>   van de  vergaderingen &amp; stukken.</p>
+			<p>Zie ook 332.</p>
+		</scopecontent>
+		<bioghist><p>Opgericht in&#160;1889.</p></bioghist>
+		<accessrestrict><p>Niet openbaar tot 2030.</p></accessrestrict>
+	</c>`), c)
+	is.NoErr(err)
+
+	node, err := NewNode(c, []string{}, NewNodeConfig(context.Background()))
+	is.NoErr(err)
+
+	is.Equal(
+		node.FullText(),
+		"Inhoud Bevat notulen van de vergaderingen & stukken. Zie ook 332. Opgericht in 1889. Niet openbaar tot 2030.",
+	)
+}
+
+func TestCdsc_NewNodeList_withoutComponents(t *testing.T) {
+	tests := []struct {
+		name      string
+		fname     string
+		wantLabel []string
+	}{
+		{"missing dsc", "ead.nodsc.xml", nil},
+		{"empty dsc", "ead.emptydsc.xml", []string{"Beschrijving van de archiefbestanddelen"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			ead := new(Cead)
+			is.NoErr(parseUtil(ead, tt.fname))
+
+			nl, processed, err := ead.Carchdesc.Cdsc.NewNodeList(NewNodeConfig(context.Background()))
+			is.NoErr(err)
+			is.Equal(processed, uint64(0))
+			is.Equal(len(nl.Nodes), 0)
+			is.Equal(nl.Label, tt.wantLabel)
+
+			// the streaming conversion closes the channel without sending nodes
+			cfg := NewNodeConfig(context.Background())
+			cfg.Nodes = make(chan *Node, 1)
+
+			_, processed, err = ead.Carchdesc.Cdsc.NewNodeList(cfg)
+			is.NoErr(err)
+			is.Equal(processed, uint64(0))
+
+			_, open := <-cfg.Nodes
+			is.True(!open)
+
+			stats, err := ead.Carchdesc.Cdsc.Analyze()
+			is.NoErr(err)
+			is.Equal(stats.Nodes, 0)
+		})
+	}
+}
+
+func TestNodeList_Sparse(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     SparseOptions
+		wantCTag string
+	}{
+		{"ctag is removed", SparseOptions{}, ""},
+		{"ctag is kept", SparseOptions{KeepCTag: true}, "c"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			dsc := new(Cdsc)
+			is.NoErr(parseUtil(dsc, "ead.1.xml"))
+
+			nl, _, err := dsc.NewNodeList(NewNodeConfig(context.Background()))
+			is.NoErr(err)
+			is.True(len(nl.Nodes) != 0)
+
+			nl.Sparse(tt.opts)
+
+			node := nl.Nodes[0]
+			is.Equal(node.CTag, tt.wantCTag)
+			is.Equal(node.Header.ID, nil)
+			is.Equal(node.Header.Date, nil)
+
+			for _, child := range node.Nodes {
+				is.Equal(child.CTag, tt.wantCTag)
+			}
+		})
+	}
+}
+
+func TestNodeList_Walk(t *testing.T) {
+	is := is.New(t)
+
+	node := func(order uint64, children ...*Node) *Node {
+		return &Node{Order: order, Nodes: children}
+	}
+
+	// siblings are out of order to verify the walk is in Order sequence
+	nl := &NodeList{
+		Nodes: []*Node{
+			node(5, node(7), node(6)),
+			node(1, node(2, node(3)), node(4)),
+		},
+	}
+
+	orders := []uint64{}
+	for _, n := range nl.Flatten() {
+		orders = append(orders, n.Order)
+	}
+
+	is.Equal(orders, []uint64{1, 2, 3, 4, 5, 6, 7})
+
+	// the original order is not changed
+	is.Equal(nl.Nodes[0].Order, uint64(5))
+
+	errStop := errors.New("stop")
+	visited := 0
+
+	err := nl.Walk(func(n *Node) error {
+		visited++
+		if n.Order == 3 {
+			return errStop
+		}
+
+		return nil
+	})
+	is.True(errors.Is(err, errStop))
+	is.Equal(visited, 3)
+}
+
+func TestBuildTree(t *testing.T) {
+	is := is.New(t)
+
+	ead := new(Cead)
+	is.NoErr(parseUtil(ead, "4.ZHPB2.xml"))
+
+	nl, _, err := ead.Carchdesc.Cdsc.NewNodeList(NewNodeConfig(context.Background()))
+	is.NoErr(err)
+
+	type summary struct {
+		Order    uint64
+		Children int
+	}
+
+	summarize := func(nl *NodeList) []summary {
+		s := []summary{}
+		for _, n := range nl.Flatten() {
+			s = append(s, summary{n.Order, len(n.Nodes)})
+		}
+
+		return s
+	}
+
+	want := summarize(nl)
+	is.True(len(want) > len(nl.Nodes)) // the fixture must have nested nodes
+
+	// copy the nodes without their nested nodes in reverse order
+	flat := []*Node{}
+	for _, n := range nl.Flatten() {
+		c := *n
+		c.Nodes = nil
+		flat = append([]*Node{&c}, flat...)
+	}
+
+	tree, err := BuildTree(flat)
+	is.NoErr(err)
+	is.Equal(summarize(tree), want)
+	is.Equal(len(tree.Nodes), len(nl.Nodes))
+
+	// a node without its parent is an orphan
+	_, err = BuildTree([]*Node{{ID: "2", ParentNodeIDs: []string{"1"}}})
+	is.True(errors.Is(err, ErrOrphanedNode))
+}
+
+func TestNodeConfig_AddParentLabels(t *testing.T) {
+	is := is.New(t)
+
+	ead := new(Cead)
+	is.NoErr(parseUtil(ead, "4.ZHPB2.xml"))
+
+	cfg := NewNodeConfig(context.Background())
+	cfg.AddParentLabels = true
+
+	nl, _, err := ead.Carchdesc.Cdsc.NewNodeList(cfg)
+	is.NoErr(err)
+
+	var (
+		checked int
+		check   func(nodes []*Node, ancestors []string)
+	)
+
+	// the ParentLabels must match the labels of the ancestors in the tree
+	check = func(nodes []*Node, ancestors []string) {
+		for _, n := range nodes {
+			checked++
+
+			if len(ancestors) == 0 {
+				is.Equal(len(n.ParentLabels), 0)
+			} else {
+				is.Equal(n.ParentLabels, ancestors)
+			}
+
+			is.Equal(len(n.ParentLabels), len(n.ParentIDs))
+
+			path := append(append([]string{}, ancestors...), n.Header.GetTreeLabel())
+			check(n.Nodes, path)
+		}
+	}
+
+	check(nl.Nodes, nil)
+	is.True(checked > 1)
+
+	withLabels := func(nl *NodeList) int {
+		count := 0
+
+		for _, n := range nl.Flatten() {
+			if len(n.ParentLabels) != 0 {
+				count++
+			}
+		}
+
+		return count
+	}
+
+	nested := withLabels(nl)
+	is.True(nested != 0)
+
+	// sparse only keeps the labels when requested
+	nl.Sparse(SparseOptions{KeepParentLabels: true})
+	is.Equal(withLabels(nl), nested)
+
+	nl.Sparse(SparseOptions{})
+	is.Equal(withLabels(nl), 0)
+
+	// the labels are not added by default
+	nl, _, err = ead.Carchdesc.Cdsc.NewNodeList(NewNodeConfig(context.Background()))
+	is.NoErr(err)
+	is.Equal(withLabels(nl), 0)
+}
@@ -77,8 +77,8 @@ func (c *Cc) GetAttrlevel() string                   { return c.Attrlevel }
 func (c *Cc) GetAttrotherlevel() string              { return c.Attrotherlevel }
 func (c *Cc) GetAttraltrender() string               { return c.Attraltrender }
 func (c *Cc) GetCaccessrestrict() []*Caccessrestrict { return c.Caccessrestrict }
-func (c *Cc) GetCdid() *Cdid                         { return c.Cdid[0] }
 func (c *Cc) GetScopeContent() []*Cscopecontent      { return c.Cscopecontent }
+func (c *Cc) GetBioghist() []*Cbioghist              { return c.Cbioghist }
 func (c *Cc) GetOdd() []*Codd                        { return c.Codd }
 func (c *Cc) GetPhystech() []*Cphystech              { return c.Cphystech }
 func (c *Cc) GetNested() []CLevel {
@@ -91,6 +91,16 @@ func (c *Cc) GetNested() []CLevel {
 }
 func (c *Cc) GetCc() *Cc { return c }
 
+// GetCdid returns the first did of the clevel. An empty Cdid is returned when
+// the clevel has no did.
+func (c *Cc) GetCdid() *Cdid {
+	if len(c.Cdid) == 0 {
+		return &Cdid{}
+	}
+
+	return c.Cdid[0]
+}
+
 func (c *Cc) GetGenreform() string {
 	if c.Ccontrolaccess != nil && len(c.Ccontrolaccess) != 0 {
 		if c.Ccontrolaccess[0].Cgenreform != nil {
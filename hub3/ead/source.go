@@ -0,0 +1,111 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// cLevelTag matches the unnumbered and numbered clevel elements, e.g. c, c01 and c12.
+var cLevelTag = regexp.MustCompile(`^c(0[1-9]|1[0-2])?$`)
+
+// sourceRange is the byte range of a clevel element in the source XML.
+type sourceRange struct {
+	offset int64
+	length int64
+}
+
+// TrackSourceOffsets enables recording the byte range of each clevel in src on
+// the converted Nodes, see Node.SourceOffset. src must be the document that the
+// Cdsc is unmarshalled from.
+//
+// The ranges are collected with a streaming xml.Decoder, because the byte
+// offsets are lost during struct unmarshalling. The clevels are matched to the
+// Nodes by their document order, so this is only supported for a dsc that does
+// not mix unnumbered and numbered clevels.
+func (cfg *NodeConfig) TrackSourceOffsets(src []byte) error {
+	ranges, err := clevelRanges(src)
+	if err != nil {
+		return err
+	}
+
+	cfg.sourceRanges = ranges
+
+	return nil
+}
+
+// clevelRanges returns the byte ranges of all clevel elements in document order.
+func clevelRanges(src []byte) ([]sourceRange, error) {
+	d := xml.NewDecoder(bytes.NewReader(src))
+
+	ranges := []sourceRange{}
+	// open contains the index in ranges of the open clevel elements
+	open := []int{}
+
+	for {
+		start := d.InputOffset()
+
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to read source offsets; %w", err)
+		}
+
+		switch el := t.(type) {
+		case xml.StartElement:
+			if cLevelTag.MatchString(el.Name.Local) {
+				open = append(open, len(ranges))
+				ranges = append(ranges, sourceRange{offset: start})
+			}
+		case xml.EndElement:
+			if cLevelTag.MatchString(el.Name.Local) && len(open) != 0 {
+				idx := open[len(open)-1]
+				open = open[:len(open)-1]
+				ranges[idx].length = d.InputOffset() - ranges[idx].offset
+			}
+		}
+	}
+
+	return ranges, nil
+}
+
+// setSourceRange sets the source offsets of the Node when they are tracked.
+func (cfg *NodeConfig) setSourceRange(node *Node) {
+	idx := int(node.Order) - 1
+	if idx < 0 || idx >= len(cfg.sourceRanges) {
+		return
+	}
+
+	node.SourceOffset = cfg.sourceRanges[idx].offset
+	node.SourceLength = cfg.sourceRanges[idx].length
+}
+
+// Source returns the original clevel element of the Node from src.
+// It returns nil when the source offsets were not tracked.
+func (n *Node) Source(src []byte) []byte {
+	end := n.SourceOffset + n.SourceLength
+	if n.SourceLength == 0 || end > int64(len(src)) {
+		return nil
+	}
+
+	return src[n.SourceOffset:end]
+}
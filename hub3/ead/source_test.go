@@ -0,0 +1,83 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/delving/hub3/hub3/ead"
+)
+
+func TestNodeConfig_TrackSourceOffsets(t *testing.T) {
+	is := is.New(t)
+
+	src, err := ioutil.ReadFile("testdata/ead/ead.0x.xml")
+	is.NoErr(err)
+
+	dsc := new(ead.Cdsc)
+	is.NoErr(xml.Unmarshal(src, dsc))
+
+	cfg := ead.NewNodeConfig(context.Background())
+	is.NoErr(cfg.TrackSourceOffsets(src))
+
+	nl, _, err := dsc.NewNodeList(cfg)
+	is.NoErr(err)
+
+	nodes := []*ead.Node{}
+
+	var flatten func(level []*ead.Node)
+	flatten = func(level []*ead.Node) {
+		for _, n := range level {
+			nodes = append(nodes, n)
+			flatten(n.Nodes)
+		}
+	}
+
+	flatten(nl.Nodes)
+	is.Equal(len(nodes), 7)
+
+	for _, n := range nodes {
+		fragment := n.Source(src)
+		is.True(len(fragment) != 0)
+		is.True(bytes.HasPrefix(fragment, []byte("<c0")))
+		is.True(bytes.HasSuffix(fragment, []byte(">")))
+
+		// the fragment can be unmarshalled again into the same clevel
+		var c struct {
+			Cdid []*ead.Cdid `xml:"did"`
+		}
+		is.NoErr(xml.Unmarshal(fragment, &c))
+		is.Equal(c.Cdid[0].Cunittitle[0].Title(), n.Header.Label[0])
+	}
+}
+
+func TestNode_Source_notTracked(t *testing.T) {
+	is := is.New(t)
+
+	dsc := new(ead.Cdsc)
+	is.NoErr(parseUtil(dsc, "ead.0x.xml"))
+
+	nl, _, err := dsc.NewNodeList(ead.NewNodeConfig(context.Background()))
+	is.NoErr(err)
+
+	is.Equal(nl.Nodes[0].SourceLength, int64(0))
+	is.Equal(nl.Nodes[0].Source([]byte("<c01/>")), nil)
+}
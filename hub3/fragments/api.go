@@ -22,6 +22,7 @@ import (
 	"log"
 	"math/rand"
 	"net/url"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -40,13 +41,17 @@ const (
 	qfIDKeyList        = "qf.id[]"
 	qfExistList        = "qf.exist[]"
 	qfDateRangeKey     = "qf.dateRange"
+	qfFieldedKey       = "qf.fielded"
+	qfFieldedKeyList   = "qf.fielded[]"
 	responseSize       = int32(16)
+	maxResponseSize    = int32(1000)
 	metaTags           = "meta.tags"
 	metaSpec           = "meta.spec"
 	treeDepth          = "tree.depth"
 	treeLeaf           = "tree.leaf"
 	treeCLevel         = "tree.cLevel"
 	resourcesEntries   = "resources.entries"
+	entriesValue       = "resources.entries.@value"
 	entriesSearchLabel = "resources.entries.searchLabel"
 	facetDisplayLabel  = "%s (%d)"
 )
@@ -117,8 +122,149 @@ func NewFacetField(field string) (*FacetField, error) {
 	return &ff, nil
 }
 
+// setFacetOptions applies the 'facet.size' and 'facet.sort' parameters to the FacetField.
+//
+// The options can be set for a single facet with 'f.{name}.facet.size' and
+// 'f.{name}.facet.sort'. These take precedence over the global parameters,
+// which are only applied to facets that are not declared as JSON.
+//
+// Valid sort values are 'count' and 'index' with an optional ':asc' or ':desc'
+// suffix. 'count' sorts descending and 'index' ascending by default.
+func setFacetOptions(ff *FacetField, params url.Values, global bool) error {
+	option := func(key string) string {
+		if v := params.Get(fmt.Sprintf("f.%s.%s", ff.GetName(), key)); v != "" {
+			return v
+		}
+
+		if global {
+			return params.Get(key)
+		}
+
+		return ""
+	}
+
+	if size := option("facet.size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 {
+			return fmt.Errorf("facet.size for %s must be a positive integer; got %q", ff.GetName(), size)
+		}
+
+		ff.Size = int32(n)
+	}
+
+	if sortBy := option("facet.sort"); sortBy != "" {
+		parts := strings.SplitN(sortBy, ":", 2)
+
+		switch parts[0] {
+		case "count":
+			ff.ByName = false
+			ff.Asc = false
+		case "index":
+			ff.ByName = true
+			ff.Asc = true
+		default:
+			return fmt.Errorf("facet.sort for %s must be 'count' or 'index'; got %q", ff.GetName(), sortBy)
+		}
+
+		if len(parts) == 2 {
+			switch parts[1] {
+			case "asc":
+				ff.Asc = true
+			case "desc":
+				ff.Asc = false
+			default:
+				return fmt.Errorf("facet.sort direction for %s must be 'asc' or 'desc'; got %q", ff.GetName(), sortBy)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ErrMaxRowsExceeded is returned by NewSearchRequest when rows is larger than
+// the maximum and WithMaxRows is set to reject it.
+var ErrMaxRowsExceeded = errors.New("rows exceeds the maximum")
+
+var (
+	errNegative          = errors.New("must not be negative")
+	errInvalidFacetField = errors.New("invalid facet field")
+)
+
+// ParamError is returned by NewSearchRequest when a request parameter has an
+// invalid value. Err is the underlying error, e.g. ErrMaxRowsExceeded.
+type ParamError struct {
+	Param string
+	Value string
+	Err   error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid value %q for parameter %q; %s", e.Value, e.Param, e.Err)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+func newParamError(param, value string, err error) error {
+	return &ParamError{Param: param, Value: value, Err: err}
+}
+
+// facetFieldName matches the names that can be used as a facet.field, like the
+// searchLabel 'dc_subject' or the fields 'meta.tags', 'tree.type' and 'tags'.
+// The searchLabels of namespaces with a generated prefix start with a digit,
+// e.g. '1a2b3c4d5e6f7a8b_title'. It is looser than sortLabel, because facets
+// can be created for any field, but it rejects whitespace and query syntax.
+var facetFieldName = regexp.MustCompile(`^\w[\w.-]*$`)
+
+// isFacetField returns if field can be used as a facet.field.
+func isFacetField(field string) bool {
+	return facetFieldName.MatchString(field)
+}
+
+// SearchRequestOption configures how NewSearchRequest parses the URL parameters.
+type SearchRequestOption func(*searchRequestOptions)
+
+type searchRequestOptions struct {
+	defaultRows int32
+	maxRows     int32
+	rejectRows  bool
+}
+
+// WithDefaultRows sets the number of rows when the 'rows' parameter is not
+// given. The default is 16. Values below 1 are ignored.
+func WithDefaultRows(rows int) SearchRequestOption {
+	return func(o *searchRequestOptions) {
+		if rows > 0 {
+			o.defaultRows = int32(rows)
+		}
+	}
+}
+
+// WithMaxRows sets the maximum of the 'rows' parameter. The default is 1000.
+// Larger values are clamped to the maximum, unless reject is true. Then
+// ErrMaxRowsExceeded is returned. Values below 1 are ignored.
+func WithMaxRows(rows int, reject bool) SearchRequestOption {
+	return func(o *searchRequestOptions) {
+		if rows > 0 {
+			o.maxRows = int32(rows)
+		}
+
+		o.rejectRows = reject
+	}
+}
+
 // NewSearchRequest builds a search request object from URL Parameters
-func NewSearchRequest(params url.Values) (*SearchRequest, error) {
+func NewSearchRequest(params url.Values, options ...SearchRequestOption) (*SearchRequest, error) {
+	opts := &searchRequestOptions{
+		defaultRows: responseSize,
+		maxRows:     maxResponseSize,
+	}
+
+	for _, option := range options {
+		option(opts)
+	}
+
 	hexRequest := params.Get("scrollID")
 	if hexRequest == "" {
 		hexRequest = params.Get("qs")
@@ -141,6 +287,11 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 	}
 
 	sr := DefaultSearchRequest(&c.Config)
+	sr.ResponseSize = opts.defaultRows
+
+	if sr.ResponseSize > opts.maxRows {
+		sr.ResponseSize = opts.maxRows
+	}
 
 	for p, v := range params {
 		switch p {
@@ -150,35 +301,41 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			for _, qf := range v {
 				err := sr.AddQueryFilter(qf, false)
 				if err != nil {
-					return sr, err
+					return sr, newParamError(p, qf, err)
+				}
+			}
+		case qfFieldedKey, qfFieldedKeyList:
+			for _, fq := range v {
+				if err := sr.AddFieldedQuery(fq); err != nil {
+					return sr, newParamError(p, fq, err)
 				}
 			}
 		case qfIDKey, qfIDKeyList:
 			for _, qf := range v {
 				err := sr.AddQueryFilter(qf, true)
 				if err != nil {
-					return sr, err
+					return sr, newParamError(p, qf, err)
 				}
 			}
 		case qfDateRangeKey, "qf.dateRange[]":
 			for _, qf := range v {
 				err := sr.AddDateRangeFilter(qf)
 				if err != nil {
-					return sr, err
+					return sr, newParamError(p, qf, err)
 				}
 			}
 		case "qf.tree", "qf.tree[]":
 			for _, qf := range v {
 				err := sr.AddTreeFilter(qf)
 				if err != nil {
-					return sr, err
+					return sr, newParamError(p, qf, err)
 				}
 			}
 		case "qf.date", "qf.date[]":
 			for _, qf := range v {
 				err := sr.AddDateFilter(qf)
 				if err != nil {
-					return sr, err
+					return sr, newParamError(p, qf, err)
 				}
 			}
 
@@ -186,15 +343,25 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			for _, qf := range v {
 				err := sr.AddFieldExistFilter(qf)
 				if err != nil {
-					return sr, err
+					return sr, newParamError(p, qf, err)
 				}
 			}
 		case "facet.field":
 			for _, ff := range v {
+				if !strings.HasPrefix(ff, "{") && !isFacetField(ff) {
+					return nil, newParamError(p, ff, errInvalidFacetField)
+				}
+
 				facet, err := NewFacetField(ff)
 				if err != nil {
-					return nil, err
+					return nil, newParamError(p, ff, err)
+				}
+
+				err = setFacetOptions(facet, params, !strings.HasPrefix(ff, "{"))
+				if err != nil {
+					return nil, newParamError(p, ff, err)
 				}
+
 				sr.FacetField = append(sr.FacetField, facet)
 			}
 		case "facetBoolType":
@@ -215,11 +382,20 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			size, err := strconv.Atoi(params.Get(p))
 			if err != nil {
 				logConvErr(p, []string{params.Get(p)}, err)
-				return sr, err
+				return sr, newParamError(p, params.Get(p), err)
 			}
 
-			if size > 1000 {
-				size = 1000
+			if size < 0 {
+				return sr, newParamError(p, params.Get(p), errNegative)
+			}
+
+			if size > int(opts.maxRows) {
+				if opts.rejectRows {
+					err = fmt.Errorf("%w: %d is larger than %d", ErrMaxRowsExceeded, size, opts.maxRows)
+					return sr, newParamError(p, params.Get(p), err)
+				}
+
+				size = int(opts.maxRows)
 			}
 
 			sr.ResponseSize = int32(size)
@@ -239,18 +415,38 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			default:
 				sr.ItemFormat = ItemFormatType_SUMMARY
 			}
+		case "sort":
+			if err := sr.SetSort(params.Get(p)); err != nil {
+				return nil, newParamError(p, params.Get(p), err)
+			}
 		case "sortBy":
 			sr.SortBy = params.Get(p)
 		case "sortAsc":
 			switch params.Get(p) {
 			case "true":
 				sr.SortAsc = true
+			case "false", "":
+			default:
+				return nil, newParamError(p, params.Get(p), errors.New("must be 'true' or 'false'"))
 			}
 		case "sortOrder":
 			switch params.Get(p) {
 			case "asc":
 				sr.SortAsc = true
+			case "desc", "":
+			default:
+				return nil, newParamError(p, params.Get(p), errors.New("must be 'asc' or 'desc'"))
 			}
+		case "index":
+			if err := sr.SetIndex(v...); err != nil {
+				return nil, newParamError(p, params.Get(p), err)
+			}
+		case "hl":
+			sr.Highlight = strings.EqualFold(params.Get(p), "true")
+		case "hl.pre":
+			sr.HighlightPreTag = params.Get(p)
+		case "hl.post":
+			sr.HighlightPostTag = params.Get(p)
 		case "collapseOn":
 			sr.CollapseOn = params.Get(p)
 		case "collapseSort":
@@ -259,7 +455,7 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			size, err := strconv.Atoi(params.Get(p))
 			if err != nil {
 				logConvErr(p, v, err)
-				return sr, err
+				return sr, newParamError(p, params.Get(p), err)
 			}
 			sr.CollapseSize = int32(size)
 		case "peek":
@@ -297,9 +493,12 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			sr.Tree = tree
 			tree.HasDigitalObject = strings.EqualFold(params.Get("hasDigitalObject"), "true")
 		case "paging":
-			if strings.EqualFold(params.Get("paging"), "true") {
+			switch strings.ToLower(params.Get("paging")) {
+			case "true":
 				sr.Tree = tree
 				tree.IsPaging = true
+			case "search_after":
+				sr.SearchAfterPaging = true
 			}
 		case "pageMode":
 			sr.Tree = tree
@@ -321,7 +520,7 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			hint, err := strconv.Atoi(params.Get(p))
 			if err != nil {
 				logConvErr(p, v, err)
-				return sr, err
+				return sr, newParamError(p, params.Get(p), err)
 			}
 
 			tree.CursorHint = int32(hint)
@@ -333,7 +532,7 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 				hint, err := strconv.Atoi(page)
 				if err != nil {
 					logConvErr(p, v, err)
-					return sr, err
+					return sr, newParamError(p, page, err)
 				}
 
 				tree.Page = append(tree.Page, int32(hint))
@@ -346,7 +545,7 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			hint, err := strconv.Atoi(params.Get(p))
 			if err != nil {
 				logConvErr(p, v, err)
-				return sr, err
+				return sr, newParamError(p, params.Get(p), err)
 			}
 
 			tree.PageSize = int32(hint)
@@ -354,19 +553,31 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			start, err := strconv.Atoi(params.Get(p))
 			if err != nil {
 				logConvErr(p, v, err)
-				return sr, err
+				return sr, newParamError(p, params.Get(p), err)
+			}
+			if start < 0 {
+				return sr, newParamError(p, params.Get(p), errNegative)
 			}
+
 			sr.Start = int32(start)
 		case "searchAfter":
 			var sa = make([]interface{}, 0)
 			parts := strings.SplitN(params.Get(p), ",", 2)
-			sortKey, _ := strconv.Atoi(parts[0])
+			if len(parts) != 2 {
+				return sr, newParamError(p, params.Get(p), errors.New("must be 'sortKey,cLevel'"))
+			}
+
+			sortKey, err := strconv.Atoi(parts[0])
+			if err != nil {
+				return sr, newParamError(p, params.Get(p), err)
+			}
+
 			cLevel := parts[1]
 			sa = append(sa, sortKey, cLevel)
 			sb, err := getInterfaceBytes(sa)
 			if err != nil {
 				log.Printf("unable to create bytes from interface %v", sa)
-				return sr, err
+				return sr, newParamError(p, params.Get(p), err)
 			}
 			sr.SearchAfter = sb
 		}
@@ -383,6 +594,119 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 	return sr, nil
 }
 
+// sortableMetaFields maps the sort names of the keyword and date fields in the
+// record header to their Elasticsearch field.
+var sortableMetaFields = map[string]string{
+	"hubID":    "meta.hubID",
+	"spec":     "meta.spec",
+	"orgID":    "meta.orgID",
+	"modified": "meta.modified",
+}
+
+// sortLabel matches searchLabels like 'dc_title' that are sorted on their keyword value.
+var sortLabel = regexp.MustCompile(`^[a-zA-Z][\w-]*_[\w-]+$`)
+
+// SetSort parses a 'field:direction' sort value and sets SortBy and SortAsc.
+//
+// The field must be '_score', 'random', a header field from sortableMetaFields
+// or a searchLabel. Sorting on a searchLabel uses the keyword
+// value of the entry, or the integer value when the label has an '_int' suffix.
+// The direction is either 'asc' (default) or 'desc'.
+func (sr *SearchRequest) SetSort(sortBy string) error {
+	parts := strings.SplitN(sortBy, ":", 2)
+	field := parts[0]
+
+	asc := true
+
+	if len(parts) == 2 {
+		switch parts[1] {
+		case "asc":
+		case "desc":
+			asc = false
+		default:
+			return fmt.Errorf("sort direction must be 'asc' or 'desc'; got %q", sortBy)
+		}
+	}
+
+	metaField := false
+
+	for name, esField := range sortableMetaFields {
+		if field == name || field == esField {
+			field = esField
+			metaField = true
+
+			break
+		}
+	}
+
+	switch {
+	case field == "_score":
+		field = ""
+	case field == "random", strings.HasPrefix(field, "random_"):
+	case metaField:
+	case sortLabel.MatchString(field):
+	default:
+		return fmt.Errorf("%q is not a sortable field", parts[0])
+	}
+
+	sr.SortBy = field
+	sr.SortAsc = asc
+
+	return nil
+}
+
+// SetIndex sets the indices that are searched. Each value can contain a comma
+// separated list of index names. Only the configured index and the indices in
+// the 'searchIndices' allow-list can be searched.
+func (sr *SearchRequest) SetIndex(values ...string) error {
+	indices := []string{}
+
+	for _, value := range values {
+		for _, index := range strings.Split(value, ",") {
+			index = strings.ToLower(strings.TrimSpace(index))
+			if index == "" {
+				continue
+			}
+
+			if !c.Config.ElasticSearch.IsSearchIndex(index) {
+				return fmt.Errorf("index %q is not allowed to be searched", index)
+			}
+
+			indices = append(indices, index)
+		}
+	}
+
+	sr.Index = indices
+
+	return nil
+}
+
+// searchIndices returns the indices that are searched.
+func (sr *SearchRequest) searchIndices() []string {
+	if len(sr.GetIndex()) == 0 {
+		return []string{c.Config.ElasticSearch.GetIndexName()}
+	}
+
+	return sr.GetIndex()
+}
+
+// orgQuery restricts the records to the configured orgID. It is applied to all
+// the searched indices, so searching other allowed indices never returns the
+// records of another organization.
+func (sr *SearchRequest) orgQuery() elastic.Query {
+	return elastic.NewTermQuery(c.Config.ElasticSearch.OrgIDKey, c.Config.OrgID)
+}
+
+// hasFieldSort returns true when the results are sorted on a record field
+// instead of on relevance or the position in the tree.
+func (sr *SearchRequest) hasFieldSort() bool {
+	sortBy := sr.GetSortBy()
+
+	return sortBy != "" &&
+		!strings.HasPrefix(sortBy, "random") &&
+		!strings.HasPrefix(sortBy, "tree.")
+}
+
 var letters = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
 // RandSeq returns a random string of letters with the size of 'n'
@@ -679,12 +1003,37 @@ func (sr *SearchRequest) NewUserQuery() (*Query, *BreadCrumbBuilder, error) {
 	return q, bcb, nil
 }
 
+// highlightQuery returns an optional nested query that returns the highlighted
+// resource entries matching the rawQuery as the 'highlight' inner hits.
+//
+// The full_text field is not stored so it cannot be highlighted directly.
+// The entry values are HTML escaped by Elasticsearch except for the pre and post tags.
+func (sr *SearchRequest) highlightQuery(rawQuery string) elastic.Query {
+	preTag, postTag := sr.GetHighlightPreTag(), sr.GetHighlightPostTag()
+	if preTag == "" || postTag == "" {
+		preTag, postTag = "<em>", "</em>"
+	}
+
+	hl := elastic.NewHighlight().
+		Field(entriesValue).
+		PreTags(preTag).
+		PostTags(postTag).
+		Encoder("html")
+
+	qs := elastic.NewQueryStringQuery(escapeRawQuery(rawQuery)).
+		Field(entriesValue).
+		DefaultOperator("or")
+
+	return elastic.NewNestedQuery(resourcesEntries, qs).
+		InnerHit(elastic.NewInnerHit().Name("highlight").Size(10).Highlight(hl))
+}
+
 // ElasticQuery creates an ElasticSearch query from the Search Request
 // This query can be passed into an elastic Search Object.
 func (sr *SearchRequest) ElasticQuery() (elastic.Query, error) {
 	query := elastic.NewBoolQuery()
 	query = query.Must(elastic.NewTermQuery("meta.docType", FragmentGraphDocType))
-	query = query.Must(elastic.NewTermQuery(c.Config.ElasticSearch.OrgIDKey, c.Config.OrgID))
+	query = query.Must(sr.orgQuery())
 
 	metaSpecPrefix := "meta.spec:"
 
@@ -710,8 +1059,26 @@ func (sr *SearchRequest) ElasticQuery() (elastic.Query, error) {
 				Field("full_text").
 				MinimumShouldMatch(c.Config.ElasticSearch.MinimumShouldMatch)
 			query = query.Must(qs)
+
+			if sr.GetHighlight() {
+				query = query.Should(sr.highlightQuery(rawQuery))
+			}
+		}
+
+	}
+
+	clauses, err := sr.fieldedQueryClauses()
+	if err != nil {
+		return query, err
+	}
+
+	for _, clause := range clauses {
+		filter, err := clause.ElasticQuery()
+		if err != nil {
+			return query, err
 		}
 
+		query = query.Filter(filter)
 	}
 
 	if strings.HasPrefix(sr.GetSortBy(), "random") {
@@ -1073,6 +1440,8 @@ func (sr *SearchRequest) ElasticSearchService(ec *elastic.Client) (*elastic.Sear
 		fieldSort = elastic.NewFieldSort("_score").Desc()
 	case strings.HasPrefix(sr.GetSortBy(), "tree."):
 		fieldSort = elastic.NewFieldSort(sr.GetSortBy())
+	case strings.HasPrefix(sr.GetSortBy(), "meta."):
+		fieldSort = elastic.NewFieldSort(sr.GetSortBy()).Order(sr.SortAsc)
 	case strings.HasSuffix(sr.GetSortBy(), "_int"):
 		field := strings.TrimSuffix(sr.GetSortBy(), "_int")
 		sortNestedQuery := elastic.NewTermQuery(entriesSearchLabel, field)
@@ -1104,7 +1473,7 @@ func (sr *SearchRequest) ElasticSearchService(ec *elastic.Client) (*elastic.Sear
 	}
 
 	s := ec.Search().
-		Index(c.Config.ElasticSearch.GetIndexName()).
+		Index(sr.searchIndices()...).
 		TrackTotalHits(c.Config.ElasticSearch.TrackTotalHits).
 		Preference(sr.GetSessionID()).
 		Size(int(sr.GetResponseSize()))
@@ -1126,7 +1495,8 @@ func (sr *SearchRequest) ElasticSearchService(ec *elastic.Client) (*elastic.Sear
 			if err != nil {
 				return nil, nil, err
 			}
-			if c.Config.ElasticSearch.EnableSearchAfter {
+			// paging with from is not stable when sorting on a field
+			if c.Config.ElasticSearch.EnableSearchAfter || sr.hasFieldSort() || sr.GetSearchAfterPaging() {
 				s = s.SearchAfter(sa...)
 			} else {
 				s = s.From(int(sr.GetStart()))
@@ -1213,7 +1583,10 @@ func NewScrollPager() *ScrollPager {
 	return sp
 }
 
-// Echo returns a json version of the request object for introspection
+// Echo returns a json version of the request object for introspection.
+//
+// 'request' and 'searchRequest' return the SearchRequest itself. The search
+// handlers echo 'request' before the search is executed.
 func (sr *SearchRequest) Echo(echoType string, total int64) (interface{}, error) {
 	switch echoType {
 	case "es":
@@ -1234,7 +1607,7 @@ func (sr *SearchRequest) Echo(echoType string, total int64) (interface{}, error)
 			sourceMap[k] = source
 		}
 		return sourceMap, nil
-	case "searchRequest":
+	case "request", "searchRequest":
 		return sr, nil
 	case "options":
 		options := []string{
@@ -1243,7 +1616,7 @@ func (sr *SearchRequest) Echo(echoType string, total int64) (interface{}, error)
 		}
 		sort.Strings(options)
 		return options, nil
-	case "searchService", "searchResponse", "request", "nextScrollID", "previousScrollID", "searchAfter":
+	case "searchService", "searchResponse", "nextScrollID", "previousScrollID", "searchAfter":
 		return nil, nil
 	}
 
@@ -1316,6 +1689,18 @@ func (sr *SearchRequest) ScrollPagers(total int64) (*ScrollPager, error) {
 		}
 	}
 
+	// search_after can only page forward from the sort values of the last hit
+	if sr.GetSearchAfterPaging() {
+		if len(sr.GetSearchAfter()) != 0 {
+			sp.SearchAfter, err = sr.DecodeSearchAfter()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return sp, nil
+	}
+
 	if prev.GetStart() >= 0 {
 		sp.PreviousScrollID, err = prev.SearchRequestToHex()
 		if err != nil {
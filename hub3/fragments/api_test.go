@@ -16,8 +16,10 @@ package fragments
 
 import (
 	"encoding/json"
+	"errors"
 	fmt "fmt"
 	"io/ioutil"
+	"net/url"
 	"reflect"
 	"regexp"
 	"strings"
@@ -25,6 +27,7 @@ import (
 
 	c "github.com/delving/hub3/config"
 	"github.com/google/go-cmp/cmp"
+	elastic "github.com/olivere/elastic/v7"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
@@ -923,3 +926,467 @@ func TestFacetURIBuilder_CreateFacetFilterURI(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSearchRequest_facetOptions(t *testing.T) {
+	defaultSize := int32(c.Config.ElasticSearch.FacetSize)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantSize   []int32
+		wantByName []bool
+		wantAsc    []bool
+		wantErr    bool
+	}{
+		{
+			"defaults",
+			"facet.field=dc_subject",
+			[]int32{defaultSize},
+			[]bool{false},
+			[]bool{false},
+			false,
+		},
+		{
+			"global size and sort",
+			"facet.field=dc_subject&facet.field=dc_type&facet.size=5&facet.sort=index",
+			[]int32{5, 5},
+			[]bool{true, true},
+			[]bool{true, true},
+			false,
+		},
+		{
+			"per facet options override global",
+			"facet.field=dc_subject&facet.field=dc_type&facet.size=5&f.dc_type.facet.size=20&f.dc_type.facet.sort=count:asc",
+			[]int32{5, 20},
+			[]bool{false, false},
+			[]bool{false, true},
+			false,
+		},
+		{
+			"global options do not override JSON facets",
+			`facet.field={"field":"dc_subject","size":3,"byName":true}&facet.size=5`,
+			[]int32{3},
+			[]bool{true},
+			[]bool{false},
+			false,
+		},
+		{"invalid size", "facet.field=dc_subject&facet.size=abc", nil, nil, nil, true},
+		{"zero size", "facet.field=dc_subject&f.dc_subject.facet.size=0", nil, nil, nil, true},
+		{"invalid sort", "facet.field=dc_subject&facet.sort=random", nil, nil, nil, true},
+		{"invalid sort direction", "facet.field=dc_subject&facet.sort=count:up", nil, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unable to parse query: %s", err)
+			}
+
+			sr, err := NewSearchRequest(params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSearchRequest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(sr.FacetField) != len(tt.wantSize) {
+				t.Fatalf("NewSearchRequest() got %d facets; want %d", len(sr.FacetField), len(tt.wantSize))
+			}
+
+			// facet.field values are parsed in order
+			for i, ff := range sr.FacetField {
+				if ff.GetSize() != tt.wantSize[i] {
+					t.Errorf("%s size = %d; want %d", ff.GetName(), ff.GetSize(), tt.wantSize[i])
+				}
+
+				if ff.GetByName() != tt.wantByName[i] {
+					t.Errorf("%s byName = %v; want %v", ff.GetName(), ff.GetByName(), tt.wantByName[i])
+				}
+
+				if ff.GetAsc() != tt.wantAsc[i] {
+					t.Errorf("%s asc = %v; want %v", ff.GetName(), ff.GetAsc(), tt.wantAsc[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchRequest_SetSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sortBy  string
+		want    string
+		wantAsc bool
+		wantErr bool
+	}{
+		{"searchLabel default ascending", "dc_title", "dc_title", true, false},
+		{"searchLabel descending", "dc_title:desc", "dc_title", false, false},
+		{"integer searchLabel", "nave_year_int:asc", "nave_year_int", true, false},
+		{"meta alias", "modified:desc", "meta.modified", false, false},
+		{"meta field", "meta.spec", "meta.spec", true, false},
+		{"relevance", "_score", "", true, false},
+		{"random with seed", "random_123", "random_123", true, false},
+		{"unknown meta field", "meta.revision", "", false, true},
+		{"full-text field", "full_text.raw", "", false, true},
+		{"no namespace prefix", "title", "", false, true},
+		{"invalid direction", "dc_title:up", "", false, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			sr := &SearchRequest{}
+
+			err := sr.SetSort(tt.sortBy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SearchRequest.SetSort() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if sr.GetSortBy() != tt.want {
+				t.Errorf("SearchRequest.SetSort() sortBy = %v, want %v", sr.GetSortBy(), tt.want)
+			}
+
+			if sr.GetSortAsc() != tt.wantAsc {
+				t.Errorf("SearchRequest.SetSort() sortAsc = %v, want %v", sr.GetSortAsc(), tt.wantAsc)
+			}
+		})
+	}
+}
+
+func TestSearchRequest_hasFieldSort(t *testing.T) {
+	tests := []struct {
+		sortBy string
+		want   bool
+	}{
+		{"", false},
+		{"random_123", false},
+		{"tree.sortKey", false},
+		{"dc_title", true},
+		{"meta.modified", true},
+	}
+
+	for _, tt := range tests {
+		sr := &SearchRequest{SortBy: tt.sortBy}
+		if got := sr.hasFieldSort(); got != tt.want {
+			t.Errorf("SearchRequest.hasFieldSort(%q) = %v, want %v", tt.sortBy, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRequest_highlight(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantInner   bool
+		wantPreTag  string
+		wantPostTag string
+	}{
+		{"disabled", "q=boerderij", false, "", ""},
+		{"default tags", "q=boerderij&hl=true", true, "<em>", "</em>"},
+		{"custom tags", "q=boerderij&hl=true&hl.pre=<mark>&hl.post=</mark>", true, "<mark>", "</mark>"},
+		{"no query", "hl=true", false, "", ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unable to parse query: %s", err)
+			}
+
+			sr, err := NewSearchRequest(params)
+			if err != nil {
+				t.Fatalf("NewSearchRequest() error = %v", err)
+			}
+
+			// the highlight options must survive the scrollID round-trip
+			scrollID, err := sr.SearchRequestToHex()
+			if err != nil {
+				t.Fatalf("SearchRequestToHex() error = %v", err)
+			}
+
+			sr, err = SearchRequestFromHex(scrollID)
+			if err != nil {
+				t.Fatalf("SearchRequestFromHex() error = %v", err)
+			}
+
+			query, err := sr.ElasticQuery()
+			if err != nil {
+				t.Fatalf("SearchRequest.ElasticQuery() error = %v", err)
+			}
+
+			src, err := query.Source()
+			if err != nil {
+				t.Fatalf("unable to get query source: %s", err)
+			}
+
+			var buf strings.Builder
+
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+
+			if err := enc.Encode(src); err != nil {
+				t.Fatalf("unable to marshal query source: %s", err)
+			}
+
+			source := buf.String()
+
+			if got := strings.Contains(source, `"inner_hits"`); got != tt.wantInner {
+				t.Fatalf("inner_hits in query = %v, want %v; %s", got, tt.wantInner, source)
+			}
+
+			if !tt.wantInner {
+				return
+			}
+
+			for _, want := range []string{
+				`"name":"highlight"`,
+				`"encoder":"html"`,
+				fmt.Sprintf(`"pre_tags":[%q]`, tt.wantPreTag),
+				fmt.Sprintf(`"post_tags":[%q]`, tt.wantPostTag),
+			} {
+				if !strings.Contains(source, want) {
+					t.Errorf("query source should contain %s; got %s", want, source)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchRequest_SetIndex(t *testing.T) {
+	defaultIndex := c.Config.ElasticSearch.GetIndexName()
+
+	orig := c.Config.ElasticSearch.SearchIndices
+	c.Config.ElasticSearch.SearchIndices = []string{"org1v2", "org2v2"}
+
+	defer func() { c.Config.ElasticSearch.SearchIndices = orig }()
+
+	tests := []struct {
+		name    string
+		values  []string
+		want    []string
+		wantErr bool
+	}{
+		{"default", []string{}, []string{defaultIndex}, false},
+		{"comma separated", []string{"org1v2, ORG2v2"}, []string{"org1v2", "org2v2"}, false},
+		{"multiple values", []string{"org1v2", defaultIndex}, []string{"org1v2", defaultIndex}, false},
+		{"only default", []string{defaultIndex}, []string{defaultIndex}, false},
+		{"not allowed", []string{"org1v2,secret"}, nil, true},
+	}
+
+	wantOrgQuery, err := elastic.NewTermQuery(c.Config.ElasticSearch.OrgIDKey, c.Config.OrgID).Source()
+	if err != nil {
+		t.Fatalf("unable to create orgID query source; %s", err)
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			sr := &SearchRequest{}
+
+			err := sr.SetIndex(tt.values...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SearchRequest.SetIndex() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, sr.searchIndices()); diff != "" {
+				t.Errorf("SearchRequest.searchIndices() mismatch (-want +got):\n%s", diff)
+			}
+
+			// the orgID filter is applied to the default and the other indices
+			query, err := sr.ElasticQuery()
+			if err != nil {
+				t.Fatalf("SearchRequest.ElasticQuery() error = %v", err)
+			}
+
+			src, err := query.Source()
+			if err != nil {
+				t.Fatalf("unable to create query source; %s", err)
+			}
+
+			var must []interface{}
+			if bq, ok := src.(map[string]interface{})["bool"].(map[string]interface{}); ok {
+				must, _ = bq["must"].([]interface{})
+			}
+
+			if !containsSource(must, wantOrgQuery) {
+				t.Errorf("SearchRequest.ElasticQuery() must = %v, want orgID filter %v", must, wantOrgQuery)
+			}
+		})
+	}
+}
+
+// containsSource returns true when one of the query sources equals want.
+func containsSource(sources []interface{}, want interface{}) bool {
+	for _, src := range sources {
+		if reflect.DeepEqual(src, want) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestNewSearchRequest_rows(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    string
+		options []SearchRequestOption
+		want    int32
+		wantErr error
+	}{
+		{"default", "", nil, 16, nil},
+		{"below default maximum", "1000", nil, 1000, nil},
+		{"clamped to default maximum", "1001", nil, 1000, nil},
+		{"configured default", "", []SearchRequestOption{WithDefaultRows(50)}, 50, nil},
+		{"default larger than maximum", "", []SearchRequestOption{WithDefaultRows(50), WithMaxRows(20, false)}, 20, nil},
+		{"ignored zero options", "", []SearchRequestOption{WithDefaultRows(0), WithMaxRows(0, false)}, 16, nil},
+		{"equal to maximum", "100", []SearchRequestOption{WithMaxRows(100, false)}, 100, nil},
+		{"clamped to maximum", "101", []SearchRequestOption{WithMaxRows(100, false)}, 100, nil},
+		{"equal to rejected maximum", "100", []SearchRequestOption{WithMaxRows(100, true)}, 100, nil},
+		{"rejected above maximum", "101", []SearchRequestOption{WithMaxRows(100, true)}, 0, ErrMaxRowsExceeded},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			params := url.Values{}
+			if tt.rows != "" {
+				params.Set("rows", tt.rows)
+			}
+
+			sr, err := NewSearchRequest(params, tt.options...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewSearchRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got := sr.GetResponseSize(); got != tt.want {
+				t.Errorf("NewSearchRequest() rows = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSearchRequest_invalidParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantParam string
+		wantValue string
+	}{
+		{"non-numeric rows", "rows=abc", "rows", "abc"},
+		{"negative rows", "rows=-1", "rows", "-1"},
+		{"non-numeric start", "start=first", "start", "first"},
+		{"negative start", "start=-10", "start", "-10"},
+		{"invalid facet field", "facet.field=dc_title:asc", "facet.field", "dc_title:asc"},
+		{"invalid sort direction", "sort=dc_title:up", "sort", "dc_title:up"},
+		{"invalid sortOrder", "sortOrder=up", "sortOrder", "up"},
+		{"invalid sortAsc", "sortAsc=yes", "sortAsc", "yes"},
+		{"invalid query filter", "qf=title", "qf", "title"},
+		{"invalid date range", "qf.dateRange=dc_date:2000", "qf.dateRange", "dc_date:2000"},
+		{"non-numeric collapseSize", "collapseSize=many", "collapseSize", "many"},
+		{"non-numeric page", "page=1&page=two", "page", "two"},
+		{"searchAfter without cLevel", "searchAfter=10", "searchAfter", "10"},
+		{"non-numeric searchAfter", "searchAfter=a,b", "searchAfter", "a,b"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unable to parse query; %s", err)
+			}
+
+			_, err = NewSearchRequest(params)
+
+			var paramErr *ParamError
+			if !errors.As(err, &paramErr) {
+				t.Fatalf("NewSearchRequest() error = %v, want *ParamError", err)
+			}
+
+			if paramErr.Param != tt.wantParam || paramErr.Value != tt.wantValue {
+				t.Errorf("NewSearchRequest() ParamError = %q=%q, want %q=%q", paramErr.Param, paramErr.Value, tt.wantParam, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestIsFacetField(t *testing.T) {
+	tests := []struct {
+		field string
+		want  bool
+	}{
+		{"dc_subject", true},
+		{"ead-rdf_physdesc", true},
+		{"ead-rdf_physdescPhysfacet", true},
+		{"delving_spec", true},
+		{"meta.tags", true},
+		{"meta.tag", true},
+		{"meta.spec", true},
+		{"tree.type", true},
+		{"tree.hasDigitalObject", true},
+		{"tags", true},
+		{"spec", true},
+		{"title", true},
+		{"1a2b3c4d5e6f7a8b_title", true},
+		{"_subject", true},
+		{"", false},
+		{".tags", false},
+		{"dc subject", false},
+		{"dc_title:asc", false},
+		{"dc_*", false},
+	}
+
+	for _, tt := range tests {
+		if got := isFacetField(tt.field); got != tt.want {
+			t.Errorf("isFacetField(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestNewSearchRequest_validParams(t *testing.T) {
+	for _, query := range []string{
+		"rows=0&start=0",
+		"sortOrder=desc&sortAsc=false",
+		"facet.field=dc_subject&facet.field=meta.tags&facet.field=tree.type&facet.field=tags",
+		"facet.field=spec&facet.field=ead-rdf_physdesc&facet.field=title",
+		`facet.field={"field":"anything","size":3}`,
+		"searchAfter=10,c01",
+	} {
+		params, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatalf("unable to parse query; %s", err)
+		}
+
+		if _, err := NewSearchRequest(params); err != nil {
+			t.Errorf("NewSearchRequest(%q) unexpected error; %s", query, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+const (
+	csvColumnHubID = "hubID"
+	csvColumnSpec  = "spec"
+	csvColumnOrgID = "orgID"
+
+	// CSVValueSeparator joins multiple values of a search label in a single CSV cell.
+	CSVValueSeparator = "; "
+)
+
+// DefaultCSVColumns are the columns of a CSV export when none are requested.
+var DefaultCSVColumns = []string{csvColumnHubID, csvColumnSpec}
+
+// CSVExport writes FragmentGraphs as CSV rows.
+//
+// The columns 'hubID', 'spec' and 'orgID' are taken from the Header. All other
+// columns are search labels, e.g. 'dc_title'. Multiple values of a search label
+// are joined with CSVValueSeparator.
+type CSVExport struct {
+	Columns []string
+	Header  bool
+}
+
+// NewCSVExport creates a CSVExport that writes a header row.
+// When no columns are given DefaultCSVColumns is used.
+func NewCSVExport(columns ...string) *CSVExport {
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+
+	return &CSVExport{
+		Columns: columns,
+		Header:  true,
+	}
+}
+
+// Write writes the header (when enabled) and one row per record to w.
+// The values are quoted when they contain separators, quotes or newlines.
+func (ce *CSVExport) Write(w io.Writer, records []*FragmentGraph) error {
+	cw := csv.NewWriter(w)
+
+	if ce.Header {
+		if err := cw.Write(ce.Columns); err != nil {
+			return err
+		}
+	}
+
+	for _, fg := range records {
+		if err := cw.Write(ce.row(fg)); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func (ce *CSVExport) row(fg *FragmentGraph) []string {
+	fields := fg.NewFields(nil, ce.Columns...)
+
+	row := make([]string, 0, len(ce.Columns))
+
+	for _, col := range ce.Columns {
+		switch col {
+		case csvColumnHubID:
+			row = append(row, fg.Meta.GetHubID())
+		case csvColumnSpec:
+			row = append(row, fg.Meta.GetSpec())
+		case csvColumnOrgID:
+			row = append(row, fg.Meta.GetOrgID())
+		default:
+			row = append(row, strings.Join(fields[col], CSVValueSeparator))
+		}
+	}
+
+	return row
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCSVExport_Write(t *testing.T) {
+	fg := &FragmentGraph{
+		Meta: &Header{HubID: "org_spec_1", Spec: "spec", OrgID: "org"},
+		Resources: []*FragmentResource{
+			{
+				ID: "http://example.org/1",
+				Entries: []*ResourceEntry{
+					{Value: "title, with comma", SearchLabel: "dc_title", Order: 1},
+					{Value: "line one\nline two", SearchLabel: "dc_description", Order: 2},
+					{Value: "second title", SearchLabel: "dc_title", Order: 3},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		columns []string
+		header  bool
+		want    string
+	}{
+		{
+			"default columns",
+			nil,
+			true,
+			"hubID,spec\norg_spec_1,spec\n",
+		},
+		{
+			"search labels are quoted",
+			[]string{"hubID", "dc_title", "dc_description", "dc_creator"},
+			true,
+			"hubID,dc_title,dc_description,dc_creator\n" +
+				"org_spec_1,\"title, with comma; second title\",\"line one\nline two\",\n",
+		},
+		{
+			"without header",
+			[]string{"orgID", "hubID"},
+			false,
+			"org,org_spec_1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			export := NewCSVExport(tt.columns...)
+			export.Header = tt.header
+
+			var sb strings.Builder
+			if err := export.Write(&sb, []*FragmentGraph{fg}); err != nil {
+				t.Fatalf("CSVExport.Write() error = %v", err)
+			}
+
+			if got := sb.String(); got != tt.want {
+				t.Errorf("CSVExport.Write() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// ErrInvalidFieldedQuery is returned when a fielded query cannot be parsed.
+var ErrInvalidFieldedQuery = errors.New("invalid fielded query")
+
+// FieldedQueryError describes where a fielded query has invalid syntax.
+type FieldedQueryError struct {
+	Query  string
+	Pos    int
+	Reason string
+}
+
+func (e *FieldedQueryError) Error() string {
+	return fmt.Sprintf("%s %q at position %d: %s", ErrInvalidFieldedQuery, e.Query, e.Pos, e.Reason)
+}
+
+func (e *FieldedQueryError) Unwrap() error {
+	return ErrInvalidFieldedQuery
+}
+
+// ClauseType is the type of a FieldedClause.
+type ClauseType int
+
+const (
+	// TermClause matches the exact value of a field.
+	TermClause ClauseType = iota
+	// RangeClause matches the values of a field between From and To.
+	RangeClause
+	// BoolClause combines Clauses with its Operator.
+	BoolClause
+)
+
+// BoolOperator combines the Clauses of a BoolClause.
+type BoolOperator string
+
+const (
+	OperatorAnd BoolOperator = "AND"
+	OperatorOr  BoolOperator = "OR"
+)
+
+// FieldedClause is a structured filter clause of a fielded query.
+type FieldedClause struct {
+	Type  ClauseType
+	Field string
+	// Value is the value of a TermClause.
+	Value string
+	// From and To are the inclusive bounds of a RangeClause. An empty bound is unbounded.
+	From string
+	To   string
+	// Operator and Clauses are set for a BoolClause.
+	Operator BoolOperator
+	Clauses  []*FieldedClause
+	// Negate excludes the documents that match the clause.
+	Negate bool
+}
+
+// ParseFieldedQuery parses a fielded query into a FieldedClause. Fielded
+// queries are given with the 'qf.fielded[]' parameter, so 'qf[]' values that
+// contain the same keywords are still filtered literally.
+//
+// A clause is a 'field:value' term or a 'field:[from TO to]' range, where '*'
+// is an open bound. Values with spaces must be quoted, e.g. 'dc_title:"the
+// war"'. Clauses are combined with AND, OR and parentheses and are negated with
+// NOT or a leading '-'. Adjacent clauses without an operator are combined with
+// AND, which binds stronger than OR. For example:
+//
+//	dc_subject:war AND (dc_date:[1940 TO 1945] OR NOT dc_type:photo)
+func ParseFieldedQuery(query string) (*FieldedClause, error) {
+	p := &fieldedQueryParser{query: query, input: []rune(query)}
+
+	p.skipSpace()
+
+	if p.eof() {
+		return nil, p.errorf("the query is empty")
+	}
+
+	clause, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+
+	return clause, nil
+}
+
+// Fields returns the fields of all the term and range clauses in order of appearance.
+func (fc *FieldedClause) Fields() []string {
+	if fc.Type != BoolClause {
+		return []string{fc.Field}
+	}
+
+	fields := []string{}
+	for _, clause := range fc.Clauses {
+		fields = append(fields, clause.Fields()...)
+	}
+
+	return fields
+}
+
+// ElasticQuery converts the FieldedClause into an ElasticSearch query. The
+// term and range clauses on search labels are queried like a QueryFilter.
+func (fc *FieldedClause) ElasticQuery() (elastic.Query, error) {
+	var (
+		query elastic.Query
+		err   error
+	)
+
+	switch fc.Type {
+	case TermClause:
+		qf := &QueryFilter{SearchLabel: fc.Field, Value: fc.Value, Type: QueryFilterType_TEXT}
+
+		switch fc.Field {
+		case "tag", "tags":
+			qf.Type = QueryFilterType_ENTRYTAG
+		}
+
+		query, err = qf.ElasticFilter()
+	case RangeClause:
+		if strings.HasPrefix(fc.Field, "meta.") || strings.HasPrefix(fc.Field, "tree.") {
+			rq := elastic.NewRangeQuery(fc.Field)
+			if fc.From != "" {
+				rq = rq.Gte(fc.From)
+			}
+
+			if fc.To != "" {
+				rq = rq.Lte(fc.To)
+			}
+
+			query = rq
+
+			break
+		}
+
+		qf := &QueryFilter{SearchLabel: fc.Field, Type: QueryFilterType_DATERANGE, Gte: fc.From, Lte: fc.To}
+		query, err = qf.ElasticFilter()
+	case BoolClause:
+		bq := elastic.NewBoolQuery()
+
+		for _, clause := range fc.Clauses {
+			q, err := clause.ElasticQuery()
+			if err != nil {
+				return nil, err
+			}
+
+			if fc.Operator == OperatorOr {
+				bq = bq.Should(q)
+				continue
+			}
+
+			bq = bq.Must(q)
+		}
+
+		if fc.Operator == OperatorOr {
+			bq = bq.MinimumNumberShouldMatch(1)
+		}
+
+		query = bq
+	default:
+		return nil, fmt.Errorf("unknown clause type %d", fc.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fc.Negate {
+		return elastic.NewBoolQuery().MustNot(query), nil
+	}
+
+	return query, nil
+}
+
+// AddFieldedQuery validates the fielded query and adds it to the SearchRequest.
+func (sr *SearchRequest) AddFieldedQuery(query string) error {
+	if _, err := ParseFieldedQuery(query); err != nil {
+		return err
+	}
+
+	for _, fq := range sr.FieldedQuery {
+		if fq == query {
+			return nil
+		}
+	}
+
+	sr.FieldedQuery = append(sr.FieldedQuery, query)
+
+	return nil
+}
+
+// fieldedQueryClauses parses the fielded queries of the SearchRequest.
+func (sr *SearchRequest) fieldedQueryClauses() ([]*FieldedClause, error) {
+	clauses := []*FieldedClause{}
+
+	for _, fq := range sr.GetFieldedQuery() {
+		clause, err := ParseFieldedQuery(fq)
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return clauses, nil
+}
+
+type fieldedQueryParser struct {
+	query string
+	input []rune
+	pos   int
+}
+
+func (p *fieldedQueryParser) errorf(format string, args ...interface{}) error {
+	return &FieldedQueryError{Query: p.query, Pos: p.pos, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (p *fieldedQueryParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *fieldedQueryParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// keyword consumes the operator when it is the next word of the input.
+func (p *fieldedQueryParser) keyword(word string) bool {
+	end := p.pos + len(word)
+	if end > len(p.input) || string(p.input[p.pos:end]) != word {
+		return false
+	}
+
+	if end < len(p.input) && !unicode.IsSpace(p.input[end]) && p.input[end] != '(' {
+		return false
+	}
+
+	p.pos = end
+
+	return true
+}
+
+func (p *fieldedQueryParser) parseOr() (*FieldedClause, error) {
+	clause, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	clauses := []*FieldedClause{clause}
+
+	for {
+		p.skipSpace()
+
+		if !p.keyword(string(OperatorOr)) {
+			break
+		}
+
+		clause, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return combineClauses(OperatorOr, clauses), nil
+}
+
+func (p *fieldedQueryParser) parseAnd() (*FieldedClause, error) {
+	clause, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	clauses := []*FieldedClause{clause}
+
+	for {
+		p.skipSpace()
+
+		if p.eof() || p.input[p.pos] == ')' {
+			break
+		}
+
+		start := p.pos
+		if p.keyword(string(OperatorOr)) {
+			p.pos = start
+			break
+		}
+
+		p.keyword(string(OperatorAnd))
+
+		clause, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return combineClauses(OperatorAnd, clauses), nil
+}
+
+func (p *fieldedQueryParser) parseUnary() (*FieldedClause, error) {
+	p.skipSpace()
+
+	if p.eof() {
+		return nil, p.errorf("expected a clause")
+	}
+
+	negate := p.input[p.pos] == '-'
+	if negate {
+		p.pos++
+	} else {
+		negate = p.keyword("NOT")
+	}
+
+	if negate {
+		clause, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		clause.Negate = !clause.Negate
+
+		return clause, nil
+	}
+
+	if p.input[p.pos] == '(' {
+		p.pos++
+
+		clause, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+
+		if p.eof() || p.input[p.pos] != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+
+		p.pos++
+
+		return clause, nil
+	}
+
+	return p.parseField()
+}
+
+func isFieldRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+func (p *fieldedQueryParser) parseField() (*FieldedClause, error) {
+	start := p.pos
+
+	for !p.eof() && isFieldRune(p.input[p.pos]) {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return nil, p.errorf("expected a field, got %q", p.input[p.pos])
+	}
+
+	field := string(p.input[start:p.pos])
+
+	if p.eof() || p.input[p.pos] != ':' {
+		return nil, p.errorf("expected ':' after field %q", field)
+	}
+
+	p.pos++
+
+	if p.eof() || unicode.IsSpace(p.input[p.pos]) {
+		return nil, p.errorf("expected a value for field %q", field)
+	}
+
+	switch p.input[p.pos] {
+	case '[':
+		return p.parseRange(field)
+	case '"':
+		value, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+
+		return &FieldedClause{Type: TermClause, Field: field, Value: value}, nil
+	}
+
+	start = p.pos
+
+	for !p.eof() && !unicode.IsSpace(p.input[p.pos]) && p.input[p.pos] != ')' {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return nil, p.errorf("expected a value for field %q", field)
+	}
+
+	return &FieldedClause{Type: TermClause, Field: field, Value: string(p.input[start:p.pos])}, nil
+}
+
+func (p *fieldedQueryParser) parseQuoted() (string, error) {
+	start := p.pos
+	p.pos++
+
+	var sb strings.Builder
+
+	for !p.eof() {
+		r := p.input[p.pos]
+		p.pos++
+
+		switch {
+		case r == '\\' && !p.eof():
+			sb.WriteRune(p.input[p.pos])
+			p.pos++
+		case r == '"':
+			return sb.String(), nil
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	p.pos = start
+
+	return "", p.errorf("unterminated quoted value")
+}
+
+func (p *fieldedQueryParser) parseRange(field string) (*FieldedClause, error) {
+	start := p.pos
+	p.pos++
+
+	for !p.eof() && p.input[p.pos] != ']' {
+		p.pos++
+	}
+
+	if p.eof() {
+		p.pos = start
+		return nil, p.errorf("unterminated range for field %q", field)
+	}
+
+	bounds := strings.Fields(string(p.input[start+1 : p.pos]))
+	if len(bounds) != 3 || bounds[1] != "TO" {
+		p.pos = start
+		return nil, p.errorf("range for field %q must have the form [from TO to]", field)
+	}
+
+	p.pos++
+
+	clause := &FieldedClause{Type: RangeClause, Field: field}
+
+	if bounds[0] != "*" {
+		clause.From = bounds[0]
+	}
+
+	if bounds[2] != "*" {
+		clause.To = bounds[2]
+	}
+
+	return clause, nil
+}
+
+// combineClauses returns a BoolClause with the clauses or the clause itself
+// when there is only one.
+func combineClauses(operator BoolOperator, clauses []*FieldedClause) *FieldedClause {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+
+	return &FieldedClause{Type: BoolClause, Operator: operator, Clauses: clauses}
+}
@@ -0,0 +1,235 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFieldedQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *FieldedClause
+		wantErr bool
+	}{
+		{
+			"term",
+			"dc_subject:war",
+			&FieldedClause{Type: TermClause, Field: "dc_subject", Value: "war"},
+			false,
+		},
+		{
+			"quoted term",
+			`dc_title:"the \"great\" war"`,
+			&FieldedClause{Type: TermClause, Field: "dc_title", Value: `the "great" war`},
+			false,
+		},
+		{
+			"and with range",
+			"dc_subject:war AND dc_date:[1940 TO 1945]",
+			&FieldedClause{
+				Type:     BoolClause,
+				Operator: OperatorAnd,
+				Clauses: []*FieldedClause{
+					{Type: TermClause, Field: "dc_subject", Value: "war"},
+					{Type: RangeClause, Field: "dc_date", From: "1940", To: "1945"},
+				},
+			},
+			false,
+		},
+		{
+			"open range",
+			"dc_date:[* TO 1945]",
+			&FieldedClause{Type: RangeClause, Field: "dc_date", To: "1945"},
+			false,
+		},
+		{
+			"and binds stronger than or",
+			"dc_subject:war dc_type:photo OR NOT dc_type:map",
+			&FieldedClause{
+				Type:     BoolClause,
+				Operator: OperatorOr,
+				Clauses: []*FieldedClause{
+					{
+						Type:     BoolClause,
+						Operator: OperatorAnd,
+						Clauses: []*FieldedClause{
+							{Type: TermClause, Field: "dc_subject", Value: "war"},
+							{Type: TermClause, Field: "dc_type", Value: "photo"},
+						},
+					},
+					{Type: TermClause, Field: "dc_type", Value: "map", Negate: true},
+				},
+			},
+			false,
+		},
+		{
+			"negated group",
+			"-(dc_type:photo OR dc_type:map)",
+			&FieldedClause{
+				Type:     BoolClause,
+				Operator: OperatorOr,
+				Negate:   true,
+				Clauses: []*FieldedClause{
+					{Type: TermClause, Field: "dc_type", Value: "photo"},
+					{Type: TermClause, Field: "dc_type", Value: "map"},
+				},
+			},
+			false,
+		},
+		{"empty", "  ", nil, true},
+		{"missing field", "war AND peace", nil, true},
+		{"missing value", "dc_subject: AND dc_type:photo", nil, true},
+		{"unbalanced parentheses", "(dc_subject:war", nil, true},
+		{"unexpected parenthesis", "dc_subject:war)", nil, true},
+		{"dangling operator", "dc_subject:war AND", nil, true},
+		{"dangling not", "NOT", nil, true},
+		{"trailing not", "dc_title:x NOT", nil, true},
+		{"trailing and not", "dc_title:x AND NOT", nil, true},
+		{"dangling minus", "dc_title:x -", nil, true},
+		{"invalid range", "dc_date:[1940 1945]", nil, true},
+		{"unterminated quote", `dc_title:"the war`, nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFieldedQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFieldedQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFieldedQuery) {
+					t.Errorf("ParseFieldedQuery() error = %v, want %v", err, ErrInvalidFieldedQuery)
+				}
+
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseFieldedQuery() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFieldedClause_ElasticQuery(t *testing.T) {
+	clause, err := ParseFieldedQuery("dc_subject:war AND -meta.spec:[a TO *]")
+	if err != nil {
+		t.Fatalf("ParseFieldedQuery() unexpected error: %s", err)
+	}
+
+	query, err := clause.ElasticQuery()
+	if err != nil {
+		t.Fatalf("FieldedClause.ElasticQuery() unexpected error: %s", err)
+	}
+
+	src, err := query.Source()
+	if err != nil {
+		t.Fatalf("unable to get query source: %s", err)
+	}
+
+	got, _ := json.Marshal(src)
+
+	// nolint:lll
+	want := `{"bool":{"must":[{"nested":{"path":"resources.entries","query":{"bool":{"must":[{"term":{"resources.entries.searchLabel":"dc_subject"}},{"term":{"resources.entries.@value.keyword":"war"}}]}}}},{"bool":{"must_not":{"range":{"meta.spec":{"from":"a","include_lower":true,"include_upper":true,"to":null}}}}}]}}`
+
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("FieldedClause.ElasticQuery() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewSearchRequest_fieldedQuery(t *testing.T) {
+	tests := []struct {
+		name             string
+		param            string
+		qf               string
+		wantFieldedQuery []string
+		wantQueryFilters int
+		wantErr          bool
+	}{
+		{"query filter", "qf[]", "dc_subject:war", nil, 1, false},
+		{"query filter with spaces", "qf[]", "dc_subject:second world war", nil, 1, false},
+		{"query filter with operators", "qf[]", "dc_title:War AND Peace", nil, 1, false},
+		{"query filter with parentheses", "qf[]", "dc_title:(Untitled) OR NOT", nil, 1, false},
+		{"query filter with range syntax", "qf", "dc_date:[1940 TO 1945]", nil, 1, false},
+		{"fielded query", "qf.fielded[]", "dc_subject:war AND dc_date:[1940 TO 1945]", []string{"dc_subject:war AND dc_date:[1940 TO 1945]"}, 0, false},
+		{"fielded query without list", "qf.fielded", "dc_subject:war", []string{"dc_subject:war"}, 0, false},
+		{"invalid fielded query", "qf.fielded[]", "dc_subject:war AND (dc_date:[1940 TO 1945]", nil, 0, true},
+		{"dangling not in fielded query", "qf.fielded[]", "NOT", nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			sr, err := NewSearchRequest(url.Values{tt.param: {tt.qf}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSearchRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(tt.wantFieldedQuery, sr.GetFieldedQuery()); diff != "" {
+				t.Errorf("NewSearchRequest() FieldedQuery mismatch (-want +got):\n%s", diff)
+			}
+
+			if got := len(sr.GetQueryFilter()); got != tt.wantQueryFilters {
+				t.Errorf("NewSearchRequest() QueryFilters = %d, want %d", got, tt.wantQueryFilters)
+			}
+
+			// the fielded queries are kept in the scrollID
+			hex, err := sr.SearchRequestToHex()
+			if err != nil {
+				t.Fatalf("SearchRequestToHex() unexpected error: %s", err)
+			}
+
+			next, err := SearchRequestFromHex(hex)
+			if err != nil {
+				t.Fatalf("SearchRequestFromHex() unexpected error: %s", err)
+			}
+
+			if diff := cmp.Diff(tt.wantFieldedQuery, next.GetFieldedQuery()); diff != "" {
+				t.Errorf("SearchRequestFromHex() FieldedQuery mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestNewSearchRequest_literalQueryFilter guards that qf[] values with the
+// keywords of the fielded query syntax are still filtered literally.
+func TestNewSearchRequest_literalQueryFilter(t *testing.T) {
+	for _, value := range []string{"War AND Peace", "(Untitled)", "Crime OR Punishment", "NOT for sale"} {
+		sr, err := NewSearchRequest(url.Values{"qf[]": {"dc_title:" + value}})
+		if err != nil {
+			t.Fatalf("NewSearchRequest(%q) unexpected error: %s", value, err)
+		}
+
+		filters := sr.GetQueryFilter()
+		if len(filters) != 1 || filters[0].GetSearchLabel() != "dc_title" || filters[0].GetValue() != value {
+			t.Errorf("NewSearchRequest(%q) QueryFilter = %v, want dc_title:%s", value, filters, value)
+		}
+	}
+}
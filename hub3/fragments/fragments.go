@@ -176,7 +176,7 @@ func (fr FragmentRequest) BuildQuery() *elastic.BoolQuery {
 }
 
 // Do executes the fragments request on elasticsearch
-func (fr FragmentRequest) Do(cxt context.Context, client *elastic.Client) (*elastic.SearchResult, error) {
+func (fr FragmentRequest) Do(ctx context.Context, client *elastic.Client) (*elastic.SearchResult, error) {
 	q := fr.BuildQuery()
 	return client.Search().
 		Index(c.Config.ElasticSearch.FragmentIndexName()).
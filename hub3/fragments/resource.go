@@ -232,7 +232,7 @@ func (tq *TreeQuery) SearchPages(sortKey int32) ([]int32, error) {
 // GetPreviousScrollIDs returns scrollIDs up to the cLevel
 // This information can be used to construct the previous search results when
 // both the UnitID and the Label are being queried
-func (tq *TreeQuery) GetPreviousScrollIDs(cLevel string, sr *SearchRequest, pager *ScrollPager) ([]string, error) {
+func (tq *TreeQuery) GetPreviousScrollIDs(ctx context.Context, cLevel string, sr *SearchRequest, pager *ScrollPager) ([]string, error) {
 	previous := []string{}
 	query := elastic.NewBoolQuery()
 
@@ -267,7 +267,7 @@ func (tq *TreeQuery) GetPreviousScrollIDs(cLevel string, sr *SearchRequest, page
 	sr.Tree.FillTree = false
 
 	for {
-		results, err := scroll.Do(context.Background())
+		results, err := scroll.Do(ctx)
 		if err == io.EOF {
 			return previous, nil // all results retrieved
 		}
@@ -420,6 +420,8 @@ type ScrollPager struct {
 	Cursor           int32  `json:"cursor"`
 	Total            int64  `json:"total"`
 	Rows             int32  `json:"rows"`
+	// SearchAfter contains the sort values of the last hit with 'paging=search_after'
+	SearchAfter []interface{} `json:"searchAfter,omitempty"`
 }
 
 // ProtoBuf holds a protobuf encode version of the messageType.
@@ -1489,6 +1491,101 @@ func (fg *FragmentGraph) NewJSONLD() []map[string]interface{} {
 	return fg.JSONLD
 }
 
+// Triples returns all the triples of the Resources in the order of the
+// Resources. The rdf:type triples of each Resource precede its entries.
+// Subjects and objects that start with '_:' are returned as blank nodes.
+func (fg *FragmentGraph) Triples() []*r.Triple {
+	triples := []*r.Triple{}
+
+	for _, rsc := range fg.Resources {
+		s := newTerm(rsc.ID)
+
+		for _, rdfType := range rsc.Types {
+			triples = append(triples, r.NewTriple(s, r.NewResource(RDFType), r.NewResource(rdfType)))
+		}
+
+		for _, entry := range rsc.Entries {
+			if entry.Predicate == "" {
+				continue
+			}
+
+			var o r.Term
+
+			switch {
+			case entry.ID != "":
+				o = newTerm(entry.ID)
+			case entry.DataType != "":
+				o = r.NewLiteralWithDatatype(entry.Value, r.NewResource(entry.DataType))
+			default:
+				o = r.NewLiteralWithLanguage(entry.Value, entry.Language)
+			}
+
+			triples = append(triples, r.NewTriple(s, r.NewResource(entry.Predicate), o))
+		}
+	}
+
+	return triples
+}
+
+// newTerm returns a blank node for ids that start with '_:' and a resource otherwise.
+func newTerm(id string) r.Term {
+	if strings.HasPrefix(id, "_:") {
+		return r.NewBlankNode(strings.TrimPrefix(id, "_:"))
+	}
+
+	return r.NewResource(id)
+}
+
+// NewMetadataItemV1 creates the legacy v1 representation of the FragmentGraph.
+// All triples are flattened into a map of searchLabel to values, ordered by
+// their position in the graph. The header information is added with the
+// legacy 'delving_' prefixed keys.
+func (fg *FragmentGraph) NewMetadataItemV1() *MetadataItemV1 {
+	item := &MetadataItemV1{
+		DocId:   fg.Meta.GetHubID(),
+		DocType: fg.Meta.GetDocType(),
+		Fields:  map[string]*MetadataFieldV1{},
+	}
+
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+
+		field, ok := item.Fields[key]
+		if !ok {
+			field = &MetadataFieldV1{}
+			item.Fields[key] = field
+		}
+
+		field.Field = append(field.Field, value)
+	}
+
+	add("delving_hubId", fg.Meta.GetHubID())
+	add("delving_spec", fg.Meta.GetSpec())
+	add("delving_orgId", fg.Meta.GetOrgID())
+
+	entries := []*ResourceEntry{}
+	for _, rsc := range fg.Resources {
+		entries = append(entries, rsc.Entries...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Order < entries[j].Order
+	})
+
+	for _, entry := range entries {
+		switch entry.EntryType {
+		case "Resource":
+			add(entry.SearchLabel, entry.ID)
+		default:
+			add(entry.SearchLabel, entry.Value)
+		}
+	}
+
+	return item
+}
+
 // NewGrouped returns an inlined version of the FragmentResources in the FragmentGraph
 func (fg *FragmentGraph) NewGrouped() (*FragmentResource, error) {
 	rm := &ResourceMap{make(map[string]*FragmentResource)}
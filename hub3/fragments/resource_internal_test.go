@@ -171,3 +171,45 @@ func Test_hyphenateDate(t *testing.T) {
 		})
 	}
 }
+
+func TestFragmentGraph_Triples(t *testing.T) {
+	fg := &FragmentGraph{
+		Resources: []*FragmentResource{
+			{
+				ID:    "http://example.org/1",
+				Types: []string{"http://example.org/Book"},
+				Entries: []*ResourceEntry{
+					{Value: "title", Language: "en", Predicate: "http://purl.org/dc/elements/1.1/title"},
+					{Value: "1990", DataType: "http://www.w3.org/2001/XMLSchema#gYear", Predicate: "http://purl.org/dc/terms/date"},
+					{ID: "_:b1", Value: "resolved label", Predicate: "http://purl.org/dc/elements/1.1/creator"},
+					{Value: "no predicate"},
+				},
+			},
+			{
+				ID: "_:b1",
+				Entries: []*ResourceEntry{
+					{Value: "creator", Predicate: "http://xmlns.com/foaf/0.1/name"},
+				},
+			},
+		},
+	}
+
+	want := []string{
+		`<http://example.org/1> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Book> .`,
+		`<http://example.org/1> <http://purl.org/dc/elements/1.1/title> "title"@en .`,
+		`<http://example.org/1> <http://purl.org/dc/terms/date> "1990"^^<http://www.w3.org/2001/XMLSchema#gYear> .`,
+		`<http://example.org/1> <http://purl.org/dc/elements/1.1/creator> _:b1 .`,
+		`_:b1 <http://xmlns.com/foaf/0.1/name> "creator" .`,
+	}
+
+	got := fg.Triples()
+	if len(got) != len(want) {
+		t.Fatalf("FragmentGraph.Triples() returned %d triples, want %d", len(got), len(want))
+	}
+
+	for idx, triple := range got {
+		if triple.String() != want[idx] {
+			t.Errorf("FragmentGraph.Triples()[%d] = %s, want %s", idx, triple, want[idx])
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/delving/hub3/ikuzo/domain"
+)
+
+// generatedPrefix matches the search labels with the hashed prefix that is
+// generated for base-URIs without a registered prefix.
+var generatedPrefix = regexp.MustCompile(`^[0-9a-f]{16}_`)
+
+// ErrUnknownSearchLabel is returned when the prefix of a search label is not a
+// registered namespace.
+var ErrUnknownSearchLabel = errors.New("unknown search label prefix")
+
+// SearchLabelResolver expands a search label like 'dc_title' to its full URI.
+// It is implemented by *namespace.Service.
+type SearchLabelResolver interface {
+	DecodeSearchLabel(label string) (string, error)
+}
+
+// ResolveSearchLabels resolves the search labels of the QueryFilters, fielded
+// queries and FacetFields to their URI. The URIs are returned keyed by search label.
+//
+// Only labels in the 'prefix_label' form are resolved. Internal fields like
+// 'tags', 'meta.tags' and 'tree.type' are skipped, and so are the labels with
+// a prefix that is generated at index time for an unknown base-URI, see
+// config.NameSpaceMap.GetSearchLabel. ErrUnknownSearchLabel is returned when a
+// prefix is not registered, so the request can be rejected before it is sent
+// to ElasticSearch.
+func (sr *SearchRequest) ResolveSearchLabels(resolver SearchLabelResolver) (map[string]string, error) {
+	uris := map[string]string{}
+
+	resolve := func(label string) error {
+		if !isSearchLabel(label) || generatedPrefix.MatchString(label) {
+			return nil
+		}
+
+		if _, ok := uris[label]; ok {
+			return nil
+		}
+
+		uri, err := resolver.DecodeSearchLabel(label)
+		if err != nil {
+			if errors.Is(err, domain.ErrNameSpaceNotFound) || errors.Is(err, domain.ErrNameSpaceNotValid) {
+				return fmt.Errorf("%w: %s", ErrUnknownSearchLabel, label)
+			}
+
+			return err
+		}
+
+		uris[label] = uri
+
+		return nil
+	}
+
+	for _, qf := range sr.GetQueryFilter() {
+		switch qf.GetType() {
+		case QueryFilterType_TREEITEM, QueryFilterType_ENTRYTAG:
+			continue
+		}
+
+		labels := []string{qf.GetSearchLabel()}
+
+		if qf.GetLevel1() != nil {
+			labels = append(labels, qf.GetLevel1().GetSearchLabel())
+		}
+
+		if qf.GetLevel2() != nil {
+			labels = append(labels, qf.GetLevel2().GetSearchLabel())
+		}
+
+		for _, label := range labels {
+			if err := resolve(label); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	clauses, err := sr.fieldedQueryClauses()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, clause := range clauses {
+		for _, field := range clause.Fields() {
+			if err := resolve(field); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, ff := range sr.GetFacetField() {
+		if err := resolve(ff.GetField()); err != nil {
+			return nil, err
+		}
+	}
+
+	return uris, nil
+}
+
+// isSearchLabel returns true when the field has the 'prefix_label' form of a search label.
+func isSearchLabel(field string) bool {
+	if strings.Contains(field, ".") {
+		return false
+	}
+
+	idx := strings.Index(field, "_")
+
+	return idx > 0 && idx < len(field)-1
+}
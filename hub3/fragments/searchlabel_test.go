@@ -0,0 +1,115 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/service/x/namespace"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSearchRequest_ResolveSearchLabels(t *testing.T) {
+	ns, err := namespace.NewService(
+		namespace.WithDefaults(),
+		namespace.WithNameSpaces(map[string]string{"musip": "http://www.musip.nl/terms/"}),
+	)
+	if err != nil {
+		t.Fatalf("unable to create namespace.Service; %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		params  url.Values
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			"query filters and facets",
+			url.Values{
+				"qf":          []string{"dc_title:title", "-dc_subject:subject", "tags:tag"},
+				"qf.tree":     []string{"type:series"},
+				"facet.field": []string{"dc_creator", "meta.tags", "tree.type"},
+			},
+			map[string]string{
+				"dc_title":   "http://purl.org/dc/elements/1.1/title",
+				"dc_subject": "http://purl.org/dc/elements/1.1/subject",
+				"dc_creator": "http://purl.org/dc/elements/1.1/creator",
+			},
+			nil,
+		},
+		{
+			"context query filter",
+			url.Values{"qf": []string{"edm_hasMet[]skos_prefLabel:label"}},
+			map[string]string{
+				"edm_hasMet":     "http://www.europeana.eu/schemas/edm/hasMet",
+				"skos_prefLabel": "http://www.w3.org/2004/02/skos/core#prefLabel",
+			},
+			nil,
+		},
+		{
+			"configured prefix",
+			url.Values{"qf": []string{"musip_title:title"}, "facet.field": []string{"musip_maker"}},
+			map[string]string{
+				"musip_title": "http://www.musip.nl/terms/title",
+				"musip_maker": "http://www.musip.nl/terms/maker",
+			},
+			nil,
+		},
+		{
+			"generated prefix",
+			url.Values{"qf": []string{"1a2b3c4d5e6f7a8b_title:title"}},
+			map[string]string{},
+			nil,
+		},
+		{
+			"unknown query filter prefix",
+			url.Values{"qf": []string{"unknown_title:title"}},
+			nil,
+			ErrUnknownSearchLabel,
+		},
+		{
+			"unknown facet prefix",
+			url.Values{"facet.field": []string{"unknown_title"}},
+			nil,
+			ErrUnknownSearchLabel,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			sr, err := NewSearchRequest(tt.params)
+			if err != nil {
+				t.Fatalf("NewSearchRequest() unexpected error = %v", err)
+			}
+
+			got, err := sr.ResolveSearchLabels(ns)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SearchRequest.ResolveSearchLabels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("SearchRequest.ResolveSearchLabels() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
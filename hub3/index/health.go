@@ -0,0 +1,102 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/delving/hub3/config"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// ESHealthCacheTTL is the duration that the result of ESHealth is reused.
+var ESHealthCacheTTL = 5 * time.Second
+
+var (
+	// ErrESDisabled is returned by ESHealth when ElasticSearch is not enabled.
+	ErrESDisabled = errors.New("elasticsearch is not enabled")
+	// ErrESClusterRed is returned by ESHealth when the cluster status is red.
+	ErrESClusterRed = errors.New("elasticsearch cluster status is red")
+)
+
+var esHealth = &healthCache{check: checkESHealth}
+
+// ESHealth returns an error when the ElasticSearch cluster is unreachable or
+// its status is red. The result is cached for ESHealthCacheTTL so readiness
+// probes do not hit the cluster on every call.
+func ESHealth(ctx context.Context) error {
+	return esHealth.get(ctx, ESHealthCacheTTL)
+}
+
+// checkESHealth calls the cluster health API.
+//
+// A separate client is used because the lazily initialized ESClient creates the
+// indices and exits the process when the cluster cannot be reached.
+func checkESHealth(ctx context.Context) error {
+	if !config.Config.ElasticSearch.Enabled {
+		return ErrESDisabled
+	}
+
+	options := []elastic.ClientOptionFunc{
+		elastic.SetURL(config.Config.ElasticSearch.Urls...),
+	}
+
+	if config.Config.ElasticSearch.HasAuthentication() {
+		es := config.Config.ElasticSearch
+		options = append(options, elastic.SetBasicAuth(es.UserName, es.Password))
+	}
+
+	c, err := elastic.NewSimpleClient(options...)
+	if err != nil {
+		return fmt.Errorf("unable to create elasticsearch health client; %w", err)
+	}
+
+	health, err := c.ClusterHealth().Do(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to reach elasticsearch; %w", err)
+	}
+
+	if health.Status == "red" {
+		return ErrESClusterRed
+	}
+
+	return nil
+}
+
+// healthCache reuses the result of a health check until it expires.
+type healthCache struct {
+	check   func(ctx context.Context) error
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+func (hc *healthCache) get(ctx context.Context, ttl time.Duration) error {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if !hc.checked.IsZero() && time.Since(hc.checked) < ttl {
+		return hc.err
+	}
+
+	hc.err = hc.check(ctx)
+	hc.checked = time.Now()
+
+	return hc.err
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthCache(t *testing.T) {
+	var calls int
+
+	hc := &healthCache{check: func(ctx context.Context) error {
+		calls++
+		return ErrESClusterRed
+	}}
+
+	for i := 0; i < 3; i++ {
+		if err := hc.get(context.Background(), time.Minute); !errors.Is(err, ErrESClusterRed) {
+			t.Errorf("healthCache.get() error = %v, want %v", err, ErrESClusterRed)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("healthCache.get() called check %d times, want 1", calls)
+	}
+
+	// expired results are checked again
+	if err := hc.get(context.Background(), 0); !errors.Is(err, ErrESClusterRed) {
+		t.Errorf("healthCache.get() error = %v, want %v", err, ErrESClusterRed)
+	}
+
+	if calls != 2 {
+		t.Errorf("healthCache.get() called check %d times, want 2", calls)
+	}
+}
@@ -15,15 +15,21 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/asdine/storm"
 	"github.com/asdine/storm/q"
 	"github.com/delving/hub3/config"
 )
 
+// RecordDeleteHook is called after an RDFRecord is deleted, e.g. to enqueue a
+// PostHookJob with Deleted=true so the downstream systems stay in sync.
+var RecordDeleteHook func(r *RDFRecord)
+
 // RDFRecord contains all the information about a grouping of RDF triples
 // that are considered a single search record.
 // RDFRecord can be stored in various backends. The default is a Boltdb database
@@ -62,6 +68,27 @@ func NewRDFRecord(hubID string, spec string) RDFRecord {
 	}
 }
 
+// NewRDFRecordValidated creates a new RDFRecord after verifying that the hubID
+// has the orgID_spec_localID format. An error is returned when a separator is
+// missing or one of the parts is empty.
+func NewRDFRecordValidated(hubID string, spec string) (RDFRecord, error) {
+	record := NewRDFRecord(hubID, spec)
+
+	orgID, hubSpec, localID, err := record.ExtractHubID()
+	if err != nil {
+		return RDFRecord{}, err
+	}
+
+	if orgID == "" || hubSpec == "" || localID == "" {
+		return RDFRecord{}, fmt.Errorf(
+			"%s is not properly formatted. The orgid, spec and localid must not be empty",
+			hubID,
+		)
+	}
+
+	return record, nil
+}
+
 // CountRDFRecords returns an int with the records count for spec.
 // If the spec is empty it should return a count for all
 func CountRDFRecords(spec string) int {
@@ -100,9 +127,58 @@ func (r RDFRecord) Save() error {
 	return ORM().Save(&r)
 }
 
-// ExtractHubID extracts the orgId, spec and localId from the HubID
+// RecordsBySpec returns all RDFRecords of the spec.
+func RecordsBySpec(spec string) ([]*RDFRecord, error) {
+	records := []*RDFRecord{}
+
+	err := ORM().Find("Spec", spec, &records)
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		return nil, fmt.Errorf("unable to find RDFRecords for %s; %w", spec, err)
+	}
+
+	return records, nil
+}
+
+// Delete removes the RDFRecord by its HubID from Boltdb.
+// storm.ErrNotFound is returned when the record is not stored.
+func (r *RDFRecord) Delete() error {
+	if err := ORM().DeleteStruct(r); err != nil {
+		return err
+	}
+
+	if RecordDeleteHook != nil {
+		RecordDeleteHook(r)
+	}
+
+	return nil
+}
+
+// SaveRecords saves all RDFRecords to Boltdb in a single transaction.
+// When one of the records cannot be saved none of the records are stored.
+func SaveRecords(records []*RDFRecord) error {
+	tx, err := ORM().Begin(true)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction; %w", err)
+	}
+	defer tx.Rollback()
+
+	modified := time.Now()
+
+	for _, r := range records {
+		r.Modified = modified
+		if err := tx.Save(r); err != nil {
+			return fmt.Errorf("unable to save RDFRecord %s; %w", r.HubID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ExtractHubID extracts the orgId, spec and localId from the HubID.
+// The orgId is the first and the spec the second part of the HubID. The localId
+// is everything after the second underscore, so it can contain underscores.
 func (r RDFRecord) ExtractHubID() (orgID string, spec string, localID string, err error) {
-	parts := strings.Split(r.HubID, "_")
+	parts := strings.SplitN(r.HubID, "_", 3)
 	if len(parts) != 3 {
 		return "", "", "", fmt.Errorf(
 			"%s is not properly formatted. It should have three parts: orgid_spec_localid",
@@ -15,6 +15,11 @@
 package models
 
 import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdine/storm"
 	"github.com/delving/hub3/config"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -34,6 +39,38 @@ var _ = Describe("RDFRecord", func() {
 		})
 	})
 
+	Context("When creating a validated RDFRecord", func() {
+		It("should accept a valid hubID", func() {
+			record, err := NewRDFRecordValidated(hubID, spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(record.HubID).To(Equal(hubID))
+			Expect(record.Spec).To(Equal(spec))
+		})
+
+		It("should reject a hubID with an empty orgID", func() {
+			_, err := NewRDFRecordValidated("_spec_123", spec)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject a hubID with an empty localID", func() {
+			_, err := NewRDFRecordValidated("test_spec_", spec)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject a hubID with missing separators", func() {
+			_, err := NewRDFRecordValidated("testspec_123", spec)
+			Expect(err).To(HaveOccurred())
+
+			_, err = NewRDFRecordValidated("testspec123", spec)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject an empty hubID", func() {
+			_, err := NewRDFRecordValidated("", spec)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Context("when saving an RDFRecord", func() {
 		It("should store the record in BoltDB", func() {
 			record := NewRDFRecord(hubID, spec)
@@ -50,6 +87,72 @@ var _ = Describe("RDFRecord", func() {
 		})
 	})
 
+	Context("when saving RDFRecords in bulk", func() {
+		It("should store all the records in a single transaction", func() {
+			records := []*RDFRecord{}
+			for _, id := range []string{"test_bulk_1", "test_bulk_2"} {
+				record := NewRDFRecord(id, "bulk")
+				records = append(records, &record)
+			}
+
+			err := SaveRecords(records)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(CountRDFRecords("bulk")).To(Equal(2))
+			Expect(records[0].Modified.IsZero()).To(BeFalse())
+		})
+
+		It("should roll back all records when one fails", func() {
+			valid := NewRDFRecord("test_rollback_1", "rollback")
+			invalid := NewRDFRecord("", "rollback")
+
+			err := SaveRecords([]*RDFRecord{&valid, &invalid})
+			Expect(err).To(HaveOccurred())
+			Expect(CountRDFRecords("rollback")).To(Equal(0))
+		})
+	})
+
+	Context("when deleting an RDFRecord", func() {
+		It("should not be found after the delete", func() {
+			record := NewRDFRecord("test_delete_1", "delete")
+			Expect(record.Save()).To(Succeed())
+
+			var deleted []string
+			RecordDeleteHook = func(r *RDFRecord) { deleted = append(deleted, r.HubID) }
+			defer func() { RecordDeleteHook = nil }()
+
+			Expect(record.Delete()).To(Succeed())
+			Expect(deleted).To(Equal([]string{"test_delete_1"}))
+
+			var response RDFRecord
+			err := orm.One("HubID", record.HubID, &response)
+			Expect(err).To(MatchError(storm.ErrNotFound))
+		})
+
+		It("should return not found for an unknown record", func() {
+			record := NewRDFRecord("test_delete_unknown", "delete")
+			Expect(record.Delete()).To(MatchError(storm.ErrNotFound))
+		})
+	})
+
+	Context("when listing the RDFRecords of a spec", func() {
+		It("should return only the records of the spec", func() {
+			for _, id := range []string{"test_list_1", "test_list_2"} {
+				record := NewRDFRecord(id, "list")
+				Expect(record.Save()).To(Succeed())
+			}
+
+			records, err := RecordsBySpec("list")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(HaveLen(2))
+		})
+
+		It("should return an empty list for an unknown spec", func() {
+			records, err := RecordsBySpec("unknown")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(BeEmpty())
+		})
+	})
+
 	Context("Given an HubID", func() {
 		record := RDFRecord{
 			HubID: hubID,
@@ -71,6 +174,20 @@ var _ = Describe("RDFRecord", func() {
 
 	})
 
+	Context("Given an HubID with underscores in the localID", func() {
+		record := RDFRecord{
+			HubID: "org_spec_local_with_underscores",
+		}
+		orgID, spec, localID, err := record.ExtractHubID()
+
+		It("should keep the underscores in the localID", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(orgID).To(Equal("org"))
+			Expect(spec).To(Equal("spec"))
+			Expect(localID).To(Equal("local_with_underscores"))
+		})
+	})
+
 	Context("Given an illegal HubID", func() {
 		record := RDFRecord{
 			HubID: "testspec_123",
@@ -108,3 +225,46 @@ var _ = Describe("RDFRecord", func() {
 	})
 
 })
+
+func benchmarkRecords(n int) []*RDFRecord {
+	records := make([]*RDFRecord, 0, n)
+
+	for i := 0; i < n; i++ {
+		record := NewRDFRecord(fmt.Sprintf("bench_spec_%d", i), "spec")
+		records = append(records, &record)
+	}
+
+	return records
+}
+
+func BenchmarkSaveRecords(b *testing.B) {
+	records := benchmarkRecords(10000)
+
+	b.Run("per record", func(b *testing.B) {
+		orm = newDB(filepath.Join(b.TempDir(), "bench"))
+		defer CloseStorm()
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for _, r := range records {
+				if err := r.Save(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("single transaction", func(b *testing.B) {
+		orm = newDB(filepath.Join(b.TempDir(), "bench"))
+		defer CloseStorm()
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if err := SaveRecords(records); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
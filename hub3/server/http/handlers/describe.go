@@ -0,0 +1,248 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	c "github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/delving/hub3/ikuzo/service/x/namespace"
+	rdf "github.com/kiivihal/rdf2go"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+const (
+	jsonldFormat   = "jsonld"
+	turtleFormat   = "turtle"
+	ntriplesFormat = "ntriples"
+)
+
+// describeMediaTypes maps the supported 'format' values of describe to their media type.
+var describeMediaTypes = map[string]string{
+	jsonldFormat:   "application/ld+json",
+	turtleFormat:   "text/turtle",
+	ntriplesFormat: "application/n-triples",
+}
+
+// pnLocal matches the local names that can be written as a prefixed name in Turtle.
+var pnLocal = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// describe returns all the triples of the FragmentGraph whose subject is the
+// 'uri' query parameter, like a SPARQL DESCRIBE. It returns a 404 when no
+// graph matches.
+//
+// The serialization is selected with the 'format' query parameter, i.e.
+// 'jsonld', 'turtle' or 'ntriples'. Without it the Accept header is used. The
+// default is JSON-LD. Turtle uses the prefixes of the namespace service.
+func (rs *SearchResource) describe(w http.ResponseWriter, r *http.Request) {
+	uri := strings.TrimSpace(r.URL.Query().Get("uri"))
+	if uri == "" {
+		respondWithError(w, r, http.StatusBadRequest, "uri is required", nil)
+		return
+	}
+
+	format, err := describeFormat(r)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid format", err)
+		return
+	}
+
+	query := elastic.NewBoolQuery().
+		Must(
+			elastic.NewTermQuery("meta.docType", fragments.FragmentGraphDocType),
+			elastic.NewTermQuery(c.Config.ElasticSearch.OrgIDKey, c.Config.OrgID),
+			elastic.NewTermQuery("meta.entryURI", uri),
+		)
+
+	res, err := rs.esClient().Search().
+		Index(c.Config.ElasticSearch.GetIndexName()).
+		Query(query).
+		Size(1).
+		Do(r.Context())
+	if err != nil {
+		respondWithError(w, r, searchErrorStatus(err), noSearchResultMsg, err)
+		return
+	}
+
+	records, _, err := decodeFragmentGraphs(res)
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, unableToDecodeRecordsMsg, err)
+		return
+	}
+
+	if len(records) == 0 {
+		respondWithError(w, r, http.StatusNotFound, fmt.Sprintf("%s was not found", uri), nil)
+		return
+	}
+
+	record := records[0]
+
+	var buf bytes.Buffer
+
+	switch format {
+	case turtleFormat:
+		svc, err := rs.service()
+		if err != nil {
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to load namespaces", err)
+			return
+		}
+
+		err = writeTurtle(&buf, svc, record.Triples())
+		if err != nil {
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to render turtle", err)
+			return
+		}
+	case ntriplesFormat:
+		for _, t := range record.Triples() {
+			fmt.Fprintln(&buf, t)
+		}
+	default:
+		if err := json.NewEncoder(&buf).Encode(record.NewJSONLD()); err != nil {
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to render json-ld", err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", describeMediaTypes[format])
+	_, _ = w.Write(buf.Bytes())
+}
+
+// service returns the namespace.Service of the SearchResource or the default
+// namespace.Service when it is not set.
+func (rs *SearchResource) service() (*namespace.Service, error) {
+	if rs.namespaces != nil {
+		return rs.namespaces, nil
+	}
+
+	return namespaceService()
+}
+
+// describeFormat returns the requested serialization of describe. The 'format'
+// query parameter takes precedence over the Accept header.
+func describeFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := describeMediaTypes[format]; !ok {
+			return "", fmt.Errorf("unsupported format %q", format)
+		}
+
+		return format, nil
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		for format, mt := range describeMediaTypes {
+			if mt == mediaType {
+				return format, nil
+			}
+		}
+	}
+
+	return jsonldFormat, nil
+}
+
+// writeTurtle writes the triples grouped by subject as Turtle. The IRIs are
+// written as prefixed names when their base-URI is a permanent namespace of
+// svc. Only the prefixes that are used are declared.
+func writeTurtle(w io.Writer, svc *namespace.Service, triples []*rdf.Triple) error {
+	prefixes := map[string]string{}
+
+	encode := func(term rdf.Term) string {
+		res, ok := term.(*rdf.Resource)
+		if !ok {
+			return term.String()
+		}
+
+		base, local := domain.SplitURI(res.URI)
+		if !pnLocal.MatchString(local) {
+			return term.String()
+		}
+
+		ns, err := svc.GetWithBase(base)
+		if err != nil || ns.Temporary {
+			return term.String()
+		}
+
+		prefixes[ns.Prefix] = ns.Base
+
+		return ns.Prefix + ":" + local
+	}
+
+	var (
+		body    bytes.Buffer
+		subject string
+	)
+
+	for _, t := range triples {
+		s := encode(t.Subject)
+		o := encode(t.Object)
+
+		p := "a"
+		if !t.Predicate.Equal(rdf.NewResource(fragments.RDFType)) {
+			p = encode(t.Predicate)
+		}
+
+		switch {
+		case s == subject:
+			fmt.Fprintf(&body, " ;\n    %s %s", p, o)
+		case subject == "":
+			fmt.Fprintf(&body, "%s\n    %s %s", s, p, o)
+		default:
+			fmt.Fprintf(&body, " .\n\n%s\n    %s %s", s, p, o)
+		}
+
+		subject = s
+	}
+
+	if subject != "" {
+		body.WriteString(" .\n")
+	}
+
+	keys := make([]string, 0, len(prefixes))
+	for prefix := range prefixes {
+		keys = append(keys, prefix)
+	}
+
+	sort.Strings(keys)
+
+	for _, prefix := range keys {
+		if _, err := fmt.Fprintf(w, "@prefix %s: <%s> .\n", prefix, prefixes[prefix]); err != nil {
+			return err
+		}
+	}
+
+	if len(keys) != 0 {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err := body.WriteTo(w)
+
+	return err
+}
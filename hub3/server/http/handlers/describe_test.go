@@ -0,0 +1,174 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/service/x/namespace"
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/elastictest"
+	"github.com/go-chi/chi"
+)
+
+const describeResponse = `{
+  "took": 1,
+  "timed_out": false,
+  "hits": {
+    "total": {"value": 1, "relation": "eq"},
+    "hits": [{
+      "_index": "hub3",
+      "_id": "org_spec_1",
+      "_source": {
+        "meta": {"orgID": "org", "spec": "spec", "hubID": "org_spec_1", "docType": "graph", "entryURI": "http://hub3.test/1"},
+        "resources": [{
+          "id": "http://hub3.test/1",
+          "types": ["http://www.europeana.eu/schemas/edm/ProvidedCHO"],
+          "entries": [
+            {"@value": "first title", "@language": "en", "predicate": "http://purl.org/dc/elements/1.1/title", "order": 1},
+            {"@id": "http://hub3.test/creator", "@value": "creator", "entrytype": "Resource", "predicate": "http://purl.org/dc/elements/1.1/creator", "order": 2}
+          ]
+        }]
+      }
+    }]
+  }
+}`
+
+const emptyResponse = `{
+  "took": 1,
+  "timed_out": false,
+  "hits": {"total": {"value": 0, "relation": "eq"}, "hits": []}
+}`
+
+func TestSearchResource_describe(t *testing.T) {
+	ns, err := namespace.NewService()
+	if err != nil {
+		t.Fatalf("unable to create namespace.Service; %s", err)
+	}
+
+	for prefix, base := range map[string]string{
+		"dc":  "http://purl.org/dc/elements/1.1/",
+		"edm": "http://www.europeana.eu/schemas/edm/",
+	} {
+		if _, err := ns.Add(prefix, base); err != nil {
+			t.Fatalf("unable to add namespace %s; %s", prefix, err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		url             string
+		accept          string
+		esResponse      string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			"turtle",
+			"/api/search/v2/describe?uri=http://hub3.test/1&format=turtle",
+			"",
+			describeResponse,
+			http.StatusOK,
+			"text/turtle",
+			"@prefix dc: <http://purl.org/dc/elements/1.1/> .\n" +
+				"@prefix edm: <http://www.europeana.eu/schemas/edm/> .\n\n" +
+				"<http://hub3.test/1>\n" +
+				"    a edm:ProvidedCHO ;\n" +
+				"    dc:title \"first title\"@en ;\n" +
+				"    dc:creator <http://hub3.test/creator> .\n",
+		},
+		{
+			"n-triples from the accept header",
+			"/api/search/v2/describe?uri=http://hub3.test/1",
+			"application/n-triples",
+			describeResponse,
+			http.StatusOK,
+			"application/n-triples",
+			"<http://hub3.test/1> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.europeana.eu/schemas/edm/ProvidedCHO> .\n" +
+				"<http://hub3.test/1> <http://purl.org/dc/elements/1.1/title> \"first title\"@en .\n" +
+				"<http://hub3.test/1> <http://purl.org/dc/elements/1.1/creator> <http://hub3.test/creator> .\n",
+		},
+		{
+			"json-ld by default",
+			"/api/search/v2/describe?uri=http://hub3.test/1",
+			"*/*",
+			describeResponse,
+			http.StatusOK,
+			"application/ld+json",
+			"",
+		},
+		{
+			"not found",
+			"/api/search/v2/describe?uri=http://hub3.test/unknown",
+			"",
+			emptyResponse,
+			http.StatusNotFound,
+			"",
+			"",
+		},
+		{
+			"missing uri",
+			"/api/search/v2/describe",
+			"",
+			describeResponse,
+			http.StatusBadRequest,
+			"",
+			"",
+		},
+		{
+			"unsupported format",
+			"/api/search/v2/describe?uri=http://hub3.test/1&format=rdfxml",
+			"",
+			describeResponse,
+			http.StatusBadRequest,
+			"",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			client := elastictest.NewResponseClient(t, http.StatusOK, tt.esResponse)
+
+			router := chi.NewRouter()
+			NewSearchResource(ns, SetElasticClient(client)).Routes(router)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("describe() status = %d, want %d; %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantContentType != "" && w.Header().Get("Content-Type") != tt.wantContentType {
+				t.Errorf("describe() Content-Type = %q, want %q", w.Header().Get("Content-Type"), tt.wantContentType)
+			}
+
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("describe() body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -109,9 +109,7 @@ func TreeList(w http.ResponseWriter, r *http.Request) {
 	}
 	searchRequest, err := fragments.NewSearchRequest(r.URL.Query())
 	if err != nil {
-		log.Println("Unable to create Search request")
-		render.Status(r, http.StatusBadRequest)
-		render.PlainText(w, r, err.Error())
+		respondWithError(w, r, http.StatusBadRequest, noSearchRequestMsg, err)
 		return
 	}
 	searchRequest.ItemFormat = fragments.ItemFormatType_TREE
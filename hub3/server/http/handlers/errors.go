@@ -15,8 +15,11 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
+	"github.com/delving/hub3/hub3/fragments"
 	"github.com/go-chi/render"
 )
 
@@ -36,6 +39,7 @@ type ErrResponse struct {
 	StatusText string `json:"status"`          // user-level status message
 	AppCode    int64  `json:"code,omitempty"`  // application-specific error code
 	ErrorText  string `json:"error,omitempty"` // application-level error message, for debugging
+	Param      string `json:"param,omitempty"` // the request parameter with an invalid value
 }
 
 // Render renders the ErrResponse
@@ -79,3 +83,27 @@ type ErrorMessage struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
 }
+
+// respondWithError logs the error and writes an ErrResponse with the given
+// HTTP status code. Handlers must return directly after calling it.
+func respondWithError(w http.ResponseWriter, r *http.Request, status int, msg string, err error) {
+	resp := &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: status,
+		StatusText:     msg,
+	}
+
+	if err != nil {
+		resp.ErrorText = err.Error()
+	}
+
+	var paramErr *fragments.ParamError
+	if errors.As(err, &paramErr) {
+		resp.Param = paramErr.Param
+	}
+
+	log.Printf("%s: %v", msg, err)
+
+	render.Status(r, status)
+	render.JSON(w, r, resp)
+}
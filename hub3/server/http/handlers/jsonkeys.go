@@ -0,0 +1,251 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/go-chi/render"
+)
+
+// KeyNaming is the naming convention of the keys in the JSON search results.
+type KeyNaming string
+
+const (
+	// KeyNamingDefault keeps the keys as they are defined by the response types.
+	KeyNamingDefault KeyNaming = ""
+	// KeyNamingCamelCase converts the keys to camelCase, e.g. 'nextScrollId'.
+	KeyNamingCamelCase KeyNaming = "camelCase"
+	// KeyNamingSnakeCase converts the keys to snake_case, e.g. 'next_scroll_id'.
+	KeyNamingSnakeCase KeyNaming = "snake_case"
+)
+
+const keyNamingKey contextKey = "keyNaming"
+
+// SetJSONKeyNaming sets the naming convention of the keys in the JSON scroll
+// results of the v2 search API. Unknown conventions keep the default keys.
+// The protobuf responses are not changed.
+func SetJSONKeyNaming(naming KeyNaming) SearchOption {
+	return func(rs *SearchResource) {
+		rs.keyNaming = naming
+	}
+}
+
+// withKeyNaming adds the KeyNaming to the context of the request.
+func withKeyNaming(r *http.Request, naming KeyNaming) *http.Request {
+	if naming == KeyNamingDefault {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), keyNamingKey, naming))
+}
+
+// renderScrollResult renders the result as JSON with the KeyNaming from the
+// request context.
+//
+// Only the keys that are JSON field names of fragments.ScrollResultV4 and the
+// types it contains are converted. The keys of maps with data, e.g. the search
+// labels of the flat item fields, are kept as they are.
+func renderScrollResult(w http.ResponseWriter, r *http.Request, result *fragments.ScrollResultV4) {
+	naming, _ := r.Context().Value(keyNamingKey).(KeyNaming)
+
+	convert := keyConverter(naming)
+	if convert == nil {
+		render.JSON(w, r, result)
+		return
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Unable to marshal search result", err)
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Unable to decode search result", err)
+		return
+	}
+
+	render.JSON(w, r, renameKeys(v, scrollResultKeys(), convert))
+}
+
+func keyConverter(naming KeyNaming) func(string) string {
+	switch naming {
+	case KeyNamingCamelCase:
+		return toCamelCase
+	case KeyNamingSnakeCase:
+		return toSnakeCase
+	default:
+		return nil
+	}
+}
+
+// renameKeys recursively converts the keys of the JSON objects in v that are in keys.
+func renameKeys(v interface{}, keys map[string]bool, convert func(string) string) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(value))
+
+		for k, elem := range value {
+			if keys[k] {
+				k = convert(k)
+			}
+
+			renamed[k] = renameKeys(elem, keys, convert)
+		}
+
+		return renamed
+	case []interface{}:
+		for i, elem := range value {
+			value[i] = renameKeys(elem, keys, convert)
+		}
+
+		return value
+	default:
+		return v
+	}
+}
+
+var (
+	resultKeys     map[string]bool
+	resultKeysOnce sync.Once
+)
+
+// scrollResultKeys returns the JSON field names of fragments.ScrollResultV4
+// and all the types that it contains.
+func scrollResultKeys() map[string]bool {
+	resultKeysOnce.Do(func() {
+		resultKeys = map[string]bool{}
+		collectJSONKeys(reflect.TypeOf(fragments.ScrollResultV4{}), resultKeys, map[reflect.Type]bool{})
+	})
+
+	return resultKeys
+}
+
+func collectJSONKeys(t reflect.Type, keys map[string]bool, seen map[reflect.Type]bool) {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		collectJSONKeys(t.Elem(), keys, seen)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	if seen[t] {
+		return
+	}
+
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if name != "" {
+			keys[name] = true
+		} else if !field.Anonymous {
+			keys[field.Name] = true
+		}
+
+		collectJSONKeys(field.Type, keys, seen)
+	}
+}
+
+// splitWords splits a key on underscores, hyphens and case changes.
+// Acronyms are kept together, so 'hubID' is split into 'hub' and 'ID' and
+// 'expandedIDs' into 'expanded' and 'IDs'.
+func splitWords(key string) []string {
+	words := []string{}
+	runes := []rune(key)
+	start := 0
+
+	for i, r := range runes {
+		if r == '_' || r == '-' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+
+			start = i + 1
+
+			continue
+		}
+
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+
+		prev := runes[i-1]
+		nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralSuffix(runes[i+1:])
+
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// isPluralSuffix returns true when runes starts with the 's' of a plural acronym.
+func isPluralSuffix(runes []rune) bool {
+	return runes[0] == 's' && (len(runes) == 1 || !unicode.IsLower(runes[1]))
+}
+
+func toSnakeCase(key string) string {
+	words := splitWords(key)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+
+	return strings.Join(words, "_")
+}
+
+func toCamelCase(key string) string {
+	words := splitWords(key)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+
+		words[i] = word
+	}
+
+	return strings.Join(words, "")
+}
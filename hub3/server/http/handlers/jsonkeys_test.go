@@ -0,0 +1,106 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/elastictest"
+	"github.com/go-chi/chi"
+)
+
+func Test_keyConversion(t *testing.T) {
+	tests := []struct {
+		key       string
+		wantSnake string
+		wantCamel string
+	}{
+		{"pager", "pager", "pager"},
+		{"nextScrollID", "next_scroll_id", "nextScrollId"},
+		{"previousScrollIDs", "previous_scroll_ids", "previousScrollIds"},
+		{"HTTPStatus", "http_status", "httpStatus"},
+		{"hub_id", "hub_id", "hubId"},
+		{"@value", "@value", "@value"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.key, func(t *testing.T) {
+			if got := toSnakeCase(tt.key); got != tt.wantSnake {
+				t.Errorf("toSnakeCase(%q) = %q, want %q", tt.key, got, tt.wantSnake)
+			}
+
+			if got := toCamelCase(tt.key); got != tt.wantCamel {
+				t.Errorf("toCamelCase(%q) = %q, want %q", tt.key, got, tt.wantCamel)
+			}
+		})
+	}
+}
+
+func TestSetJSONKeyNaming(t *testing.T) {
+	tests := []struct {
+		name        string
+		naming      KeyNaming
+		wantPager   string
+		unwantPager string
+	}{
+		{"default", KeyNamingDefault, "nextScrollID", "next_scroll_id"},
+		{"snake_case", KeyNamingSnakeCase, "next_scroll_id", "nextScrollID"},
+		{"camelCase", KeyNamingCamelCase, "nextScrollId", "nextScrollID"},
+		{"unknown", KeyNaming("kebab-case"), "nextScrollID", "next_scroll_id"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			client := elastictest.NewResponseClient(t, http.StatusOK, v1SearchResponse)
+
+			router := chi.NewRouter()
+			NewSearchResource(nil, SetJSONKeyNaming(tt.naming), SetElasticClient(client)).Routes(router)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search/v2?q=title", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("search status = %d, want %d; %s", w.Code, http.StatusOK, w.Body.String())
+			}
+
+			var got struct {
+				Pager map[string]interface{} `json:"pager"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("unable to decode search result; %s", err)
+			}
+
+			pager := got.Pager
+			if pager == nil {
+				t.Fatalf("search result has no pager; %s", w.Body.String())
+			}
+
+			if _, ok := pager[tt.wantPager]; !ok {
+				t.Errorf("pager = %v, want key %q", pager, tt.wantPager)
+			}
+
+			if _, ok := pager[tt.unwantPager]; ok {
+				t.Errorf("pager = %v, unexpected key %q", pager, tt.unwantPager)
+			}
+		})
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2017 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/delving/hub3/ikuzo/service/x/namespace"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+const (
+	prefixRoute      = "/{prefix}"
+	totalCountHeader = "X-Total-Count"
+	// the number of namespaces with a curated and generated prefix
+	permanentCountHeader = "X-Permanent-Count"
+	temporaryCountHeader = "X-Temporary-Count"
+)
+
+var (
+	// svc is the namespace.Service used by the namespace handlers
+	svc     *namespace.Service
+	svcErr  error
+	svcOnce sync.Once
+)
+
+// NameSpaceResource provides the namespace API.
+type NameSpaceResource struct {
+	namespaces *namespace.Service
+}
+
+// NewNameSpaceResource creates a NameSpaceResource. When namespaces is nil a
+// shared namespace.Service with the default namespaces is used.
+func NewNameSpaceResource(namespaces *namespace.Service) *NameSpaceResource {
+	return &NameSpaceResource{namespaces: namespaces}
+}
+
+func RegisterNamespace(router chi.Router) {
+	NewNameSpaceResource(nil).Routes(router)
+}
+
+// Routes registers the namespace routes on the router.
+func (rs *NameSpaceResource) Routes(router chi.Router) {
+	r := chi.NewRouter()
+
+	r.Get("/", rs.listNameSpaces)
+	r.Post("/", rs.createNameSpace)
+	// a prefix must start with a letter, so the routes that start with an
+	// underscore are never shadowed by a namespace prefix
+	r.Post("/_prune", rs.pruneNameSpaces)
+	r.Get("/_stats", rs.nameSpaceStats)
+	r.Get("/_expand", rs.expandCURIE)
+	r.Get("/_searchlabel", rs.searchLabel)
+	r.Get(prefixRoute, rs.getNameSpace)
+	r.Delete(prefixRoute, rs.deleteNameSpace)
+
+	router.Mount("/api/namespaces", r)
+}
+
+// service returns the injected namespace.Service or the shared default.
+func (rs *NameSpaceResource) service() (*namespace.Service, error) {
+	if rs.namespaces != nil {
+		return rs.namespaces, nil
+	}
+
+	return namespaceService()
+}
+
+// namespaceService returns the namespace.Service loaded with the default namespaces.
+// It is safe to call from concurrent requests.
+func namespaceService() (*namespace.Service, error) {
+	svcOnce.Do(func() {
+		svc, svcErr = namespace.NewService(namespace.WithDefaults())
+	})
+
+	return svc, svcErr
+}
+
+// nameSpaceErrorMessage is the body of the namespace API errors. It has the
+// same fields as APIErrorMessage, but the error is rendered as its message.
+type nameSpaceErrorMessage struct {
+	HTTPStatus int    `json:"code"`
+	Message    string `json:"type"`
+	Error      string `json:"error"`
+}
+
+// renderNameSpaceError renders the error with the matching HTTP status code.
+func renderNameSpaceError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, domain.ErrNameSpaceNotValid):
+		status = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNameSpaceNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrNameSpaceDuplicateEntry):
+		status = http.StatusConflict
+	default:
+		log.Printf("%s: %s", msg, err)
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, nameSpaceErrorMessage{
+		HTTPStatus: status,
+		Message:    msg,
+		Error:      err.Error(),
+	})
+}
+
+// listNameSpaces returns a list of the stored namespaces sorted by prefix.
+// When the base query parameter is given only the matching namespace is returned.
+//
+// The list can be paginated with the 'limit' and 'offset' query parameters and
+// filtered with 'temporary'. The total number of matching namespaces is
+// returned in the X-Total-Count header and the number of permanent and
+// temporary namespaces in the X-Permanent-Count and X-Temporary-Count headers.
+func (rs *NameSpaceResource) listNameSpaces(w http.ResponseWriter, r *http.Request) {
+	s, err := rs.service()
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to start namespace service")
+		return
+	}
+
+	if base := r.URL.Query().Get("base"); base != "" {
+		ns, err := s.GetWithBase(base)
+		if err != nil {
+			renderNameSpaceError(w, r, err, fmt.Sprintf("%s was not found", base))
+			return
+		}
+
+		render.JSON(w, r, ns)
+
+		return
+	}
+
+	opts, err := listOptions(r.URL.Query())
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Invalid list parameters")
+		return
+	}
+
+	namespaces, total, err := s.ListPage(opts)
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to list namespaces")
+		return
+	}
+
+	permanent, temporary, err := s.Counts()
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to count namespaces")
+		return
+	}
+
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	w.Header().Set(permanentCountHeader, strconv.Itoa(permanent))
+	w.Header().Set(temporaryCountHeader, strconv.Itoa(temporary))
+	render.JSON(w, r, namespaces)
+}
+
+// listOptions parses the 'limit', 'offset' and 'temporary' query parameters.
+func listOptions(params url.Values) (namespace.ListOptions, error) {
+	var opts namespace.ListOptions
+
+	for key, target := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		v := params.Get(key)
+		if v == "" {
+			continue
+		}
+
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return opts, fmt.Errorf("%s must be a positive integer; %w", key, domain.ErrNameSpaceNotValid)
+		}
+
+		*target = i
+	}
+
+	if v := params.Get("temporary"); v != "" {
+		temporary, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("temporary must be a boolean; %w", domain.ErrNameSpaceNotValid)
+		}
+
+		opts.Temporary = &temporary
+	}
+
+	return opts, nil
+}
+
+// pruneNameSpaces removes the temporary namespaces that have not been used for
+// the duration of the required 'olderThan' query parameter, e.g. '720h'.
+func (rs *NameSpaceResource) pruneNameSpaces(w http.ResponseWriter, r *http.Request) {
+	olderThan, err := time.ParseDuration(r.URL.Query().Get("olderThan"))
+	if err != nil {
+		renderNameSpaceError(w, r, fmt.Errorf("%s; %w", err, domain.ErrNameSpaceNotValid), "Invalid olderThan duration")
+		return
+	}
+
+	s, err := rs.service()
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to start namespace service")
+		return
+	}
+
+	removed, err := s.PruneTemporary(olderThan)
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to prune temporary namespaces")
+		return
+	}
+
+	render.JSON(w, r, map[string]int{"removed": removed})
+}
+
+// nameSpaceStats returns the namespace.NameSpaceStats. When the namespace store
+// cannot be reached a 503 is returned with 'storeReachable' set to false.
+func (rs *NameSpaceResource) nameSpaceStats(w http.ResponseWriter, r *http.Request) {
+	s, err := rs.service()
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to start namespace service")
+		return
+	}
+
+	stats, err := s.Stats()
+	if err != nil {
+		log.Printf("Unable to get namespace stats: %s", err)
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+
+	render.JSON(w, r, stats)
+}
+
+// getNameSpace returns the namespace for the prefix when found or a 404
+func (rs *NameSpaceResource) getNameSpace(w http.ResponseWriter, r *http.Request) {
+	prefix := chi.URLParam(r, "prefix")
+
+	s, err := rs.service()
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to start namespace service")
+		return
+	}
+
+	ns, err := s.GetWithPrefix(prefix)
+	if err != nil {
+		renderNameSpaceError(w, r, err, fmt.Sprintf("%s was not found", prefix))
+		return
+	}
+
+	render.JSON(w, r, ns)
+}
+
+// uriConversion is the response of the expand and searchlabel endpoints.
+type uriConversion struct {
+	Input  string `json:"input"`
+	Result string `json:"result"`
+}
+
+// expandCURIE expands the required 'curie' query parameter, e.g. 'dc:title' or
+// 'dc_title', to the full URI. A 404 is returned when the prefix is unknown.
+func (rs *NameSpaceResource) expandCURIE(w http.ResponseWriter, r *http.Request) {
+	rs.convertURI(w, r, "curie", func(s *namespace.Service, curie string) (string, error) {
+		return s.URI(curie)
+	})
+}
+
+// searchLabel returns the search label of the required 'uri' query parameter,
+// e.g. 'dc_title'. A 404 is returned when the base-URI is unknown.
+func (rs *NameSpaceResource) searchLabel(w http.ResponseWriter, r *http.Request) {
+	rs.convertURI(w, r, "uri", func(s *namespace.Service, uri string) (string, error) {
+		return s.SearchLabel(uri)
+	})
+}
+
+// convertURI renders the uriConversion of the query parameter param.
+func (rs *NameSpaceResource) convertURI(
+	w http.ResponseWriter, r *http.Request, param string,
+	convert func(s *namespace.Service, input string) (string, error),
+) {
+	input := r.URL.Query().Get(param)
+	if input == "" {
+		renderNameSpaceError(w, r, fmt.Errorf("%s is required; %w", param, domain.ErrNameSpaceNotValid), fmt.Sprintf("Missing %s", param))
+		return
+	}
+
+	s, err := rs.service()
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to start namespace service")
+		return
+	}
+
+	result, err := convert(s, input)
+	if err != nil {
+		renderNameSpaceError(w, r, err, fmt.Sprintf("Unable to resolve %s", input))
+		return
+	}
+
+	render.JSON(w, r, uriConversion{Input: input, Result: result})
+}
+
+// createNameSpace adds the prefix and base from the JSON body to the namespace service.
+// With the 'strict' query parameter set to true a 409 is returned when the
+// prefix or base is already linked to another namespace, see
+// namespace.Service.AddStrict.
+func (rs *NameSpaceResource) createNameSpace(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prefix string `json:"prefix"`
+		Base   string `json:"base"`
+	}
+
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		renderNameSpaceError(w, r, fmt.Errorf("%s; %w", err, domain.ErrNameSpaceNotValid), "Unable to decode namespace")
+		return
+	}
+
+	s, err := rs.service()
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to start namespace service")
+		return
+	}
+
+	add := s.Add
+
+	if v := r.URL.Query().Get("strict"); v != "" {
+		strict, err := strconv.ParseBool(v)
+		if err != nil {
+			renderNameSpaceError(w, r, fmt.Errorf("strict must be a boolean; %w", domain.ErrNameSpaceNotValid), "Invalid strict parameter")
+			return
+		}
+
+		if strict {
+			add = s.AddStrict
+		}
+	}
+
+	ns, err := add(req.Prefix, req.Base)
+	if err != nil {
+		renderNameSpaceError(w, r, err, fmt.Sprintf("Unable to add namespace %s", req.Prefix))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, ns)
+}
+
+// deleteNameSpace removes the namespace with the prefix from the namespace service
+func (rs *NameSpaceResource) deleteNameSpace(w http.ResponseWriter, r *http.Request) {
+	prefix := chi.URLParam(r, "prefix")
+
+	s, err := rs.service()
+	if err != nil {
+		renderNameSpaceError(w, r, err, "Unable to start namespace service")
+		return
+	}
+
+	ns, err := s.GetWithPrefix(prefix)
+	if err != nil {
+		renderNameSpaceError(w, r, err, fmt.Sprintf("%s was not found", prefix))
+		return
+	}
+
+	if err := s.Delete(ns); err != nil {
+		renderNameSpaceError(w, r, err, fmt.Sprintf("Unable to delete namespace %s", prefix))
+		return
+	}
+
+	render.NoContent(w, r)
+}
@@ -0,0 +1,382 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/delving/hub3/ikuzo/service/x/namespace"
+	"github.com/go-chi/chi"
+	"github.com/matryer/is"
+)
+
+func TestNameSpaceResource_listNameSpaces(t *testing.T) {
+	svc, err := namespace.NewService()
+	if err != nil {
+		t.Fatalf("unable to create namespace.Service; %s", err)
+	}
+
+	for _, prefix := range []string{"c", "a", "b"} {
+		if _, err := svc.Add(prefix, "http://example.org/"+prefix+"/"); err != nil {
+			t.Fatalf("unable to add namespace; %s", err)
+		}
+	}
+
+	if _, err := svc.Add("", "http://example.org/temporary/"); err != nil {
+		t.Fatalf("unable to add namespace; %s", err)
+	}
+
+	router := chi.NewRouter()
+	NewNameSpaceResource(svc).Routes(router)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantTotal  string
+		want       []string
+	}{
+		{"paginated", "/api/namespaces?temporary=false&limit=2&offset=1", http.StatusOK, "3", []string{"b", "c"}},
+		{"temporary", "/api/namespaces?temporary=true", http.StatusOK, "1", nil},
+		{"all", "/api/namespaces", http.StatusOK, "4", nil},
+		{"invalid limit", "/api/namespaces?limit=ten", http.StatusBadRequest, "", nil},
+		{"invalid temporary", "/api/namespaces?temporary=maybe", http.StatusBadRequest, "", nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			is.Equal(w.Code, tt.wantStatus)
+			is.Equal(w.Header().Get(totalCountHeader), tt.wantTotal)
+
+			if w.Code == http.StatusOK {
+				is.Equal(w.Header().Get(permanentCountHeader), "3")
+				is.Equal(w.Header().Get(temporaryCountHeader), "1")
+			}
+
+			if tt.want == nil {
+				return
+			}
+
+			var got []*domain.NameSpace
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+
+			prefixes := []string{}
+			for _, ns := range got {
+				prefixes = append(prefixes, ns.Prefix)
+			}
+
+			is.Equal(prefixes, tt.want)
+		})
+	}
+}
+
+func TestNameSpaceResource_pruneNameSpaces(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := namespace.NewService()
+	is.NoErr(err)
+
+	lastUsed := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	_, err = svc.Load(strings.NewReader(`[{
+		"prefix": "ns1", "base": "http://example.org/temporary/", "temporary": true, "lastUsed": "` + lastUsed + `"
+	}]`))
+	is.NoErr(err)
+
+	router := chi.NewRouter()
+	NewNameSpaceResource(svc).Routes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/namespaces/_prune", nil))
+	is.Equal(w.Code, http.StatusBadRequest)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/namespaces/_prune?olderThan=30m", nil))
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Body.String(), "{\"removed\":1}\n")
+	is.Equal(svc.Len(), 0)
+}
+
+func TestNameSpaceResource_nameSpaceStats(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := namespace.NewService()
+	is.NoErr(err)
+
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+	_, err = svc.Add("", "http://example.org/temporary/")
+	is.NoErr(err)
+
+	router := chi.NewRouter()
+	NewNameSpaceResource(svc).Routes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/namespaces/_stats", nil))
+	is.Equal(w.Code, http.StatusOK)
+
+	var stats namespace.NameSpaceStats
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &stats))
+	is.Equal(stats, namespace.NameSpaceStats{StoreReachable: true, NameSpaces: 2, Temporary: 1})
+}
+
+func TestNameSpaceResource_convertURI(t *testing.T) {
+	svc, err := namespace.NewService()
+	if err != nil {
+		t.Fatalf("unable to create namespace.Service; %s", err)
+	}
+
+	if _, err := svc.Add("skos", "http://www.w3.org/2004/02/skos/core#"); err != nil {
+		t.Fatalf("unable to add namespace; %s", err)
+	}
+
+	router := chi.NewRouter()
+	NewNameSpaceResource(svc).Routes(router)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		want       uriConversion
+	}{
+		{
+			"expand curie",
+			"/api/namespaces/_expand?curie=skos:prefLabel",
+			http.StatusOK,
+			uriConversion{Input: "skos:prefLabel", Result: "http://www.w3.org/2004/02/skos/core#prefLabel"},
+		},
+		{
+			"expand search label",
+			"/api/namespaces/_expand?curie=skos_prefLabel",
+			http.StatusOK,
+			uriConversion{Input: "skos_prefLabel", Result: "http://www.w3.org/2004/02/skos/core#prefLabel"},
+		},
+		{"expand unknown prefix", "/api/namespaces/_expand?curie=dc:title", http.StatusNotFound, uriConversion{}},
+		{"expand without prefix", "/api/namespaces/_expand?curie=title", http.StatusBadRequest, uriConversion{}},
+		{"expand missing curie", "/api/namespaces/_expand", http.StatusBadRequest, uriConversion{}},
+		{
+			"search label",
+			"/api/namespaces/_searchlabel?uri=" + url.QueryEscape("http://www.w3.org/2004/02/skos/core#prefLabel"),
+			http.StatusOK,
+			uriConversion{Input: "http://www.w3.org/2004/02/skos/core#prefLabel", Result: "skos_prefLabel"},
+		},
+		{
+			"search label unknown base",
+			"/api/namespaces/_searchlabel?uri=" + url.QueryEscape("http://purl.org/dc/elements/1.1/title"),
+			http.StatusNotFound,
+			uriConversion{},
+		},
+		{"search label missing uri", "/api/namespaces/_searchlabel", http.StatusBadRequest, uriConversion{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			is.Equal(w.Code, tt.wantStatus)
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got uriConversion
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+			is.Equal(got, tt.want)
+		})
+	}
+}
+
+func TestNameSpaceResource_routes(t *testing.T) {
+	const dcBase = "http://purl.org/dc/elements/1.1/"
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		wantStatus int
+		wantPrefix string
+	}{
+		{"create", http.MethodPost, "/api/namespaces", `{"prefix": "skos", "base": "http://www.w3.org/2004/02/skos/core#"}`, http.StatusCreated, "skos"},
+		{"create existing pair", http.MethodPost, "/api/namespaces", `{"prefix": "dc", "base": "` + dcBase + `"}`, http.StatusCreated, "dc"},
+		{"create invalid base", http.MethodPost, "/api/namespaces", `{"prefix": "skos", "base": "skos"}`, http.StatusBadRequest, ""},
+		{"create without base", http.MethodPost, "/api/namespaces", `{"prefix": "skos"}`, http.StatusBadRequest, ""},
+		{"create invalid body", http.MethodPost, "/api/namespaces", `{"prefix":`, http.StatusBadRequest, ""},
+		{"create strict", http.MethodPost, "/api/namespaces?strict=true", `{"prefix": "dcterms", "base": "http://purl.org/dc/terms/"}`, http.StatusCreated, "dcterms"},
+		{"create strict prefix conflict", http.MethodPost, "/api/namespaces?strict=true", `{"prefix": "dc", "base": "http://purl.org/dc/terms/"}`, http.StatusConflict, ""},
+		{"create strict base conflict", http.MethodPost, "/api/namespaces?strict=true", `{"prefix": "dce", "base": "` + dcBase + `"}`, http.StatusConflict, ""},
+		{"create invalid strict", http.MethodPost, "/api/namespaces?strict=maybe", `{"prefix": "dc", "base": "` + dcBase + `"}`, http.StatusBadRequest, ""},
+		{"get by prefix", http.MethodGet, "/api/namespaces/dc", "", http.StatusOK, "dc"},
+		{"get unknown prefix", http.MethodGet, "/api/namespaces/unknown", "", http.StatusNotFound, ""},
+		{"get by base", http.MethodGet, "/api/namespaces?base=" + url.QueryEscape(dcBase), "", http.StatusOK, "dc"},
+		{"get unknown base", http.MethodGet, "/api/namespaces?base=" + url.QueryEscape("http://example.org/unknown/"), "", http.StatusNotFound, ""},
+		{"delete", http.MethodDelete, "/api/namespaces/dc", "", http.StatusNoContent, ""},
+		{"delete unknown prefix", http.MethodDelete, "/api/namespaces/unknown", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			svc, err := namespace.NewService()
+			is.NoErr(err)
+
+			_, err = svc.Add("dc", dcBase)
+			is.NoErr(err)
+
+			router := chi.NewRouter()
+			NewNameSpaceResource(svc).Routes(router)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+
+			is.Equal(w.Code, tt.wantStatus)
+
+			if tt.wantPrefix == "" {
+				return
+			}
+
+			var got domain.NameSpace
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+			is.Equal(got.Prefix, tt.wantPrefix)
+
+			stored, err := svc.GetWithPrefix(tt.wantPrefix)
+			is.NoErr(err)
+			is.Equal(stored.Base, got.Base)
+		})
+	}
+
+	t.Run("prefixes are not shadowed by the API routes", func(t *testing.T) {
+		is := is.New(t)
+
+		svc, err := namespace.NewService()
+		is.NoErr(err)
+
+		router := chi.NewRouter()
+		NewNameSpaceResource(svc).Routes(router)
+
+		for _, prefix := range []string{"expand", "searchlabel", "prune", "stats"} {
+			_, err = svc.Add(prefix, "http://example.org/"+prefix+"/")
+			is.NoErr(err)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/namespaces/"+prefix, nil))
+			is.Equal(w.Code, http.StatusOK)
+
+			var got domain.NameSpace
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+			is.Equal(got.Prefix, prefix)
+		}
+	})
+
+	t.Run("deleted namespace is not found", func(t *testing.T) {
+		is := is.New(t)
+
+		svc, err := namespace.NewService()
+		is.NoErr(err)
+
+		_, err = svc.Add("dc", dcBase)
+		is.NoErr(err)
+
+		router := chi.NewRouter()
+		NewNameSpaceResource(svc).Routes(router)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/namespaces/dc", nil))
+		is.Equal(w.Code, http.StatusNoContent)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/namespaces/dc", nil))
+		is.Equal(w.Code, http.StatusNotFound)
+		is.Equal(svc.Len(), 0)
+	})
+}
+
+func TestNameSpaceResource_errorBody(t *testing.T) {
+	svc, err := namespace.NewService()
+	if err != nil {
+		t.Fatalf("unable to create namespace.Service; %s", err)
+	}
+
+	router := chi.NewRouter()
+	NewNameSpaceResource(svc).Routes(router)
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		want   nameSpaceErrorMessage
+	}{
+		{
+			"unknown prefix",
+			http.MethodGet, "/api/namespaces/dc", "",
+			nameSpaceErrorMessage{
+				HTTPStatus: http.StatusNotFound,
+				Message:    "dc was not found",
+				Error:      "namespace not found",
+			},
+		},
+		{
+			"missing base",
+			http.MethodPost, "/api/namespaces", `{"prefix": "dc"}`,
+			nameSpaceErrorMessage{
+				HTTPStatus: http.StatusBadRequest,
+				Message:    "Unable to add namespace dc",
+				Error:      "base is required; prefix or base not valid",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+
+			is.Equal(w.Code, tt.want.HTTPStatus)
+
+			var got nameSpaceErrorMessage
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+			is.Equal(got, tt.want)
+		})
+	}
+}
@@ -15,12 +15,15 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	log "log"
+	"mime"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -33,39 +36,137 @@ import (
 	"github.com/delving/hub3/hub3/fragments"
 	"github.com/delving/hub3/hub3/index"
 	"github.com/delving/hub3/ikuzo/service/x/bulk"
+	"github.com/delving/hub3/ikuzo/service/x/namespace"
 	"github.com/delving/hub3/ikuzo/storage/x/memory"
+	"github.com/die-net/lrucache"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 	elastic "github.com/olivere/elastic/v7"
+	"github.com/rs/zerolog/hlog"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
 var (
-	noSearchServiceMsg        = "Unable to create Search Service: %v"
-	unexpectedResponseMsg     = "expected response != nil; got: %v"
-	unableToAddQueryFilterMsg = "Unable to add QueryFilter: %v"
+	noSearchRequestMsg        = "Unable to create Search Request"
+	noSearchServiceMsg        = "Unable to create Search Service"
+	noSearchResultMsg         = "Unable to get search result from Elasticsearch"
+	unexpectedResponseMsg     = "expected response != nil"
+	unableToAddQueryFilterMsg = "Unable to add QueryFilter"
+	unableToDecodeRecordsMsg  = "Unable to decode records"
+	unableToDecodeRecordMsg   = "Unable to decode record"
+	unableToGroupRecordMsg    = "Unable to render grouped resources"
 )
 
+// defaultESClient returns the elastic.Client of the search handlers when no
+// client is set with SetElasticClient.
+var defaultESClient = index.ESClient
+
 type contextKey string
 
+const (
+	protobufFormat            = "protobuf"
+	protobufStreamFormat      = "protobuf-stream"
+	csvFormat                 = "csv"
+	protobufContentType       = "application/x-protobuf"
+	protobufStreamContentType = "application/x-protobuf; delimited=true"
+)
+
 const retryKey contextKey = "retry"
 
+// SearchResource provides the search API.
+//
+// When a namespace.Service is set, the search labels of the query filters and
+// facets are resolved before the request is sent to Elasticsearch, so requests
+// with unknown prefixes are rejected with a 400.
+type SearchResource struct {
+	namespaces  *namespace.Service
+	client      *elastic.Client
+	cache       *lrucache.LruCache
+	keyNaming   KeyNaming
+	defaultRows int
+	maxRows     int
+	rejectRows  bool
+}
+
+// SearchOption is a closure to configure the SearchResource.
+// It is used in NewSearchResource.
+type SearchOption func(*SearchResource)
+
+// NewSearchResource creates a SearchResource. When namespaces is nil the search
+// labels are not validated.
+func NewSearchResource(namespaces *namespace.Service, options ...SearchOption) *SearchResource {
+	rs := &SearchResource{namespaces: namespaces}
+
+	for _, option := range options {
+		option(rs)
+	}
+
+	return rs
+}
+
+// SetElasticClient sets the elastic.Client that is used for all the search
+// requests of the SearchResource, e.g. to point an organization at its own
+// cluster. The default is the shared client of index.ESClient.
+func SetElasticClient(client *elastic.Client) SearchOption {
+	return func(rs *SearchResource) {
+		rs.client = client
+	}
+}
+
+// esClient returns the elastic.Client of the SearchResource.
+func (rs *SearchResource) esClient() *elastic.Client {
+	if rs.client != nil {
+		return rs.client
+	}
+
+	return defaultESClient()
+}
+
+// SetDefaultRows sets the number of search results per page when the request
+// has no 'rows' parameter. The default is 16.
+func SetDefaultRows(rows int) SearchOption {
+	return func(rs *SearchResource) {
+		rs.defaultRows = rows
+	}
+}
+
+// SetMaxRows sets the maximum number of search results per page. Larger 'rows'
+// values are clamped to the maximum. The default is 1000.
+func SetMaxRows(rows int) SearchOption {
+	return func(rs *SearchResource) {
+		rs.maxRows = rows
+	}
+}
+
+// SetRejectExceedingRows rejects requests with more 'rows' than the maximum
+// with a 400 instead of clamping them.
+func SetRejectExceedingRows() SearchOption {
+	return func(rs *SearchResource) {
+		rs.rejectRows = true
+	}
+}
+
+// RegisterSearch registers the search routes without search label validation.
 func RegisterSearch(router chi.Router) {
+	NewSearchResource(nil).Routes(router)
+}
+
+// Routes registers the search routes on the router.
+func (rs *SearchResource) Routes(router chi.Router) {
 	r := chi.NewRouter()
 
 	// throttle queries on elasticsearch
 	r.Use(middleware.Throttle(100))
 
-	r.Get("/v2", GetScrollResult)
-	r.Get("/v2/{id}", func(w http.ResponseWriter, r *http.Request) {
-		getSearchRecord(w, r)
-		return
-	})
+	r.Get("/suggest", rs.getSuggestions)
+	r.Get("/v2", rs.getScrollResult)
+	r.Get("/v2/describe", rs.describe)
+	r.Get("/v2/{id}", rs.getSearchRecord)
 
-	r.Get("/v1", func(w http.ResponseWriter, r *http.Request) {
-		render.JSON(w, r, &ErrorMessage{"not enabled", ""})
-		return
-	})
+	r.Get("/v1", rs.getSearchResultV1)
 	r.Get("/v1/{id}", func(w http.ResponseWriter, r *http.Request) {
 		render.JSON(w, r, &ErrorMessage{"not enabled", ""})
 		return
@@ -75,25 +176,66 @@ func RegisterSearch(router chi.Router) {
 
 }
 
+// GetScrollResult returns the search results without search label validation.
 func GetScrollResult(w http.ResponseWriter, r *http.Request) {
-	searchRequest, err := fragments.NewSearchRequest(r.URL.Query())
+	NewSearchResource(nil).getScrollResult(w, r)
+}
+
+func (rs *SearchResource) getScrollResult(w http.ResponseWriter, r *http.Request) {
+	searchRequest, err := rs.newSearchRequest(r.URL.Query())
 	if err != nil {
-		log.Println("Unable to create Search request")
-		render.Status(r, http.StatusBadRequest)
-		render.PlainText(w, r, err.Error())
+		respondWithError(w, r, http.StatusBadRequest, noSearchRequestMsg, err)
+		return
+	}
+
+	r = withKeyNaming(r, rs.keyNaming)
+
+	if rs.cache != nil {
+		rs.cachedSearch(w, r, searchRequest)
 		return
 	}
-	ProcessSearchRequest(w, r, searchRequest)
+
+	rs.processSearchRequest(w, r, searchRequest)
 	return
 }
 
-//
+// newSearchRequest creates the fragments.SearchRequest from the URL parameters
+// and resolves its search labels when a namespace.Service is set.
+func (rs *SearchResource) newSearchRequest(params url.Values) (*fragments.SearchRequest, error) {
+	searchRequest, err := fragments.NewSearchRequest(
+		params,
+		fragments.WithDefaultRows(rs.defaultRows),
+		fragments.WithMaxRows(rs.maxRows, rs.rejectRows),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if rs.namespaces != nil {
+		if _, err := searchRequest.ResolveSearchLabels(rs.namespaces); err != nil {
+			return nil, err
+		}
+	}
+
+	return searchRequest, nil
+}
+
+// ProcessSearchRequest executes the fragments.SearchRequest with the default
+// elastic.Client and renders the results.
 func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest *fragments.SearchRequest) {
+	NewSearchResource(nil).processSearchRequest(w, r, searchRequest)
+}
+
+func (rs *SearchResource) processSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest *fragments.SearchRequest) {
+	// the parsed request is echoed before it becomes an Elasticsearch query
+	if r.URL.Query().Get("echo") == "request" {
+		echoSearchRequest(w, r, searchRequest)
+		return
+	}
 
-	s, fub, err := searchRequest.ElasticSearchService(index.ESClient())
+	s, fub, err := searchRequest.ElasticSearchService(rs.esClient())
 	if err != nil {
-		log.Printf(noSearchServiceMsg, err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondWithError(w, r, http.StatusBadRequest, noSearchServiceMsg, err)
 		return
 	}
 
@@ -104,10 +246,9 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 	echoRequest := r.URL.Query().Get("echo")
 	if err != nil {
 		if echoRequest != "" {
-			echo, err := searchRequest.Echo(echoRequest, res.TotalHits())
-			if err != nil {
-				log.Println("Unable to echo request")
-				log.Println(err)
+			echo, echoErr := searchRequest.Echo(echoRequest, res.TotalHits())
+			if echoErr != nil {
+				respondWithError(w, r, http.StatusBadRequest, "Unable to echo request", echoErr)
 				return
 			}
 			if echo != nil {
@@ -115,19 +256,18 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 				return
 			}
 		}
-		log.Println("Unable to get search result.")
-		log.Println(err)
+		respondWithError(w, r, searchErrorStatus(err), noSearchResultMsg, err)
 		return
 	}
 	if res == nil {
-		log.Printf(unexpectedResponseMsg, res)
+		respondWithError(w, r, http.StatusBadGateway, unexpectedResponseMsg, nil)
 		return
 	}
 
 	if searchRequest.Peek != "" {
 		aggs, err := searchRequest.DecodeFacets(res, nil)
 		if err != nil {
-			log.Printf("Unable to decode facets: %#v", err)
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to decode facets", err)
 			return
 		}
 		peek := make(map[string]int64)
@@ -139,39 +279,40 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 
 		result := &fragments.ScrollResultV4{}
 		result.Peek = peek
-		render.JSON(w, r, result)
+		renderScrollResult(w, r, result)
 		return
 	}
 
 	if searchRequest.CollapseOn != "" {
 		records, err := decodeCollapsed(res, searchRequest)
 		if err != nil {
-			log.Printf("Unable to render collapse")
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to render collapse", err)
 			return
 		}
 		result := &fragments.ScrollResultV4{}
 		result.Collapsed = records
 		result.Pager = &fragments.ScrollPager{Total: res.TotalHits()}
-		render.JSON(w, r, result)
+		renderScrollResult(w, r, result)
 		return
 	}
 
 	records, searchAfter, err := decodeFragmentGraphs(res)
-	searchAfterBin, err := searchRequest.CreateBinKey(searchAfter)
 	if err != nil {
-		log.Printf("Unable to encode searchAfter")
+		respondWithError(w, r, http.StatusInternalServerError, unableToDecodeRecordsMsg, err)
 		return
 	}
 
-	searchRequest.SearchAfter = searchAfterBin
+	searchAfterBin, err := searchRequest.CreateBinKey(searchAfter)
 	if err != nil {
-		log.Printf("Unable to decode records")
+		respondWithError(w, r, http.StatusInternalServerError, "Unable to encode searchAfter", err)
 		return
 	}
 
+	searchRequest.SearchAfter = searchAfterBin
+
 	pager, err := searchRequest.ScrollPagers(res.TotalHits())
 	if err != nil {
-		log.Println("Unable to create Scroll Pager. ")
+		respondWithError(w, r, http.StatusInternalServerError, "Unable to create Scroll Pager", err)
 		return
 	}
 
@@ -191,9 +332,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 			retryCount = interface{}(0)
 		}
 		if retryCount.(int) > 3 {
-			msg := "empty response from elasticsearch. failed after 3 tries"
-			log.Println(msg)
-			http.Error(w, msg, http.StatusInternalServerError)
+			respondWithError(w, r, http.StatusBadGateway, "empty response from elasticsearch. failed after 3 tries", nil)
 			return
 		}
 		ctx := context.WithValue(r.Context(), retryKey, retryCount.(int)+1)
@@ -205,8 +344,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 	if echoRequest != "" {
 		echo, err := searchRequest.Echo(echoRequest, res.TotalHits())
 		if err != nil {
-			log.Println("Unable to echo request")
-			log.Println(err)
+			respondWithError(w, r, http.StatusBadRequest, "Unable to echo request", err)
 			return
 		}
 		if echo != nil {
@@ -223,7 +361,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 		}
 
 		if err != nil {
-			http.Error(w, "unable to decode ScrollID", http.StatusInternalServerError)
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to decode ScrollID", err)
 			return
 		}
 		if echoRequest != "searchAfter" {
@@ -232,8 +370,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 		}
 		sa, err := sr.DecodeSearchAfter()
 		if err != nil {
-			log.Printf("unable to decode searchAfter: %#v", err)
-			http.Error(w, "unable to decode next SearchAfter", http.StatusInternalServerError)
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to decode next SearchAfter", err)
 			return
 		}
 		render.JSON(w, r, sa)
@@ -246,26 +383,19 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 		src := reflect.NewAt(ss.Type(), unsafe.Pointer(ss.UnsafeAddr())).Elem().Interface().(*elastic.SearchSource)
 		srcMap, err := src.Source()
 		if err != nil {
-			log.Printf("Unable to decode SearchSource: got %s", err)
-			http.Error(w, "unable to decode next SearchSource", http.StatusInternalServerError)
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to decode SearchSource", err)
 			return
 		}
 		render.JSON(w, r, srcMap)
 		return
-	case "request":
-		dump, err := httputil.DumpRequest(r, true)
-		if err != nil {
-			msg := fmt.Sprintf("Unable to dump request: %s", err)
-			log.Print(msg)
-			render.JSON(w, r, APIErrorMessage{
-				HTTPStatus: http.StatusBadRequest,
-				Message:    fmt.Sprint(msg),
-				Error:      err,
-			})
-			return
-		}
+	}
 
-		render.PlainText(w, r, string(dump))
+	switch responseFormat(r) {
+	case protobufFormat, protobufStreamFormat:
+		streamProtobuf(w, r, records)
+		return
+	case csvFormat:
+		renderCSV(w, r, records)
 		return
 	}
 
@@ -292,9 +422,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 			rec.NewJSONLD()
 			graph, err := json.Marshal(rec.JSONLD)
 			if err != nil {
-				render.Status(r, http.StatusInternalServerError)
-				log.Printf("Unable to marshal json-ld to string : %s\n", err.Error())
-				render.PlainText(w, r, err.Error())
+				respondWithError(w, r, http.StatusInternalServerError, "Unable to marshal json-ld to string", err)
 				return
 			}
 
@@ -310,9 +438,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 
 			bytes, err := json.Marshal(action)
 			if err != nil {
-				render.Status(r, http.StatusInternalServerError)
-				log.Printf("Unable to create Bulkactions: %s\n", err.Error())
-				render.PlainText(w, r, err.Error())
+				respondWithError(w, r, http.StatusInternalServerError, "Unable to create Bulkactions", err)
 				return
 			}
 
@@ -333,8 +459,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 
 		textQuery, textQueryErr = memory.NewTextQueryFromString(searchRequest.Query)
 		if textQueryErr != nil {
-			log.Printf("unable to build text query: %s\n", err.Error())
-			http.Error(w, textQueryErr.Error(), http.StatusInternalServerError)
+			respondWithError(w, r, http.StatusBadRequest, "Unable to build text query", textQueryErr)
 			return
 		}
 	}
@@ -381,8 +506,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 
 			textQuery, textQueryErr = memory.NewTextQueryFromString(searchRequest.Tree.Query)
 			if textQueryErr != nil {
-				log.Printf("unable to build text query: %s\n", err.Error())
-				http.Error(w, textQueryErr.Error(), http.StatusInternalServerError)
+				respondWithError(w, r, http.StatusBadRequest, "Unable to build text query", textQueryErr)
 				return
 			}
 		}
@@ -396,8 +520,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 				leaf := records[0].Tree
 				pages, err := searchRequest.Tree.SearchPages(int32(leaf.SortKey))
 				if err != nil {
-					log.Printf("Unable to get searchPages: %v", err)
-					http.Error(w, err.Error(), http.StatusBadRequest)
+					respondWithError(w, r, http.StatusBadRequest, "Unable to get searchPages", err)
 					return
 				}
 
@@ -410,28 +533,28 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 				m, _ := url.ParseQuery(qs)
 				sr, _ := fragments.NewSearchRequest(m)
 				sr.Tree.WithFields = searchRequest.Tree.WithFields
+				sr.Index = searchRequest.Index
 
 				err = sr.AddQueryFilter(
 					fmt.Sprintf("%s:%s", config.Config.ElasticSearch.SpecKey, searchRequest.Tree.GetSpec()),
 					false,
 				)
 				if err != nil {
-					log.Printf(unableToAddQueryFilterMsg, err)
-					http.Error(w, err.Error(), http.StatusBadRequest)
+					respondWithError(w, r, http.StatusBadRequest, unableToAddQueryFilterMsg, err)
 					return
 				}
-				s, _, err := sr.ElasticSearchService(index.ESClient())
+				s, _, err := sr.ElasticSearchService(rs.esClient())
 				if err != nil {
-					log.Printf(noSearchServiceMsg, err)
-					http.Error(w, err.Error(), http.StatusBadRequest)
+					respondWithError(w, r, http.StatusBadRequest, noSearchServiceMsg, err)
 					return
 				}
 				res, err := s.Do(r.Context())
 				if err != nil {
+					respondWithError(w, r, searchErrorStatus(err), noSearchResultMsg, err)
 					return
 				}
 				if res == nil {
-					log.Printf(unexpectedResponseMsg, res)
+					respondWithError(w, r, http.StatusBadGateway, unexpectedResponseMsg, nil)
 					return
 				}
 				paging.HitsTotalCount = int32(res.TotalHits())
@@ -440,6 +563,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 
 				records, _, err = decodeFragmentGraphs(res)
 				if err != nil {
+					respondWithError(w, r, http.StatusInternalServerError, unableToDecodeRecordsMsg, err)
 					return
 				}
 
@@ -454,28 +578,28 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 				m, _ := url.ParseQuery(qs)
 				sr, _ := fragments.NewSearchRequest(m)
 				sr.Tree.WithFields = searchRequest.Tree.WithFields
+				sr.Index = searchRequest.Index
 
 				err := sr.AddQueryFilter(
 					fmt.Sprintf("%s:%s", config.Config.ElasticSearch.SpecKey, searchRequest.Tree.GetSpec()),
 					false,
 				)
 				if err != nil {
-					log.Printf(unableToAddQueryFilterMsg, err)
-					http.Error(w, err.Error(), http.StatusBadRequest)
+					respondWithError(w, r, http.StatusBadRequest, unableToAddQueryFilterMsg, err)
 					return
 				}
-				s, _, err := sr.ElasticSearchService(index.ESClient())
+				s, _, err := sr.ElasticSearchService(rs.esClient())
 				if err != nil {
-					log.Printf(noSearchServiceMsg, err)
-					http.Error(w, err.Error(), http.StatusBadRequest)
+					respondWithError(w, r, http.StatusBadRequest, noSearchServiceMsg, err)
 					return
 				}
 				res, err := s.Do(r.Context())
 				if err != nil {
+					respondWithError(w, r, searchErrorStatus(err), noSearchResultMsg, err)
 					return
 				}
 				if res == nil {
-					log.Printf(unexpectedResponseMsg, res)
+					respondWithError(w, r, http.StatusBadGateway, unexpectedResponseMsg, nil)
 					return
 				}
 				paging.HitsTotalCount = int32(res.TotalHits())
@@ -484,6 +608,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 
 				records, _, err = decodeFragmentGraphs(res)
 				if err != nil {
+					respondWithError(w, r, http.StatusInternalServerError, unableToDecodeRecordsMsg, err)
 					return
 				}
 				searchRequest.Tree.FillTree = true
@@ -502,32 +627,33 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 			m, _ := url.ParseQuery(qs)
 			sr, _ := fragments.NewSearchRequest(m)
 			sr.Tree.WithFields = searchRequest.Tree.WithFields
+			sr.Index = searchRequest.Index
 
 			err := sr.AddQueryFilter(
 				fmt.Sprintf("%s:%s", config.Config.ElasticSearch.SpecKey, searchRequest.Tree.GetSpec()),
 				false,
 			)
 			if err != nil {
-				log.Printf(unableToAddQueryFilterMsg, err)
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				respondWithError(w, r, http.StatusBadRequest, unableToAddQueryFilterMsg, err)
 				return
 			}
-			s, _, err := sr.ElasticSearchService(index.ESClient())
+			s, _, err := sr.ElasticSearchService(rs.esClient())
 			if err != nil {
-				log.Printf(noSearchServiceMsg, err)
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				respondWithError(w, r, http.StatusBadRequest, noSearchServiceMsg, err)
 				return
 			}
 			res, err := s.Do(r.Context())
 			if err != nil {
+				respondWithError(w, r, searchErrorStatus(err), noSearchResultMsg, err)
 				return
 			}
 			if res == nil {
-				log.Printf(unexpectedResponseMsg, res)
+				respondWithError(w, r, http.StatusBadGateway, unexpectedResponseMsg, nil)
 				return
 			}
 			parents, _, err := decodeFragmentGraphs(res)
 			if err != nil {
+				respondWithError(w, r, http.StatusInternalServerError, unableToDecodeRecordsMsg, err)
 				return
 			}
 
@@ -560,9 +686,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 			var nodeMap map[string]*fragments.Tree
 			result.Tree, nodeMap, err = fragments.InlineTree(leafs, searchRequest.Tree, res.TotalHits())
 			if err != nil {
-				render.Status(r, http.StatusInternalServerError)
-				log.Printf("Unable to render grouped TreeNodes: %s\n", err.Error())
-				render.PlainText(w, r, err.Error())
+				respondWithError(w, r, http.StatusInternalServerError, "Unable to render grouped TreeNodes", err)
 				return
 			}
 
@@ -605,15 +729,14 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 
 			if searchRequest.Tree.IsNavigatedQuery() {
 				result.TreeHeader.PreviousScrollIDs, err = searchRequest.Tree.GetPreviousScrollIDs(
+					r.Context(),
 					result.TreeHeader.ActiveID,
 					searchRequest,
 					pager,
 				)
 			}
 			if err != nil {
-				render.Status(r, http.StatusInternalServerError)
-				log.Printf("Unable to render previousScrollIDs: %s\n", err.Error())
-				render.PlainText(w, r, err.Error())
+				respondWithError(w, r, http.StatusInternalServerError, "Unable to render previousScrollIDs", err)
 				return
 			}
 
@@ -636,9 +759,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 			_, err = rec.NewGrouped()
 
 			if err != nil {
-				render.Status(r, http.StatusInternalServerError)
-				log.Printf("Unable to render grouped resources: %s\n", err.Error())
-				render.PlainText(w, r, err.Error())
+				respondWithError(w, r, http.StatusInternalServerError, "Unable to render grouped resources", err)
 				return
 			}
 		}
@@ -648,7 +769,7 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 	if !searchRequest.Paging {
 		q, _, err := searchRequest.NewUserQuery()
 		if err != nil {
-			log.Printf("Unable to create User Query")
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to create User Query", err)
 			return
 		}
 		q.Numfound = int32(res.TotalHits())
@@ -657,48 +778,108 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 		// decode Aggregations
 		aggs, err := searchRequest.DecodeFacets(res, fub)
 		if err != nil {
-			log.Printf("Unable to decode facets: %#v", err)
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to decode facets", err)
 			return
 		}
 		result.Facets = aggs
 	}
 
-	// currently only JSON is supported. Add switch when protobuf must be returned
-	render.JSON(w, r, result)
-	return
+	renderScrollResult(w, r, result)
 }
 
-func getSearchRecord(w http.ResponseWriter, r *http.Request) {
+// getSearchResultV1 returns the search results in the legacy v1 format.
+//
+// The response is a fragments.SearchResultWrapperV1 where each item contains
+// the hubID as 'doc_id' and all triples of the record flattened into 'fields'
+// as searchLabel to values, e.g.:
+//
+//	{"result": {
+//	  "query": {"numfound": 1, ...},
+//	  "items": [{"doc_id": "org_spec_1", "fields": {"dc_title": {"field": ["title"]}}}]
+//	}}
+//
+// The v1 format does not support the scroll pager.
+func (rs *SearchResource) getSearchResultV1(w http.ResponseWriter, r *http.Request) {
+	searchRequest, err := rs.newSearchRequest(r.URL.Query())
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, noSearchRequestMsg, err)
+		return
+	}
+
+	s, _, err := searchRequest.ElasticSearchService(rs.esClient())
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, noSearchServiceMsg, err)
+		return
+	}
+
+	res, err := s.Do(r.Context())
+	if err != nil {
+		respondWithError(w, r, searchErrorStatus(err), noSearchResultMsg, err)
+		return
+	}
+
+	records, _, err := decodeFragmentGraphs(res)
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, unableToDecodeRecordsMsg, err)
+		return
+	}
+
+	q, _, err := searchRequest.NewUserQuery()
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Unable to create User Query", err)
+		return
+	}
+
+	q.Numfound = int32(res.TotalHits())
+
+	result := &fragments.SearchResultV1{
+		Query: q,
+		Items: []*fragments.MetadataItemV1{},
+	}
+
+	for _, rec := range records {
+		result.Items = append(result.Items, rec.NewMetadataItemV1())
+	}
+
+	render.JSON(w, r, &fragments.SearchResultWrapperV1{Result: result})
+}
+
+func (rs *SearchResource) getSearchRecord(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	res, err := index.ESClient().Get().
-		Index(config.Config.ElasticSearch.GetIndexName()).
+
+	index, err := recordIndex(r.URL.Query().Get("index"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid index", err)
+		return
+	}
+
+	res, err := rs.esClient().Get().
+		Index(index).
 		Id(id).
 		Do(r.Context())
 	if err != nil {
-		log.Println("Unable to get search result.")
-		log.Println(err)
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, []string{})
+		if elastic.IsNotFound(err) {
+			respondWithError(w, r, http.StatusNotFound, fmt.Sprintf("%s was not found", id), err)
+			return
+		}
+
+		respondWithError(w, r, searchErrorStatus(err), noSearchResultMsg, err)
 		return
 	}
 	if res == nil {
-		log.Printf(unexpectedResponseMsg, res)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, []string{})
+		respondWithError(w, r, http.StatusBadGateway, unexpectedResponseMsg, nil)
 		return
 	}
 	if !res.Found {
-		log.Printf("%s was not found", id)
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, []string{})
+		respondWithError(w, r, http.StatusNotFound, fmt.Sprintf("%s was not found", id), nil)
 		return
 	}
 
 	record, err := decodeFragmentGraph(res.Source)
 	if err != nil {
-		fmt.Printf("Unable to decode RDFRecord: %#v", res.Source)
-		render.JSON(w, r, []string{})
-		render.Status(r, 404)
+		hlog.FromRequest(r).Error().Err(err).Str("id", id).Msg(unableToDecodeRecordMsg)
+		respondWithError(w, r, http.StatusInternalServerError, unableToDecodeRecordMsg, err)
+
 		return
 	}
 
@@ -715,15 +896,14 @@ func getSearchRecord(w http.ResponseWriter, r *http.Request) {
 	case "grouped":
 		_, err := record.NewGrouped()
 		if err != nil {
-			render.Status(r, http.StatusInternalServerError)
-			log.Printf("Unable to render grouped resources: %s\n", err.Error())
-			render.PlainText(w, r, err.Error())
+			hlog.FromRequest(r).Error().Err(err).Str("id", id).Msg(unableToGroupRecordMsg)
+			respondWithError(w, r, http.StatusInternalServerError, unableToGroupRecordMsg, err)
+
 			return
 		}
-
 	}
 
-	switch r.URL.Query().Get("format") {
+	switch responseFormat(r) {
 	case "jsonld":
 		entries := []map[string]interface{}{}
 		for _, json := range record.NewJSONLD() {
@@ -733,21 +913,174 @@ func getSearchRecord(w http.ResponseWriter, r *http.Request) {
 		render.JSON(w, r, entries)
 		w.Header().Set("Content-Type", "application/json-ld; charset=utf-8")
 		return
+	case protobufFormat:
+		msg, err := record.IndexMessage()
+		if err != nil {
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to create protobuf record", err)
+			return
+		}
 
-	// TODO enable protobuf later again
-	//case "protobuf":
-	//output, err := proto.Marshal(record)
-	//if err != nil {
-	//log.Println("Unable to marshal result to protobuf format.")
-	//return
-	//}
-	//render.Data(w, r, output)
+		output, err := proto.Marshal(msg)
+		if err != nil {
+			respondWithError(w, r, http.StatusInternalServerError, "Unable to marshal result to protobuf format", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", protobufContentType)
+		_, _ = w.Write(output)
 	default:
 		render.JSON(w, r, record)
 	}
 	return
 }
 
+// echoSearchRequest renders the parsed fragments.SearchRequest as JSON without
+// executing the search. All the fields are included, so the resolved defaults,
+// like the number of rows, are shown as well.
+func echoSearchRequest(w http.ResponseWriter, r *http.Request, sr *fragments.SearchRequest) {
+	b, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(sr)
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Unable to echo request", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// recordIndex returns the index from the 'index' query parameter or the
+// configured index when it is empty. Only the indices that are allowed to be
+// searched can be used.
+func recordIndex(index string) (string, error) {
+	index = strings.ToLower(strings.TrimSpace(index))
+	if index == "" {
+		return config.Config.ElasticSearch.GetIndexName(), nil
+	}
+
+	if !config.Config.ElasticSearch.IsSearchIndex(index) {
+		return "", fmt.Errorf("index %q is not allowed to be searched", index)
+	}
+
+	return index, nil
+}
+
+// searchErrorStatus returns the HTTP status code for an error returned by
+// Elasticsearch. The ES calls use the request context, so when it expires
+// before Elasticsearch responds a 504 is returned.
+func searchErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusBadGateway
+}
+
+// responseFormat returns the requested response format. The 'format' query
+// parameter takes precedence. Without it the Accept header is used, where
+// 'application/x-protobuf' selects protobuf. All other media types, e.g.
+// '*/*' and 'application/json', return "" for the default JSON format.
+//
+// The media types are evaluated in the order of the header; quality values
+// are ignored.
+func responseFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case protobufContentType:
+			return protobufFormat
+		case "application/json", "*/*":
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// streamProtobuf writes each record as a length-delimited domainpb.IndexMessage.
+// Each message is prefixed with its size as a protobuf varint. The Source of
+// the IndexMessage contains the JSON serialized FragmentGraph.
+//
+// The records are flushed as they are written, so clients can process them as
+// they arrive. Once the first record is written the status can no longer be
+// changed, so later errors are only logged.
+func streamProtobuf(w http.ResponseWriter, r *http.Request, records []*fragments.FragmentGraph) {
+	w.Header().Set("Content-Type", protobufStreamContentType)
+
+	flusher, _ := w.(http.Flusher)
+
+	for idx, rec := range records {
+		msg, err := rec.IndexMessage()
+		if err == nil {
+			err = writeDelimited(w, msg)
+		}
+
+		if err != nil {
+			if idx == 0 {
+				respondWithError(w, r, http.StatusInternalServerError, "Unable to stream protobuf records", err)
+				return
+			}
+
+			log.Printf("Unable to stream protobuf record %d: %s", idx, err)
+
+			return
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// renderCSV writes the records as CSV with the columns from the 'cols' parameter.
+// The header row can be disabled with 'header=false', so the pages of a
+// scroll can be concatenated into a single file.
+func renderCSV(w http.ResponseWriter, r *http.Request, records []*fragments.FragmentGraph) {
+	columns := []string{}
+
+	for _, col := range strings.Split(r.URL.Query().Get("cols"), ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			columns = append(columns, col)
+		}
+	}
+
+	export := fragments.NewCSVExport(columns...)
+	export.Header = r.URL.Query().Get("header") != "false"
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, records); err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, "Unable to render CSV", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="search.csv"`)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// writeDelimited writes the varint size of the marshaled message followed by the message.
+func writeDelimited(w io.Writer, m proto.Message) error {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	size := uint64(len(b))
+
+	buf := protowire.AppendVarint(make([]byte, 0, protowire.SizeVarint(size)+len(b)), size)
+
+	_, err = w.Write(append(buf, b...))
+
+	return err
+}
+
 func decodeFragmentGraph(hit json.RawMessage) (*fragments.FragmentGraph, error) {
 	r := new(fragments.FragmentGraph)
 	if err := json.Unmarshal(hit, r); err != nil {
@@ -800,32 +1133,42 @@ func decodeCollapsed(res *elastic.SearchResult, sr *fragments.SearchRequest) ([]
 }
 
 func decodeHighlights(r *fragments.FragmentGraph, hit *elastic.SearchHit) error {
-
 	hl, ok := hit.InnerHits["highlight"]
-	if ok {
-		for _, hlHit := range hl.Hits.Hits {
-			re, err := decodeResourceEntry(hlHit.Source)
-			if err != nil {
-				return err
-			}
-			hlEntry, ok := hlHit.Highlight["resources.entries.@value"]
-			if ok {
-				// add highlighting
-				r.Highlights = append(
-					r.Highlights,
-					&fragments.ResourceEntryHighlight{
-						SearchLabel: re.SearchLabel,
-						MarkDown:    hlEntry,
-					},
-				)
+	if !ok || hl.Hits == nil {
+		return nil
+	}
 
-			}
+	for _, hlHit := range hl.Hits.Hits {
+		hlEntry, ok := hlHit.Highlight["resources.entries.@value"]
+		if !ok {
+			continue
 		}
+
+		// highlights are optional so a broken entry should not fail the record
+		re, err := decodeResourceEntry(hlHit.Source)
+		if err != nil {
+			log.Printf("Unable to decode highlight entry: %s", err)
+			continue
+		}
+
+		r.Highlights = append(
+			r.Highlights,
+			&fragments.ResourceEntryHighlight{
+				SearchLabel: re.SearchLabel,
+				MarkDown:    hlEntry,
+			},
+		)
 	}
+
 	return nil
 }
 
-// decodeFragmentGraphs takes a search result and deserializes the records
+// decodeFragmentGraphs takes a search result and deserializes the records.
+//
+// The records are returned as stored, including their resources, just like
+// the single record returned by getSearchRecord. FragmentGraph has no separate
+// RDF payload, so there is nothing to strip; use the 'itemFormat' parameter to
+// reduce the size of the response.
 func decodeFragmentGraphs(res *elastic.SearchResult) ([]*fragments.FragmentGraph, []interface{}, error) {
 	if res == nil || res.TotalHits() == 0 {
 		return nil, nil, nil
@@ -0,0 +1,717 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/delving/hub3/ikuzo/service/x/namespace"
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/elastictest"
+	"github.com/go-chi/chi"
+	"github.com/matryer/is"
+	elastic "github.com/olivere/elastic/v7"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+const v1SearchResponse = `{
+  "took": 1,
+  "timed_out": false,
+  "hits": {
+    "total": {"value": 1, "relation": "eq"},
+    "hits": [{
+      "_index": "hub3",
+      "_id": "org_spec_1",
+      "_source": {
+        "meta": {"orgID": "org", "spec": "spec", "hubID": "org_spec_1", "docType": "graph"},
+        "resources": [{
+          "id": "http://example.org/1",
+          "entries": [
+            {"@value": "first title", "searchLabel": "dc_title", "order": 1},
+            {"@id": "http://example.org/creator", "entrytype": "Resource", "searchLabel": "dc_creator", "order": 2},
+            {"@value": "second title", "searchLabel": "dc_title", "order": 3}
+          ]
+        }]
+      }
+    }]
+  }
+}`
+
+func newSearchRouter(options ...SearchOption) http.Handler {
+	router := chi.NewRouter()
+	NewSearchResource(nil, options...).Routes(router)
+
+	return router
+}
+
+func TestGetSearchResultV1(t *testing.T) {
+	is := is.New(t)
+
+	client := elastictest.NewResponseClient(t, http.StatusOK, v1SearchResponse)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v1?q=title", nil)
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusOK)
+
+	var got fragments.SearchResultWrapperV1
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	is.NoErr(err)
+
+	is.Equal(got.GetResult().GetQuery().GetNumfound(), int32(1))
+	is.Equal(len(got.GetResult().GetItems()), 1)
+
+	item := got.GetResult().GetItems()[0]
+	is.Equal(item.GetDocId(), "org_spec_1")
+	is.Equal(item.GetDocType(), "graph")
+	is.Equal(item.GetFields()["delving_spec"].GetField(), []string{"spec"})
+	is.Equal(item.GetFields()["dc_title"].GetField(), []string{"first title", "second title"})
+	is.Equal(item.GetFields()["dc_creator"].GetField(), []string{"http://example.org/creator"})
+}
+
+func TestGetScrollResult_errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		esStatus   int
+		esResponse string
+		wantStatus int
+	}{
+		{
+			"malformed scrollID",
+			"/api/search/v2?scrollID=not-a-hex-value",
+			http.StatusOK,
+			v1SearchResponse,
+			http.StatusBadRequest,
+		},
+		{
+			"malformed query filter",
+			"/api/search/v2?qf=no-separator",
+			http.StatusOK,
+			v1SearchResponse,
+			http.StatusBadRequest,
+		},
+		{
+			"malformed fielded query",
+			"/api/search/v2?qf.fielded[]=" + url.QueryEscape("dc_subject:war AND (dc_date:[1940 TO 1945]"),
+			http.StatusOK,
+			v1SearchResponse,
+			http.StatusBadRequest,
+		},
+		{
+			"elasticsearch error",
+			"/api/search/v2?q=title",
+			http.StatusInternalServerError,
+			`{"error": {"type": "exception", "reason": "boom"}, "status": 500}`,
+			http.StatusBadGateway,
+		},
+		{
+			"elasticsearch error v1",
+			"/api/search/v1?q=title",
+			http.StatusServiceUnavailable,
+			`{"error": {"type": "exception", "reason": "unavailable"}, "status": 503}`,
+			http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			client := elastictest.NewResponseClient(t, tt.esStatus, tt.esResponse)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+			is.Equal(w.Code, tt.wantStatus)
+
+			var got ErrResponse
+			err := json.Unmarshal(w.Body.Bytes(), &got)
+			is.NoErr(err)
+			is.True(got.StatusText != "")
+			is.True(got.ErrorText != "")
+		})
+	}
+}
+
+func TestGetScrollResult_cancelledContext(t *testing.T) {
+	is := is.New(t)
+
+	received := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	client := elastictest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server only detects the closed connection after the body is read
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		close(received)
+
+		select {
+		case <-r.Context().Done():
+			cancelled <- r.Context().Err()
+		case <-time.After(5 * time.Second):
+			cancelled <- nil
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-received
+		cancel()
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v2?q=title", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(<-cancelled, context.Canceled) // ES call must receive the cancellation
+	is.Equal(w.Code, http.StatusBadGateway)
+}
+
+func TestSearchErrorStatus(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(searchErrorStatus(context.DeadlineExceeded), http.StatusGatewayTimeout)
+	is.Equal(searchErrorStatus(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)), http.StatusGatewayTimeout)
+	is.Equal(searchErrorStatus(context.Canceled), http.StatusBadGateway)
+}
+
+func TestDecodeHighlights(t *testing.T) {
+	tests := []struct {
+		name string
+		hit  string
+		want []*fragments.ResourceEntryHighlight
+	}{
+		{
+			"no inner hits",
+			`{"_id": "1"}`,
+			nil,
+		},
+		{
+			"highlighted entry",
+			`{"_id": "1", "inner_hits": {"highlight": {"hits": {"hits": [
+				{"_source": {"@value": "de boerderij", "searchLabel": "dc_title"},
+				 "highlight": {"resources.entries.@value": ["de <em>boerderij</em> &amp; stal"]}}
+			]}}}}`,
+			[]*fragments.ResourceEntryHighlight{
+				{SearchLabel: "dc_title", MarkDown: []string{"de <em>boerderij</em> &amp; stal"}},
+			},
+		},
+		{
+			"inner hit without highlight",
+			`{"_id": "1", "inner_hits": {"highlight": {"hits": {"hits": [
+				{"_source": {"@value": "de boerderij", "searchLabel": "dc_title"}}
+			]}}}}`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			var hit elastic.SearchHit
+			err := json.Unmarshal([]byte(tt.hit), &hit)
+			is.NoErr(err)
+
+			fg := &fragments.FragmentGraph{}
+			err = decodeHighlights(fg, &hit)
+			is.NoErr(err)
+			is.Equal(fg.Highlights, tt.want)
+
+			b, err := json.Marshal(fg)
+			is.NoErr(err)
+			is.Equal(strings.Contains(string(b), `"highlights"`), tt.want != nil)
+		})
+	}
+}
+
+func TestGetScrollResult_multipleIndices(t *testing.T) {
+	is := is.New(t)
+
+	orig := c.Config.ElasticSearch.SearchIndices
+	c.Config.ElasticSearch.SearchIndices = []string{"org1v2", "org2v2"}
+
+	defer func() { c.Config.ElasticSearch.SearchIndices = orig }()
+
+	paths := make(chan string, 1)
+
+	client := elastictest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+
+		// more hits than rows so the pager has a next page
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(strings.Replace(v1SearchResponse, `"value": 1`, `"value": 5`, 1)))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v2?q=title&rows=1&index=org1v2,org2v2", nil)
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(<-paths, "/org1v2,org2v2/_search")
+
+	// the pager must keep searching the same indices
+	sr, err := fragments.SearchRequestFromHex(w.Header().Get("P_NEXT_SCROLL_ID"))
+	is.NoErr(err)
+	is.Equal(sr.GetIndex(), []string{"org1v2", "org2v2"})
+
+	// indices outside the allow-list are rejected
+	req = httptest.NewRequest(http.MethodGet, "/api/search/v2?q=title&index=secret", nil)
+	w = httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusBadRequest)
+}
+
+func TestGetSearchRecord_notFound(t *testing.T) {
+	is := is.New(t)
+
+	client := elastictest.NewResponseClient(t, http.StatusNotFound, `{"_index":"hub3","_type":"_doc","_id":"missing","found":false}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v2/missing", nil)
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusNotFound)
+
+	var got ErrResponse
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	is.NoErr(err)
+	is.True(got.StatusText != "")
+	is.Equal(got.StatusText, "missing was not found")
+}
+
+func TestGetSearchRecord_decodeError(t *testing.T) {
+	is := is.New(t)
+
+	client := elastictest.NewResponseClient(t, http.StatusOK, `{"_index":"hub3","_type":"_doc","_id":"123","found":true,"_source":"not a record"}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v2/123", nil)
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusInternalServerError)
+
+	var got ErrResponse
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	is.NoErr(err)
+	is.Equal(got.StatusText, unableToDecodeRecordMsg)
+}
+
+func TestGetSearchRecord_index(t *testing.T) {
+	is := is.New(t)
+
+	orig := c.Config.ElasticSearch.SearchIndices
+	c.Config.ElasticSearch.SearchIndices = []string{"org1v2"}
+
+	defer func() { c.Config.ElasticSearch.SearchIndices = orig }()
+
+	paths := make(chan string, 1)
+
+	client := elastictest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"_index":"org1v2","_type":"_doc","_id":"123","found":false}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v2/123?index=org1v2", nil)
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusNotFound)
+	is.Equal(<-paths, "/org1v2/_doc/123")
+
+	// indices outside the allow-list are rejected
+	req = httptest.NewRequest(http.MethodGet, "/api/search/v2/123?index=secret", nil)
+	w = httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusBadRequest)
+}
+
+func TestGetScrollResult_protobufStream(t *testing.T) {
+	is := is.New(t)
+
+	client := elastictest.NewResponseClient(t, http.StatusOK, strings.Replace(v1SearchResponse, `"value": 1`, `"value": 5`, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v2?q=title&rows=1&format=protobuf-stream", nil)
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Header().Get("Content-Type"), protobufStreamContentType)
+	is.Equal(w.Header().Get("P_TOTAL"), "5")
+	is.True(w.Header().Get("P_NEXT_SCROLL_ID") != "")
+
+	body := w.Body.Bytes()
+	msgs := []*domainpb.IndexMessage{}
+
+	for len(body) > 0 {
+		size, n := protowire.ConsumeVarint(body)
+		is.True(n > 0) // valid varint prefix
+
+		body = body[n:]
+
+		msg := &domainpb.IndexMessage{}
+		err := proto.Unmarshal(body[:size], msg)
+		is.NoErr(err)
+
+		msgs = append(msgs, msg)
+		body = body[size:]
+	}
+
+	is.Equal(len(msgs), 1)
+	is.Equal(msgs[0].GetRecordID(), "org_spec_1")
+
+	var fg fragments.FragmentGraph
+	err := json.Unmarshal(msgs[0].GetSource(), &fg)
+	is.NoErr(err)
+	is.Equal(fg.Meta.GetSpec(), "spec")
+}
+
+func TestResponseFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		want   string
+	}{
+		{"no format", "/api/search/v2", "", ""},
+		{"json", "/api/search/v2", "application/json", ""},
+		{"any", "/api/search/v2", "*/*", ""},
+		{"protobuf", "/api/search/v2", "application/x-protobuf", protobufFormat},
+		{"first known media type", "/api/search/v2", "text/html, application/x-protobuf, */*", protobufFormat},
+		{"json before protobuf", "/api/search/v2", "application/json, application/x-protobuf", ""},
+		{"query parameter takes precedence", "/api/search/v2?format=csv", "application/x-protobuf", csvFormat},
+		{"query parameter", "/api/search/v2?format=protobuf", "application/json", protobufFormat},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			if got := responseFormat(req); got != tt.want {
+				t.Errorf("responseFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSearchRecord_protobuf(t *testing.T) {
+	is := is.New(t)
+
+	client := elastictest.NewResponseClient(t, http.StatusOK, `{
+  "_index": "hub3",
+  "_type": "_doc",
+  "_id": "org_spec_1",
+  "found": true,
+  "_source": {"meta": {"orgID": "org", "spec": "spec", "hubID": "org_spec_1", "docType": "graph"}}
+}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v2/org_spec_1", nil)
+	req.Header.Set("Accept", protobufContentType)
+
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Header().Get("Content-Type"), protobufContentType)
+
+	msg := &domainpb.IndexMessage{}
+	err := proto.Unmarshal(w.Body.Bytes(), msg)
+	is.NoErr(err)
+	is.Equal(msg.GetRecordID(), "org_spec_1")
+}
+
+func TestGetScrollResult_csv(t *testing.T) {
+	is := is.New(t)
+
+	client := elastictest.NewResponseClient(t, http.StatusOK, v1SearchResponse)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/v2?q=title&format=csv&cols=hubID,spec,dc_title", nil)
+	w := httptest.NewRecorder()
+
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+	is.Equal(w.Header().Get("P_TOTAL"), "1")
+	is.Equal(w.Body.String(), "hubID,spec,dc_title\norg_spec_1,spec,first title; second title\n")
+}
+
+func TestGetScrollResult_searchAfterPaging(t *testing.T) {
+	is := is.New(t)
+
+	response := strings.Replace(v1SearchResponse, `"value": 1`, `"value": 5`, 1)
+	response = strings.Replace(response, `"_id": "org_spec_1",`, `"_id": "org_spec_1", "sort": [1.5, "org_spec_1"],`, 1)
+
+	bodies := make(chan string, 2)
+
+	client := elastictest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- string(b)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		newSearchRouter(SetElasticClient(client)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+		return w
+	}
+
+	w := get("/api/search/v2?q=title&rows=1&paging=search_after")
+	is.Equal(w.Code, http.StatusOK)
+	is.True(!strings.Contains(<-bodies, `"search_after"`)) // first page starts at the top
+
+	var result fragments.ScrollResultV4
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &result))
+	is.Equal(result.Pager.SearchAfter, []interface{}{1.5, "org_spec_1"})
+	is.Equal(result.Pager.PreviousScrollID, "")
+	is.True(result.Pager.NextScrollID != "")
+
+	w = get("/api/search/v2?scrollID=" + result.Pager.NextScrollID)
+	is.Equal(w.Code, http.StatusOK)
+
+	body := <-bodies
+	is.True(strings.Contains(body, `"search_after":[1.5,"org_spec_1"]`)) // next page continues after the last hit
+	is.True(!strings.Contains(body, `"from"`))                           // no offset based paging
+}
+
+func TestSearchResource_searchLabels(t *testing.T) {
+	ns, err := namespace.NewService(namespace.WithDefaults())
+	if err != nil {
+		t.Fatalf("unable to create namespace.Service; %s", err)
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{"known prefix", "/api/search/v2?qf=dc_title:title&facet.field=dc_creator", http.StatusOK},
+		{"unknown query filter prefix", "/api/search/v2?qf=unknown_title:title", http.StatusBadRequest},
+		{"unknown facet prefix v1", "/api/search/v1?facet.field=unknown_title", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			client := elastictest.NewResponseClient(t, http.StatusOK, v1SearchResponse)
+
+			router := chi.NewRouter()
+			NewSearchResource(ns, SetElasticClient(client)).Routes(router)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			is.Equal(w.Code, tt.wantStatus)
+		})
+	}
+}
+
+func TestSearchResource_rows(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []SearchOption
+		url        string
+		wantStatus int
+		wantRows   int32
+	}{
+		{"default", nil, "/api/search/v2?q=title", http.StatusOK, 16},
+		{"configured default", []SearchOption{SetDefaultRows(25)}, "/api/search/v2?q=title", http.StatusOK, 25},
+		{"clamped", []SearchOption{SetMaxRows(50)}, "/api/search/v2?q=title&rows=51", http.StatusOK, 50},
+		{"rejected", []SearchOption{SetMaxRows(50), SetRejectExceedingRows()}, "/api/search/v2?q=title&rows=51", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			client := elastictest.NewResponseClient(t, http.StatusOK, v1SearchResponse)
+
+			router := chi.NewRouter()
+			NewSearchResource(nil, append(tt.options, SetElasticClient(client))...).Routes(router)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			is.Equal(w.Code, tt.wantStatus)
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got fragments.ScrollResultV4
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+			is.Equal(got.Pager.Rows, tt.wantRows)
+		})
+	}
+}
+
+func TestSearchResource_elasticClient(t *testing.T) {
+	is := is.New(t)
+
+	var calls int
+
+	client := elastictest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(v1SearchResponse))
+	}))
+
+	// the default client must not be used when a client is set
+	orig := defaultESClient
+	defaultESClient = func() *elastic.Client {
+		t.Fatal("default elastic client should not be used")
+		return nil
+	}
+
+	defer func() { defaultESClient = orig }()
+
+	router := chi.NewRouter()
+	NewSearchResource(nil, SetElasticClient(client)).Routes(router)
+
+	for _, path := range []string{
+		"/api/search/v2?q=title",
+		"/api/search/v1?q=title",
+		"/api/search/v2/describe?uri=http://example.org/1",
+		"/api/search/suggest?q=title",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		is.True(w.Code != http.StatusInternalServerError)
+	}
+
+	is.Equal(calls, 4)
+}
+
+func TestGetScrollResult_invalidParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		wantParam string
+	}{
+		{"non-numeric rows", "/api/search/v2?rows=abc", "rows"},
+		{"negative start", "/api/search/v2?start=-1", "start"},
+		{"invalid facet field", "/api/search/v2?facet.field=dc_title:asc", "facet.field"},
+		{"invalid sort direction", "/api/search/v2?sort=dc_title:up", "sort"},
+		{"invalid sortOrder", "/api/search/v2?sortOrder=sideways", "sortOrder"},
+		{"malformed query filter", "/api/search/v2?qf=no-separator", "qf"},
+		{"non-numeric rows v1", "/api/search/v1?rows=abc", "rows"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			client := elastictest.NewResponseClient(t, http.StatusOK, v1SearchResponse)
+
+			w := httptest.NewRecorder()
+			newSearchRouter(SetElasticClient(client)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			is.Equal(w.Code, http.StatusBadRequest)
+
+			var got struct {
+				Status string `json:"status"`
+				Error  string `json:"error"`
+				Param  string `json:"param"`
+			}
+
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+			is.Equal(got.Status, noSearchRequestMsg)
+			is.Equal(got.Param, tt.wantParam)
+			is.True(strings.Contains(got.Error, tt.wantParam))
+		})
+	}
+}
+
+func TestGetScrollResult_echoRequest(t *testing.T) {
+	is := is.New(t)
+
+	// the search must not be executed
+	client := elastictest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("elasticsearch should not be called for echo=request")
+	}))
+
+	w := httptest.NewRecorder()
+	newSearchRouter(SetElasticClient(client)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search/v2?q=title&rows=5000&echo=request", nil))
+
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Header().Get("Content-Type"), "application/json")
+
+	var got map[string]interface{}
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+
+	is.Equal(got["query"], "title")
+	// rows are clamped to the maximum
+	is.Equal(got["responseSize"], float64(1000))
+
+	// unpopulated fields are included
+	sortAsc, ok := got["sortAsc"]
+	is.True(ok)
+	is.Equal(sortAsc, false)
+}
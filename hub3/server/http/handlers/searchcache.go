@@ -0,0 +1,186 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/die-net/lrucache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// cacheBypassParam disables the search cache for a request when set to 'false'.
+	cacheBypassParam = "cache"
+	cacheHeader      = "X-Cache"
+)
+
+// Results of a cached search in the search_cache_requests_total metric.
+const (
+	cacheHit    = "hit"
+	cacheMiss   = "miss"
+	cacheBypass = "bypass"
+)
+
+var searchCacheRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "search_cache_requests_total",
+		Help: "How many search requests are served from the cache, partitioned by result (hit, miss or bypass).",
+	},
+	[]string{"result"},
+)
+
+// nolint:gochecknoinits // the metrics must only be registered once
+func init() {
+	prometheus.MustRegister(searchCacheRequests)
+}
+
+// SetSearchCache enables an in-memory LRU cache for the first page of the v2
+// search results. maxSize is the maximum size of the cached responses in bytes.
+// The entries expire after ttl, which is rounded down to whole seconds with a
+// minimum of one second. When ttl is zero the entries only expire when the
+// cache is full.
+//
+// Scroll, paging and echo requests are never cached. A single request can bypass
+// the cache with 'cache=false'.
+func SetSearchCache(maxSize int64, ttl time.Duration) SearchOption {
+	return func(rs *SearchResource) {
+		maxAge := int64(ttl.Seconds())
+		if ttl > 0 && maxAge == 0 {
+			maxAge = 1
+		}
+
+		rs.cache = lrucache.New(maxSize, maxAge)
+	}
+}
+
+// cachedResponse is a search response that is stored in the search cache.
+type cachedResponse struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// cachedSearch serves the search request from the cache when possible.
+// Successful responses of cacheable requests are added to the cache.
+func (rs *SearchResource) cachedSearch(w http.ResponseWriter, r *http.Request, sr *fragments.SearchRequest) {
+	if !isCacheable(r, sr) {
+		searchCacheRequests.WithLabelValues(cacheBypass).Inc()
+		rs.processSearchRequest(w, r, sr)
+
+		return
+	}
+
+	key := searchCacheKey(r)
+
+	if b, ok := rs.cache.Get(key); ok {
+		var resp cachedResponse
+		if err := json.Unmarshal(b, &resp); err == nil {
+			searchCacheRequests.WithLabelValues(cacheHit).Inc()
+
+			for name, values := range resp.Header {
+				w.Header()[name] = values
+			}
+
+			w.Header().Set(cacheHeader, "HIT")
+			_, _ = w.Write(resp.Body)
+
+			return
+		}
+
+		rs.cache.Delete(key)
+	}
+
+	searchCacheRequests.WithLabelValues(cacheMiss).Inc()
+
+	w.Header().Set(cacheHeader, "MISS")
+
+	capture := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+	rs.processSearchRequest(capture, r, sr)
+
+	if capture.status != http.StatusOK {
+		return
+	}
+
+	header := w.Header().Clone()
+	header.Del(cacheHeader)
+
+	b, err := json.Marshal(&cachedResponse{Header: header, Body: capture.body.Bytes()})
+	if err != nil {
+		log.Printf("Unable to cache search response: %s", err)
+		return
+	}
+
+	rs.cache.Set(key, b)
+}
+
+// isCacheable returns if the response to the search request can be cached.
+// Only the first page of search requests without scroll or tree paging
+// state are cached.
+func isCacheable(r *http.Request, sr *fragments.SearchRequest) bool {
+	if r.Method != http.MethodGet || r.URL.Query().Get(cacheBypassParam) == "false" {
+		return false
+	}
+
+	// echoed requests are for debugging and don't show the search results
+	if r.URL.Query().Get("echo") != "" {
+		return false
+	}
+
+	return !sr.Paging &&
+		!sr.SearchAfterPaging &&
+		sr.GetStart() == 0 &&
+		len(sr.SearchAfter) == 0 &&
+		sr.Tree == nil
+}
+
+// searchCacheKey returns the normalized request as the cache key. The query
+// parameters are sorted by key and the negotiated response format is added,
+// because it can also be set with the Accept header.
+func searchCacheKey(r *http.Request) string {
+	params := r.URL.Query()
+	params.Del(cacheBypassParam)
+
+	return r.URL.Path + "?" + params.Encode() + "#" + responseFormat(r)
+}
+
+// responseCapture records the status and body that are written to the
+// http.ResponseWriter.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rc *responseCapture) WriteHeader(status int) {
+	rc.status = status
+	rc.ResponseWriter.WriteHeader(status)
+}
+
+func (rc *responseCapture) Write(b []byte) (int, error) {
+	rc.body.Write(b)
+	return rc.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher so streamed responses are still flushed.
+func (rc *responseCapture) Flush() {
+	if flusher, ok := rc.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
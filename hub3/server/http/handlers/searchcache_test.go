@@ -0,0 +1,87 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/elastictest"
+	"github.com/go-chi/chi"
+	"github.com/matryer/is"
+)
+
+func TestSearchResource_cache(t *testing.T) {
+	is := is.New(t)
+
+	var calls int32
+
+	client := elastictest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+
+		// more hits than rows so the pager has a next page
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(strings.Replace(v1SearchResponse, `"value": 1`, `"value": 5`, 1)))
+	}))
+
+	router := chi.NewRouter()
+	NewSearchResource(nil, SetSearchCache(1e6, time.Minute), SetElasticClient(client)).Routes(router)
+
+	search := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+		return w
+	}
+
+	first := search("/api/search/v2?q=title&rows=1")
+	is.Equal(first.Code, http.StatusOK)
+	is.Equal(first.Header().Get(cacheHeader), "MISS")
+	is.Equal(atomic.LoadInt32(&calls), int32(1))
+
+	// the same query with the parameters in a different order is served from the cache
+	cached := search("/api/search/v2?rows=1&q=title")
+	is.Equal(cached.Code, http.StatusOK)
+	is.Equal(cached.Header().Get(cacheHeader), "HIT")
+	is.Equal(cached.Body.String(), first.Body.String())
+	is.Equal(cached.Header().Get("P_NEXT_SCROLL_ID"), first.Header().Get("P_NEXT_SCROLL_ID"))
+	is.Equal(atomic.LoadInt32(&calls), int32(1))
+
+	// the cache can be bypassed
+	bypass := search("/api/search/v2?q=title&rows=1&cache=false")
+	is.Equal(bypass.Code, http.StatusOK)
+	is.Equal(bypass.Header().Get(cacheHeader), "")
+	is.Equal(atomic.LoadInt32(&calls), int32(2))
+
+	// echo requests are never cached
+	echo := search("/api/search/v2?q=title&rows=1&echo=es")
+	is.Equal(echo.Code, http.StatusOK)
+	is.Equal(echo.Header().Get(cacheHeader), "")
+	is.Equal(atomic.LoadInt32(&calls), int32(3))
+
+	// scroll requests are never cached
+	next := first.Header().Get("P_NEXT_SCROLL_ID")
+	for i := 0; i < 2; i++ {
+		w := search("/api/search/v2?scrollID=" + next)
+		is.Equal(w.Code, http.StatusOK)
+		is.Equal(w.Header().Get(cacheHeader), "")
+	}
+
+	is.Equal(atomic.LoadInt32(&calls), int32(5))
+}
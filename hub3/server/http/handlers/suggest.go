@@ -0,0 +1,146 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	c "github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	"github.com/go-chi/render"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+const (
+	defaultSuggestions = 10
+	maxSuggestions     = 50
+	suggestAggName     = "suggest"
+)
+
+// getSuggestions returns a JSON array of entry values that start with the
+// words in the 'q' parameter, ordered by the number of entries that contain them.
+//
+// The number of suggestions can be set with 'rows' (default 10, max 50).
+// A blank query returns an empty array.
+func (rs *SearchResource) getSuggestions(w http.ResponseWriter, r *http.Request) {
+	analyzer := search.Analyzer{}
+	suggestions := []string{}
+
+	q := analyzer.TransformPhrase(r.URL.Query().Get("q"))
+	if q == "" {
+		render.JSON(w, r, suggestions)
+		return
+	}
+
+	size := defaultSuggestions
+
+	if rows := r.URL.Query().Get("rows"); rows != "" {
+		n, err := strconv.Atoi(rows)
+		if err != nil || n < 1 {
+			respondWithError(w, r, http.StatusBadRequest, "rows must be a positive integer", err)
+			return
+		}
+
+		if n < maxSuggestions {
+			size = n
+		} else {
+			size = maxSuggestions
+		}
+	}
+
+	prefixQuery := elastic.NewMatchPhrasePrefixQuery("resources.entries.@value", q)
+
+	query := elastic.NewBoolQuery().
+		Must(
+			elastic.NewTermQuery("meta.docType", fragments.FragmentGraphDocType),
+			elastic.NewTermQuery(c.Config.ElasticSearch.OrgIDKey, c.Config.OrgID),
+			elastic.NewNestedQuery("resources.entries", prefixQuery),
+		)
+
+	// request more buckets than needed because folded duplicates are merged
+	values := elastic.NewTermsAggregation().
+		Field("resources.entries.@value.keyword").
+		Size(size * 2).
+		OrderByCountDesc()
+
+	agg := elastic.NewNestedAggregation().
+		Path("resources.entries").
+		SubAggregation("matches", elastic.NewFilterAggregation().
+			Filter(prefixQuery).
+			SubAggregation("values", values),
+		)
+
+	res, err := rs.esClient().Search().
+		Index(c.Config.ElasticSearch.GetIndexName()).
+		Query(query).
+		Size(0).
+		Aggregation(suggestAggName, agg).
+		Do(r.Context())
+	if err != nil {
+		respondWithError(w, r, searchErrorStatus(err), noSearchResultMsg, err)
+		return
+	}
+
+	seen := map[string]bool{}
+
+	for _, bucket := range suggestBuckets(res) {
+		value, ok := bucket.Key.(string)
+		if !ok {
+			continue
+		}
+
+		folded := analyzer.TransformPhrase(value)
+		if seen[folded] || !strings.Contains(folded, q) {
+			continue
+		}
+
+		seen[folded] = true
+
+		suggestions = append(suggestions, value)
+
+		if len(suggestions) == size {
+			break
+		}
+	}
+
+	render.JSON(w, r, suggestions)
+}
+
+// suggestBuckets returns the term buckets of the suggest aggregation.
+func suggestBuckets(res *elastic.SearchResult) []*elastic.AggregationBucketKeyItem {
+	if res == nil || res.Aggregations == nil {
+		return nil
+	}
+
+	nested, ok := res.Aggregations.Nested(suggestAggName)
+	if !ok {
+		return nil
+	}
+
+	matches, ok := nested.Filter("matches")
+	if !ok {
+		return nil
+	}
+
+	values, ok := matches.Terms("values")
+	if !ok {
+		return nil
+	}
+
+	return values.Buckets
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/elastictest"
+	"github.com/matryer/is"
+)
+
+const suggestResponse = `{
+  "took": 1,
+  "hits": {"total": {"value": 3, "relation": "eq"}, "hits": []},
+  "aggregations": {
+    "suggest": {
+      "doc_count": 10,
+      "matches": {
+        "doc_count": 6,
+        "values": {
+          "buckets": [
+            {"key": "Boerderij", "doc_count": 3},
+            {"key": "boerderij", "doc_count": 2},
+            {"key": "Boerderij de Hoeve", "doc_count": 1}
+          ]
+        }
+      }
+    }
+  }
+}`
+
+func TestGetSuggestions(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		want       []string
+		wantStatus int
+	}{
+		{"blank query", "/api/search/suggest?q=+", []string{}, http.StatusOK},
+		{"folded duplicates are merged", "/api/search/suggest?q=BOERD", []string{"Boerderij", "Boerderij de Hoeve"}, http.StatusOK},
+		{"limited by rows", "/api/search/suggest?q=boerd&rows=1", []string{"Boerderij"}, http.StatusOK},
+		{"invalid rows", "/api/search/suggest?q=boerd&rows=none", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			client := elastictest.NewResponseClient(t, http.StatusOK, suggestResponse)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newSearchRouter(SetElasticClient(client)).ServeHTTP(w, req)
+
+			is.Equal(w.Code, tt.wantStatus)
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got []string
+			err := json.Unmarshal(w.Body.Bytes(), &got)
+			is.NoErr(err)
+			is.Equal(got, tt.want)
+		})
+	}
+}
@@ -19,8 +19,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 var (
@@ -29,6 +32,52 @@ var (
 	ErrNameSpaceNotValid       = errors.New("prefix or base not valid")
 )
 
+// ValidationError is returned when a prefix or base-URI is not valid.
+// It wraps ErrNameSpaceNotValid, so it can be matched with errors.Is as well.
+type ValidationError struct {
+	Prefix string
+	Base   string
+	// Reason describes why the prefix or base-URI is not valid.
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Reason == "" {
+		return ErrNameSpaceNotValid.Error()
+	}
+
+	return fmt.Sprintf("%s; %s", e.Reason, ErrNameSpaceNotValid)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrNameSpaceNotValid
+}
+
+// DuplicateEntryError is returned when the prefix or base-URI is already
+// linked to a different NameSpace. It wraps ErrNameSpaceDuplicateEntry.
+type DuplicateEntryError struct {
+	Prefix string
+	Base   string
+	// Existing is the NameSpace the prefix or base-URI is linked to.
+	Existing *NameSpace
+}
+
+func (e *DuplicateEntryError) Error() string {
+	if e.Existing == nil {
+		return ErrNameSpaceDuplicateEntry.Error()
+	}
+
+	if e.Existing.Prefix == e.Prefix {
+		return fmt.Sprintf("prefix %s is linked to %s; %s", e.Prefix, e.Existing.Base, ErrNameSpaceDuplicateEntry)
+	}
+
+	return fmt.Sprintf("base %s is linked to %s; %s", e.Base, e.Existing.Prefix, ErrNameSpaceDuplicateEntry)
+}
+
+func (e *DuplicateEntryError) Unwrap() error {
+	return ErrNameSpaceDuplicateEntry
+}
+
 // URI represents a NameSpace URI.
 type URI string
 
@@ -67,6 +116,15 @@ type NameSpace struct {
 	// Namespaces with prefix collissions will also be given a temporary prefix
 	Temporary bool `json:"temporary,omitempty"`
 
+	// LastUsed is the last time the NameSpace was stored or looked up by the
+	// namespace.Service when it was dumped. It is used to prune unused
+	// temporary namespaces after they are loaded.
+	LastUsed *time.Time `json:"lastUsed,omitempty"`
+
+	// Meta contains arbitrary annotations of the NameSpace, e.g. a human
+	// readable label or the URL of its documentation.
+	Meta map[string]string `json:"meta,omitempty"`
+
 	// TODO(kiivihal): add function for custom hashing similar to isIdentRune
 }
 
@@ -74,6 +132,69 @@ func (ns *NameSpace) String() string {
 	return fmt.Sprintf("%s: %s", ns.Prefix, ns.Base)
 }
 
+// prefixPattern matches the valid prefixes of a NameSpace.
+var prefixPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9.\-]*$`)
+
+// Validate returns a *ValidationError when the NameSpace is malformed.
+//
+// The Base and the BaseAlt entries must be absolute URIs. The Prefix and the
+// PrefixAlt entries must start with a letter followed by letters, digits, '.'
+// or '-'. The Prefix of a Temporary NameSpace is generated, so it only has to
+// be set.
+func (ns *NameSpace) Validate() error {
+	if ns.Base == "" {
+		return &ValidationError{Prefix: ns.Prefix, Reason: "base is required"}
+	}
+
+	for _, base := range append([]string{ns.Base}, ns.BaseAlt...) {
+		if err := ValidateBaseURI(ns.Prefix, base); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case ns.Prefix == "":
+		return &ValidationError{Base: ns.Base, Reason: "prefix is required"}
+	case !ns.Temporary && !prefixPattern.MatchString(ns.Prefix):
+		return &ValidationError{
+			Prefix: ns.Prefix,
+			Base:   ns.Base,
+			Reason: fmt.Sprintf("prefix %s must match %s", ns.Prefix, prefixPattern),
+		}
+	}
+
+	for _, prefix := range ns.PrefixAlt {
+		if !prefixPattern.MatchString(prefix) {
+			return &ValidationError{
+				Prefix: prefix,
+				Base:   ns.Base,
+				Reason: fmt.Sprintf("alternative prefix %s must match %s", prefix, prefixPattern),
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateBaseURI returns a *ValidationError when the base is not an absolute
+// URI with a scheme and host. The namespace delimiter is not checked.
+func ValidateBaseURI(prefix, base string) error {
+	if base == "" {
+		return &ValidationError{Prefix: prefix, Reason: "base is required"}
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return &ValidationError{Prefix: prefix, Base: base, Reason: fmt.Sprintf("unable to parse base %s; %s", base, err)}
+	}
+
+	if !u.IsAbs() || u.Host == "" {
+		return &ValidationError{Prefix: prefix, Base: base, Reason: fmt.Sprintf("base %s must be an absolute URI", base)}
+	}
+
+	return nil
+}
+
 // SplitURI takes a given URI and splits it into a base-URI and a localname.
 // When the URI can't be split, the full URI is returned as the label with an
 // empty base.
@@ -133,7 +254,8 @@ func (ns *NameSpace) AddBase(base string) error {
 // Prefixes returns all namespace prefix linked to this NameSpace.
 // This includes the default Prefix and all alternative prefixes.
 func (ns *NameSpace) Prefixes() []string {
-	prefixes := append(ns.PrefixAlt, ns.Prefix)
+	// copy, so appending and sorting don't modify the PrefixAlt backing array
+	prefixes := append(append(make([]string, 0, len(ns.PrefixAlt)+1), ns.PrefixAlt...), ns.Prefix)
 	sort.Slice(prefixes, func(i, j int) bool {
 		return prefixes[i] < prefixes[j]
 	})
@@ -144,7 +266,7 @@ func (ns *NameSpace) Prefixes() []string {
 // BaseURIs returns all namespace base-URIs linked to this NameSpace.
 // This includes the default Base and all alternative base-URIs.
 func (ns *NameSpace) BaseURIs() []string {
-	baseURIs := append(ns.BaseAlt, ns.Base)
+	baseURIs := append(append(make([]string, 0, len(ns.BaseAlt)+1), ns.BaseAlt...), ns.Base)
 	sort.Slice(baseURIs, func(i, j int) bool {
 		return baseURIs[i] < baseURIs[j]
 	})
@@ -15,6 +15,7 @@
 package domain_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -365,3 +366,58 @@ func TestNameSpace_Merge(t *testing.T) {
 		})
 	}
 }
+
+func TestNameSpace_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ns      *domain.NameSpace
+		wantErr bool
+	}{
+		{"valid", &domain.NameSpace{Prefix: "dc", Base: dcNS}, false},
+		{"valid alternatives", &domain.NameSpace{Prefix: "dc", Base: dcNS, PrefixAlt: []string{"dc.1-1"}, BaseAlt: []string{dcAltNS}}, false},
+		{"generated temporary prefix", &domain.NameSpace{Prefix: "2110752caf2041fa", Base: dcNS, Temporary: true}, false},
+		{"empty base", &domain.NameSpace{Prefix: "dc"}, true},
+		{"relative base", &domain.NameSpace{Prefix: "dc", Base: "/dc/elements/1.1/"}, true},
+		{"base without host", &domain.NameSpace{Prefix: "dc", Base: "urn:dc"}, true},
+		{"empty prefix", &domain.NameSpace{Base: dcNS}, true},
+		{"empty temporary prefix", &domain.NameSpace{Base: dcNS, Temporary: true}, true},
+		{"prefix starts with a digit", &domain.NameSpace{Prefix: "1dc", Base: dcNS}, true},
+		{"prefix with underscore", &domain.NameSpace{Prefix: "dc_terms", Base: dcNS}, true},
+		{"invalid alternative prefix", &domain.NameSpace{Prefix: "dc", Base: dcNS, PrefixAlt: []string{"dc terms"}}, true},
+		{"invalid alternative base", &domain.NameSpace{Prefix: "dc", Base: dcNS, BaseAlt: []string{"dc/elements"}}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ns.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NameSpace.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, domain.ErrNameSpaceNotValid) {
+				t.Errorf("NameSpace.Validate() error = %v, want %v", err, domain.ErrNameSpaceNotValid)
+			}
+		})
+	}
+}
+
+func TestNameSpace_PrefixesAndBaseURIs(t *testing.T) {
+	is := is.New(t)
+
+	// spare capacity must not let Prefixes and BaseURIs modify the alternatives
+	prefixAlt := make([]string, 2, 4)
+	copy(prefixAlt, []string{"dce", "a-dc"})
+
+	baseAlt := make([]string, 1, 4)
+	copy(baseAlt, []string{dcAltNS})
+
+	ns := &domain.NameSpace{Prefix: "dc", Base: dcNS, PrefixAlt: prefixAlt, BaseAlt: baseAlt}
+
+	is.Equal(ns.Prefixes(), []string{"a-dc", "dc", "dce"})
+	is.Equal(ns.PrefixAlt, []string{"dce", "a-dc"})
+
+	is.Equal(ns.BaseURIs(), []string{dcNS, dcAltNS})
+	is.Equal(ns.BaseAlt, []string{dcAltNS})
+}
@@ -16,6 +16,7 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"unicode"
 )
 
@@ -29,6 +30,20 @@ var (
 	ErrOrgNotFound        = errors.New("organization not found")
 )
 
+// OrgNotFoundError is returned when the Organization with ID is not found.
+// It wraps ErrOrgNotFound so it can be checked with errors.Is.
+type OrgNotFoundError struct {
+	ID OrganizationID
+}
+
+func (e *OrgNotFoundError) Error() string {
+	return fmt.Sprintf("%s; %s", e.ID, ErrOrgNotFound)
+}
+
+func (e *OrgNotFoundError) Unwrap() error {
+	return ErrOrgNotFound
+}
+
 var (
 	// MaxLengthID the maximum length of an identifier
 	MaxLengthID = 10
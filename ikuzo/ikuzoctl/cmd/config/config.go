@@ -18,6 +18,7 @@ import (
 	"github.com/delving/hub3/ikuzo"
 	"github.com/delving/hub3/ikuzo/logger"
 	"github.com/delving/hub3/ikuzo/service/x/index"
+	"github.com/delving/hub3/ikuzo/storage/x/ginger"
 	"github.com/spf13/viper"
 )
 
@@ -38,8 +39,11 @@ type Config struct {
 	DB                `json:"db"`
 	ImageProxy        `json:"imageProxy"`
 	PostHooks         []PostHook `json:"posthooks"`
-	options           []ikuzo.Option
-	logger            logger.CustomLogger
+	// path to the BoltDB dead-letter queue for failed posthooks. Empty disables the queue.
+	PostHookDeadLetters string `json:"postHookDeadLetters"`
+	deadLetters         *ginger.DeadLetterQueue
+	options             []ikuzo.Option
+	logger              logger.CustomLogger
 }
 
 func (cfg *Config) IsDataNode() bool {
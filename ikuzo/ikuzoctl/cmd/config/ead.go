@@ -16,7 +16,6 @@ package config
 
 import (
 	"expvar"
-	"fmt"
 
 	"github.com/delving/hub3/ikuzo"
 	"github.com/delving/hub3/ikuzo/service/x/ead"
@@ -26,6 +25,8 @@ type EAD struct {
 	CacheDir string `json:"cacheDir"`
 	Metrics  bool   `json:"metrics"`
 	Workers  int    `json:"workers"`
+	// MaxFileSize is the maximum size of an uploaded EAD in MB. 0 is unlimited.
+	MaxFileSize int64 `json:"maxFileSize"`
 }
 
 func (e EAD) NewService(cfg *Config) (*ead.Service, error) {
@@ -38,15 +39,12 @@ func (e EAD) NewService(cfg *Config) (*ead.Service, error) {
 		ead.SetIndexService(is),
 		ead.SetDataDir(e.CacheDir),
 		ead.SetWorkers(e.Workers),
+		ead.SetMaxFileSize(e.MaxFileSize*1024*1024),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := svc.StartWorkers(); err != nil {
-		return nil, fmt.Errorf("unable to start EAD service workers; %w", err)
-	}
-
 	if e.Metrics {
 		expvar.Publish("hub3-ead-service", expvar.Func(func() interface{} { m := svc.Metrics(); return m }))
 	}
@@ -68,7 +66,7 @@ func (e *EAD) AddOptions(cfg *Config) error {
 	cfg.options = append(
 		cfg.options,
 		ikuzo.SetEADService(svc),
-		ikuzo.SetShutdownHook("ead-service", svc),
+		ikuzo.SetWorkerServices(svc),
 	)
 
 	return nil
@@ -121,6 +121,19 @@ func (e *ElasticSearch) AddOptions(cfg *Config) error {
 		return fmt.Errorf("unable to create bulk service; %w", isErr)
 	}
 
+	if len(postHooks) != 0 {
+		// publish deleted RDFRecords to the posthooks
+		models.RecordDeleteHook = bulkSvc.RecordDeleted
+	}
+
+	if cfg.deadLetters != nil {
+		// registered before SetBulkService, so it replaces its "bulk" shutdown hook
+		cfg.options = append(
+			cfg.options,
+			ikuzo.SetShutdownHook("bulk", &drainPostHooks{bulk: bulkSvc, deadLetters: cfg.deadLetters}),
+		)
+	}
+
 	cfg.options = append(
 		cfg.options,
 		ikuzo.SetBulkService(bulkSvc),
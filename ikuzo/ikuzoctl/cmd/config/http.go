@@ -14,13 +14,41 @@
 
 package config
 
-import "github.com/delving/hub3/ikuzo"
+import (
+	"time"
+
+	"github.com/delving/hub3/hub3/server/http/handlers"
+	"github.com/delving/hub3/ikuzo"
+)
 
 type HTTP struct {
 	Port        int    `json:"port" mapstructure:"port"`
 	MetricsPort int    `json:"metricsPort"`
 	CertFile    string `json:"certFile"`
 	KeyFile     string `json:"keyFile"`
+	// Metrics serves the Prometheus metrics at /metrics on the http server.
+	Metrics bool `json:"metrics"`
+	// SearchCacheSize is the size of the search cache in megabytes. The cache is disabled when it is 0.
+	SearchCacheSize int `json:"searchCacheSize"`
+	// SearchCacheTTL is the time in seconds before a cached search response expires.
+	SearchCacheTTL int `json:"searchCacheTTL"`
+	// JSONKeyNaming is the naming convention of the keys in the JSON search results,
+	// i.e. 'camelCase' or 'snake_case'. The keys are not changed when it is empty.
+	JSONKeyNaming string `json:"jsonKeyNaming"`
+	// DefaultRows is the number of search results per page when rows is not set. 0 uses the default of 16.
+	DefaultRows int `json:"defaultRows"`
+	// MaxRows is the maximum number of search results per page. 0 uses the default of 1000.
+	MaxRows int `json:"maxRows"`
+	// RejectExceedingRows returns a 400 when rows exceeds MaxRows instead of clamping it.
+	RejectExceedingRows bool `json:"rejectExceedingRows"`
+	// APIPrefix is the path prefix where all the routes are mounted, e.g. '/hub3'.
+	APIPrefix string `json:"apiPrefix"`
+	// MaxConcurrentRequests limits the number of concurrent requests. 0 is unlimited.
+	MaxConcurrentRequests int `json:"maxConcurrentRequests"`
+	// UnlimitedPrefixes are the path prefixes that are not limited by MaxConcurrentRequests.
+	UnlimitedPrefixes []string `json:"unlimitedPrefixes"`
+	// TrustedProxies are the CIDR ranges of the proxies whose X-Forwarded-For header is used to resolve the client IP.
+	TrustedProxies []string `json:"trustedProxies"`
 }
 
 func (http *HTTP) AddOptions(cfg *Config) error {
@@ -28,11 +56,57 @@ func (http *HTTP) AddOptions(cfg *Config) error {
 		cfg.options,
 		ikuzo.SetPort(http.Port),
 		ikuzo.SetTLS(http.CertFile, http.KeyFile),
+		ikuzo.SetAPIPrefix(http.APIPrefix),
 	)
 
+	if http.MaxConcurrentRequests != 0 {
+		cfg.options = append(
+			cfg.options,
+			ikuzo.SetMaxConcurrentRequests(http.MaxConcurrentRequests, http.UnlimitedPrefixes...),
+		)
+	}
+
+	if len(http.TrustedProxies) != 0 {
+		cfg.options = append(cfg.options, ikuzo.SetTrustedProxies(http.TrustedProxies))
+	}
+
 	if http.MetricsPort != 0 {
 		cfg.options = append(cfg.options, ikuzo.SetMetricsPort(http.MetricsPort))
 	}
 
+	if http.Metrics {
+		cfg.options = append(cfg.options, ikuzo.SetMetrics())
+	}
+
 	return nil
 }
+
+// SearchOptions returns the options for the search handlers.
+func (http *HTTP) SearchOptions() []handlers.SearchOption {
+	options := []handlers.SearchOption{}
+
+	if http.SearchCacheSize > 0 {
+		options = append(options, handlers.SetSearchCache(
+			int64(http.SearchCacheSize)*1e6,
+			time.Duration(http.SearchCacheTTL)*time.Second,
+		))
+	}
+
+	if http.JSONKeyNaming != "" {
+		options = append(options, handlers.SetJSONKeyNaming(handlers.KeyNaming(http.JSONKeyNaming)))
+	}
+
+	if http.DefaultRows > 0 {
+		options = append(options, handlers.SetDefaultRows(http.DefaultRows))
+	}
+
+	if http.MaxRows > 0 {
+		options = append(options, handlers.SetMaxRows(http.MaxRows))
+	}
+
+	if http.RejectExceedingRows {
+		options = append(options, handlers.SetRejectExceedingRows())
+	}
+
+	return options
+}
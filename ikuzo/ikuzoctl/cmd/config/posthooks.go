@@ -1,6 +1,16 @@
 package config
 
 import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/delving/hub3/ikuzo"
+	"github.com/delving/hub3/ikuzo/service/organization"
 	"github.com/delving/hub3/ikuzo/service/x/bulk"
 	"github.com/delving/hub3/ikuzo/storage/x/ginger"
 )
@@ -11,25 +21,175 @@ type PostHook struct {
 	URL         string   `json:"url"`
 	OrgID       string   `json:"orgID"`
 	APIKey      string   `json:"apiKey"`
+	// number of retries for a failed post. default: 3. Use -1 to disable retries.
+	Retries int `json:"retries"`
+	// retryDelay seconds before the first retry. default: 5
+	RetryDelay int `json:"retryDelay"`
+	// retryStatusCodes are the response codes that are retried. default: 400, 500
+	RetryStatusCodes []int `json:"retryStatusCodes"`
+	// deleteStatusCodes are the response codes of a delete that are a success. default: 200, 202, 204, 404, 410
+	DeleteStatusCodes []int `json:"deleteStatusCodes"`
+	// format of the posted records: jsonld, ntriples or turtle. default: jsonld
+	Format string `json:"format"`
+	// secret to sign the requests with HMAC-SHA256 in the X-Hub-Signature header
+	Secret string `json:"secret"`
+	// batchSize number of records that are posted in one request. default: 0 (disabled)
+	BatchSize int `json:"batchSize"`
+	// batchInterval seconds before a batch that is not full is posted. default: 5
+	BatchInterval int `json:"batchInterval"`
+	// gzip compresses the posted records
+	Gzip bool `json:"gzip"`
 }
 
-// nolint:unparam // in the future other posthook services can return errors
 func (cfg *Config) getPostHookServices() ([]bulk.PostHookService, error) {
 	svc := []bulk.PostHookService{}
 
+	dlq, err := cfg.getDeadLetterQueue()
+	if err != nil {
+		return nil, err
+	}
+
 	for _, ph := range cfg.PostHooks {
 		if ph.Name == "ginger" && ph.URL != "" {
-			svc = append(
-				svc,
-				ginger.NewPostHook(
-					ph.OrgID,
-					ph.URL,
-					ph.APIKey,
-					ph.ExcludeSpec...,
-				),
+			options := ph.options()
+			if dlq != nil {
+				options = append(options, ginger.SetDeadLetterQueue(dlq))
+			}
+
+			hook, err := ginger.NewPostHook(
+				ph.OrgID,
+				ph.URL,
+				ph.APIKey,
+				options...,
 			)
+			if err != nil {
+				return nil, fmt.Errorf("unable to create posthook for %s; %w", ph.URL, err)
+			}
+
+			svc = append(svc, hook)
 		}
 	}
 
 	return svc, nil
 }
+
+// getDeadLetterQueue opens the dead-letter queue that is shared by all posthooks
+// and registers the route to inspect it.
+func (cfg *Config) getDeadLetterQueue() (*ginger.DeadLetterQueue, error) {
+	if cfg.deadLetters != nil || cfg.PostHookDeadLetters == "" {
+		return cfg.deadLetters, nil
+	}
+
+	dlq, err := ginger.NewDeadLetterQueue(cfg.PostHookDeadLetters)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.deadLetters = dlq
+
+	// the queue is closed by the shutdown hook of the bulk service, see drainPostHooks.
+	cfg.options = append(
+		cfg.options,
+		ikuzo.SetRouters(func(r chi.Router) {
+			r.With(cfg.requirePostHookKey).Get("/api/posthook/deadletters", dlq.HandleList)
+		}),
+	)
+
+	return dlq, nil
+}
+
+// requirePostHookKey only passes the requests for the dead letters of a
+// single posthook. The 'url' query parameter must be the URL of a configured
+// posthook and the 'api_key' query parameter its API key. When an
+// organization is resolved for the request, the posthook must belong to it.
+func (cfg *Config) requirePostHookKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		org, hasOrg := organization.FromRequest(r)
+
+		for _, ph := range cfg.PostHooks {
+			if ph.URL == "" || ph.URL != q.Get("url") || ph.APIKey == "" {
+				continue
+			}
+
+			if hasOrg && ph.OrgID != string(org.ID) {
+				continue
+			}
+
+			if subtle.ConstantTimeCompare([]byte(ph.APIKey), []byte(q.Get("api_key"))) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// drainPostHooks shuts down the bulk service before the dead-letter queue is
+// closed, so the posthooks that fail while they are drained are still stored.
+// The shutdown hooks of the server run concurrently, so it replaces the
+// shutdown hook of ikuzo.SetBulkService.
+type drainPostHooks struct {
+	bulk        *bulk.Service
+	deadLetters *ginger.DeadLetterQueue
+}
+
+func (d *drainPostHooks) Shutdown(ctx context.Context) error {
+	bulkErr := d.bulk.Shutdown(ctx)
+
+	if err := d.deadLetters.Shutdown(ctx); err != nil {
+		return fmt.Errorf("unable to close dead-letter queue; %w", err)
+	}
+
+	return bulkErr
+}
+
+func (ph *PostHook) options() []ginger.Option {
+	options := []ginger.Option{
+		ginger.SetExcludedDataSets(ph.ExcludeSpec...),
+	}
+
+	switch {
+	case ph.Retries < 0:
+		options = append(options, ginger.SetRetries(0))
+	case ph.Retries > 0:
+		options = append(options, ginger.SetRetries(ph.Retries))
+	}
+
+	if ph.RetryDelay > 0 {
+		options = append(options, ginger.SetRetryDelay(time.Duration(ph.RetryDelay)*time.Second))
+	}
+
+	if len(ph.RetryStatusCodes) != 0 {
+		options = append(options, ginger.SetRetryStatusCodes(ph.RetryStatusCodes...))
+	}
+
+	if len(ph.DeleteStatusCodes) != 0 {
+		options = append(options, ginger.SetDeleteStatusCodes(ph.DeleteStatusCodes...))
+	}
+
+	if ph.Secret != "" {
+		options = append(options, ginger.SetSecret(ph.Secret))
+	}
+
+	if ph.BatchSize > 0 {
+		interval := 5 * time.Second
+		if ph.BatchInterval > 0 {
+			interval = time.Duration(ph.BatchInterval) * time.Second
+		}
+
+		options = append(options, ginger.SetBatch(ph.BatchSize, interval))
+	}
+
+	if ph.Gzip {
+		options = append(options, ginger.SetGzip(true))
+	}
+
+	if ph.Format != "" {
+		options = append(options, ginger.SetFormat(ph.Format))
+	}
+
+	return options
+}
@@ -15,8 +15,11 @@
 package cmd
 
 import (
+	hub3Cfg "github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/index"
 	"github.com/delving/hub3/hub3/server/http/handlers"
 	"github.com/delving/hub3/ikuzo"
+	"github.com/delving/hub3/ikuzo/service/x/namespace"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -46,6 +49,19 @@ func serve() {
 			Msg("unable to create options")
 	}
 
+	// the search handlers resolve search labels with the namespaces of the namespace API,
+	// so it also contains the configured namespaces that are used at index time
+	namespaces, err := namespace.NewService(
+		namespace.WithDefaults(),
+		namespace.WithNameSpaces(hub3Cfg.Config.NameSpaceMap.ByPrefix()),
+	)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Stack().
+			Msg("unable to create namespace service")
+	}
+
 	options = append(
 		options,
 		ikuzo.SetBuildVersionInfo(
@@ -54,10 +70,17 @@ func serve() {
 		ikuzo.SetLegacyRouters(
 			handlers.RegisterDatasets,
 			handlers.RegisterEAD,
-			handlers.RegisterSearch,
+			handlers.NewSearchResource(namespaces, cfg.HTTP.SearchOptions()...).Routes,
+			handlers.NewNameSpaceResource(namespaces).Routes,
 		),
+		// flush persistent namespace stores on a graceful shutdown
+		ikuzo.SetShutdownHook("namespace", namespaces),
 	)
 
+	if cfg.ElasticSearch.Enabled {
+		options = append(options, ikuzo.SetReadinessCheck("elasticsearch", index.ESHealth))
+	}
+
 	// load dataNodeProxy last so that other urls are overwritten in the router
 	if !cfg.IsDataNode() {
 		options = append(options, ikuzo.SetDataNodeProxy(cfg.DataNodeURL))
@@ -0,0 +1,165 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+type clientIPKey struct{}
+
+// ParseTrustedProxies parses the CIDR ranges of the trusted proxies.
+// A single IP address is accepted as well, e.g. '10.0.0.1'.
+func ParseTrustedProxies(cidrs []string) ([]*net.IPNet, error) {
+	trusted := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q; %w", cidr, err)
+		}
+
+		trusted = append(trusted, ipNet)
+	}
+
+	return trusted, nil
+}
+
+// ResolveClientIP is a middleware that resolves the IP address of the client
+// and stores it in the request context. It can be retrieved with ClientIP.
+//
+// The X-Forwarded-For header is only used when the request comes from one of
+// the trusted proxies. It is walked from the right and the first address that
+// is not a trusted proxy is the client. Without trusted proxies the address of
+// the remote peer is used.
+func ResolveClientIP(trusted []*net.IPNet) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey{}, clientIP(r, trusted))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIP returns the IP address of the client that was resolved by
+// ResolveClientIP. When the middleware is not used the address of the remote
+// peer is returned.
+//
+// Middlewares that need the client IP, e.g. for logging or rate limiting,
+// should use ClientIP instead of reading the request headers.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+
+	return remoteIP(r)
+}
+
+// clientIP walks the X-Forwarded-For header from the right, skipping the
+// trusted proxy hops. It falls back to the address of the remote peer.
+func clientIP(r *http.Request, trusted []*net.IPNet) string {
+	ip := remoteIP(r)
+	if !isTrusted(ip, trusted) {
+		return ip
+	}
+
+	hops := r.Header.Values("X-Forwarded-For")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addrs := strings.Split(hops[i], ",")
+
+		for j := len(addrs) - 1; j >= 0; j-- {
+			addr := strings.TrimSpace(addrs[j])
+			if net.ParseIP(addr) == nil {
+				// a malformed hop cannot be trusted, so the last valid hop is the client
+				return ip
+			}
+
+			ip = addr
+
+			if !isTrusted(ip, trusted) {
+				return ip
+			}
+		}
+	}
+
+	return ip
+}
+
+// remoteIP returns the IP address of the remote peer without the port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func isTrusted(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, ipNet := range trusted {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIPHandler adds the client IP as a field to the context's logger
+// using fieldKey as field key.
+func clientIPHandler(fieldKey string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := ClientIP(r); ip != "" {
+				log := zerolog.Ctx(r.Context())
+				log.UpdateContext(func(c zerolog.Context) zerolog.Context {
+					return c.Str(fieldKey, ip)
+				})
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
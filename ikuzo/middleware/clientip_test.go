@@ -0,0 +1,140 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() unexpected error; %s", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{
+			name:       "no proxy",
+			remoteAddr: "203.0.113.5:1234",
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "spoofed header from untrusted source",
+			remoteAddr: "203.0.113.5:1234",
+			xff:        []string{"1.2.3.4"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "single trusted proxy",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"203.0.113.5"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "two trusted proxies",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"203.0.113.5, 192.168.1.1"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "spoofed hop before the client is skipped",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"1.2.3.4, 203.0.113.5"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "multiple headers",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"1.2.3.4", "203.0.113.5, 10.1.1.1"},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "malformed hop",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"203.0.113.5, not-an-ip"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "only trusted hops",
+			remoteAddr: "10.0.0.2:1234",
+			xff:        []string{"10.0.0.3"},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "ipv6 peer",
+			remoteAddr: "[2001:db8::1]:1234",
+			xff:        []string{"1.2.3.4"},
+			want:       "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+
+			for _, xff := range tt.xff {
+				req.Header.Add("X-Forwarded-For", xff)
+			}
+
+			var got string
+
+			handler := ResolveClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIP_withoutMiddleware(t *testing.T) {
+	is := is.New(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.5:1234"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	is.Equal(ClientIP(req), "203.0.113.5")
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	is := is.New(t)
+
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 127.0.0.1 ", "::1"})
+	is.NoErr(err)
+	is.Equal(len(trusted), 3)
+	is.Equal(trusted[1].String(), "127.0.0.1/32")
+	is.Equal(trusted[2].String(), "::1/128")
+
+	_, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	is.True(err != nil)
+
+	_, err = ParseTrustedProxies([]string{"proxy"})
+	is.True(err != nil)
+}
@@ -25,7 +25,11 @@ import (
 	"github.com/rs/zerolog/hlog"
 )
 
-// RequestLogger creates a middleware chain for request logging
+// RequestLogger creates a middleware chain for request logging.
+//
+// Each request log line contains the matched chi route pattern as 'route'.
+// The resolved organization is added as 'org' by the organization middleware
+// that runs after the RequestLogger.
 func RequestLogger(log *zerolog.Logger) func(next http.Handler) http.Handler {
 	c := alice.New()
 
@@ -35,16 +39,22 @@ func RequestLogger(log *zerolog.Logger) func(next http.Handler) http.Handler {
 	// Install some provided extra handler to set some request's context fields.
 	// Thanks to those handler, all our logs will come with some pre-populated fields.
 	c = c.Append(hlog.AccessHandler(func(r *http.Request, status, size int, duration time.Duration) {
-		hlog.FromRequest(r).Info().
+		event := hlog.FromRequest(r).Info().
 			Str("method", r.Method).
 			Str("url", r.URL.String()).
 			Int("status", status).
 			Int("size", size).
 			Dur("duration", duration).
-			Dict("params", LogParamsAsDict(r.URL.Query())).
-			Msg("")
+			Dict("params", LogParamsAsDict(r.URL.Query()))
+
+		// the access handler runs after routing, so the matched pattern is known
+		if pattern := routePattern(r); pattern != "" {
+			event = event.Str("route", pattern)
+		}
+
+		event.Msg("")
 	}))
-	c = c.Append(hlog.RemoteAddrHandler("ip"))
+	c = c.Append(clientIPHandler("ip"))
 	c = c.Append(hlog.UserAgentHandler("user_agent"))
 	c = c.Append(hlog.RefererHandler("referer"))
 	c = c.Append(hlog.RequestIDHandler("req_id", "Request-Id"))
@@ -57,6 +67,17 @@ func RequestLogger(log *zerolog.Logger) func(next http.Handler) http.Handler {
 	return c.Then
 }
 
+// routePattern returns the chi route pattern that matched the request, e.g.
+// '/api/datasets/{spec}'. It is empty when the request was not routed by chi.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	return rctx.RoutePattern()
+}
+
 // customURLParamHandler adds given urlParam from the request as a field to
 // the context's logger using fieldKey as field key.
 func customURLParamHandler(paramKey, fieldKey string) func(next http.Handler) http.Handler {
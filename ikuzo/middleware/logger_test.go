@@ -17,6 +17,7 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -53,6 +54,42 @@ func TestRequestLogger(t *testing.T) {
 	is.True(strings.Contains(buf.String(), `"url":"/test-ping",`))
 }
 
+func TestRequestLogger_route(t *testing.T) {
+	is := is.New(t)
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	r := chi.NewRouter()
+	r.Use(RequestLogger(&logger))
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/datasets/{spec}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/datasets/test?q=title", nil)
+
+	r.ServeHTTP(w, req)
+
+	var line map[string]interface{}
+	is.NoErr(json.Unmarshal(buf.Bytes(), &line))
+	is.Equal(line["route"], "/api/datasets/{spec}")
+	is.Equal(line["url"], "/api/datasets/test?q=title")
+	is.Equal(line["status"], float64(http.StatusOK))
+
+	// unrouted requests have no route
+	buf.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	line = map[string]interface{}{}
+	is.NoErr(json.Unmarshal(buf.Bytes(), &line))
+
+	_, ok := line["route"]
+	is.True(!ok)
+}
+
 func Test_LogParamsAsDict(t *testing.T) {
 	is := is.New(t)
 
@@ -15,6 +15,7 @@
 package ikuzo
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -22,6 +23,7 @@ import (
 
 	"github.com/delving/hub3/config"
 	"github.com/delving/hub3/ikuzo/logger"
+	"github.com/delving/hub3/ikuzo/middleware"
 	"github.com/delving/hub3/ikuzo/service/organization"
 	"github.com/delving/hub3/ikuzo/service/x/bulk"
 	"github.com/delving/hub3/ikuzo/service/x/ead"
@@ -29,6 +31,7 @@ import (
 	"github.com/delving/hub3/ikuzo/service/x/revision"
 	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch"
 	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
@@ -63,6 +66,19 @@ func SetMetricsPort(port int) Option {
 	}
 }
 
+// SetMetrics serves the Prometheus metrics at /metrics on the router of the server.
+func SetMetrics() Option {
+	return func(s *server) error {
+		s.routerFuncs = append(s.routerFuncs,
+			func(r chi.Router) {
+				r.Handle("/metrics", promhttp.Handler())
+			},
+		)
+
+		return nil
+	}
+}
+
 // SetTLS sets the TLS key and certificate.
 //
 // When both are set the server starts in TLS mode.
@@ -91,6 +107,78 @@ func SetDisableRequestLogger() Option {
 	}
 }
 
+// SetAPIPrefix mounts the default routes and the custom RouterFuncs under the
+// path prefix, e.g. '/hub3'. The RouterFuncs receive the prefixed sub-router,
+// so their routes must not include the prefix.
+//
+// The heartbeat middleware on '/ping' is not affected by the prefix.
+func SetAPIPrefix(prefix string) Option {
+	return func(s *server) error {
+		prefix = strings.Trim(prefix, "/")
+		if prefix == "" {
+			s.apiPrefix = ""
+			return nil
+		}
+
+		if strings.ContainsAny(prefix, "{}*") {
+			return fmt.Errorf("api prefix %q must not contain URL parameters", prefix)
+		}
+
+		s.apiPrefix = "/" + prefix
+
+		return nil
+	}
+}
+
+// SetMaxConcurrentRequests limits the number of requests that are served
+// concurrently to n. When the limit is reached a 503 is returned with a
+// Retry-After header. The limit is disabled when n is 0.
+//
+// Requests whose path starts with one of the unlimitedPrefixes, e.g. long-lived
+// scroll requests, are not counted and never rejected.
+func SetMaxConcurrentRequests(n int, unlimitedPrefixes ...string) Option {
+	return func(s *server) error {
+		if n < 0 {
+			return fmt.Errorf("max concurrent requests must not be negative: %d", n)
+		}
+
+		s.requestSlots = nil
+		if n > 0 {
+			s.requestSlots = make(chan struct{}, n)
+		}
+
+		s.unlimitedPrefixes = unlimitedPrefixes
+
+		return nil
+	}
+}
+
+// SetTrustedProxies sets the CIDR ranges of the proxies in front of the server,
+// e.g. '10.0.0.0/8'. The X-Forwarded-For header is only used to resolve the
+// client IP when the request comes from a trusted proxy, see
+// middleware.ClientIP. Without trusted proxies the header is ignored.
+func SetTrustedProxies(cidrs []string) Option {
+	return func(s *server) error {
+		trusted, err := middleware.ParseTrustedProxies(cidrs)
+		if err != nil {
+			return err
+		}
+
+		s.trustedProxies = trusted
+
+		return nil
+	}
+}
+
+// SetDisableIndexRoute stops the default index from being mounted on '/'.
+// The root then returns a 404, unless it is claimed by a custom RouterFunc.
+func SetDisableIndexRoute() Option {
+	return func(s *server) error {
+		s.disableIndexRoute = true
+		return nil
+	}
+}
+
 // SetMiddleware configures the global middleware for the HTTP router.
 func SetMiddleware(middleware ...func(next http.Handler) http.Handler) Option {
 	return func(s *server) error {
@@ -122,6 +210,19 @@ func SetOrganisationService(service *organization.Service) Option {
 	}
 }
 
+// SetOrganizationResolver resolves the organization for each request with the
+// resolver, see organization.HeaderResolver and organization.SubdomainResolver.
+// The resolved organization is available via organization.FromRequest.
+// Requests for an unknown organization return a 404.
+//
+// SetOrganisationService must be set as well.
+func SetOrganizationResolver(resolver organization.OrgIDResolver) Option {
+	return func(s *server) error {
+		s.orgResolver = resolver
+		return nil
+	}
+}
+
 // SetRevisionService configures the organization service.
 // When no service is set a default transient memory-based service is used.
 func SetRevisionService(service *revision.Service) Option {
@@ -216,7 +317,8 @@ func SetBulkService(svc *bulk.Service) Option {
 			},
 		)
 
-		return nil
+		// wait for the pending posthooks on shutdown
+		return SetShutdownHook("bulk", svc)(s)
 	}
 }
 
@@ -230,6 +332,25 @@ func SetShutdownHook(name string, hook Shutdown) Option {
 	}
 }
 
+// SetWorkerServices registers the WorkerServices with the background worker
+// pool. They are started when the Server starts listening and shutdown within
+// the graceful shutdown timeout.
+func SetWorkerServices(services ...WorkerService) Option {
+	return func(s *server) error {
+		s.workers.register(services...)
+		return nil
+	}
+}
+
+// SetReadinessCheck adds a named check to the '/readyz' endpoint.
+// The endpoint returns a 503 when one of the checks returns an error.
+func SetReadinessCheck(name string, check ReadinessCheck) Option {
+	return func(s *server) error {
+		s.readinessChecks[name] = check
+		return nil
+	}
+}
+
 func SetImageProxyService(service *imageproxy.Service) Option {
 	return func(s *server) error {
 		s.routerFuncs = append(s.routerFuncs,
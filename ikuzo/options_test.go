@@ -17,17 +17,24 @@ package ikuzo
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 
 	"github.com/go-chi/chi"
 	mw "github.com/go-chi/chi/middleware"
 
+	"github.com/delving/hub3/ikuzo/domain"
 	"github.com/delving/hub3/ikuzo/logger"
+	"github.com/delving/hub3/ikuzo/middleware"
+	"github.com/delving/hub3/ikuzo/service/organization"
+	"github.com/delving/hub3/ikuzo/storage/memory"
 	"github.com/matryer/is"
 	"github.com/rs/zerolog/log"
 )
@@ -87,6 +94,91 @@ func TestSetDisableRequestLogger(t *testing.T) {
 	is.True(svr.disableRequestLogger)
 }
 
+func TestSetDisableIndexRoute(t *testing.T) {
+	is := is.New(t)
+	svr, err := newServer(
+		SetDisableIndexRoute(),
+		SetDisableRequestLogger(),
+	)
+	is.NoErr(err)
+
+	for path, status := range map[string]int{
+		"/":       http.StatusNotFound,
+		"/readyz": http.StatusOK,
+	} {
+		req, err := http.NewRequest("GET", path, nil)
+		is.NoErr(err)
+
+		w := httptest.NewRecorder()
+		svr.ServeHTTP(w, req)
+		is.Equal(w.Code, status)
+	}
+}
+
+func TestSetMetrics(t *testing.T) {
+	is := is.New(t)
+
+	for _, tt := range []struct {
+		name    string
+		options []Option
+		status  int
+	}{
+		{"enabled", []Option{SetMetrics()}, http.StatusOK},
+		{"disabled", []Option{}, http.StatusNotFound},
+	} {
+		svr, err := newServer(append(tt.options, SetDisableRequestLogger())...)
+		is.NoErr(err)
+
+		req, err := http.NewRequest("GET", "/metrics", nil)
+		is.NoErr(err)
+
+		w := httptest.NewRecorder()
+		svr.ServeHTTP(w, req)
+		is.Equal(w.Code, tt.status) // /metrics status
+	}
+}
+
+func TestSetAPIPrefix(t *testing.T) {
+	is := is.New(t)
+	svr, err := newServer(
+		SetAPIPrefix("/hub3/"),
+		SetRouters(
+			func(r chi.Router) {
+				r.Get("/router-test", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, "router-test")
+				})
+			},
+		),
+		SetDisableRequestLogger(),
+	)
+	is.NoErr(err)
+	is.Equal(svr.apiPrefix, "/hub3")
+
+	for path, status := range map[string]int{
+		"/hub3":             http.StatusFound,
+		"/hub3/readyz":      http.StatusOK,
+		"/hub3/router-test": http.StatusOK,
+		"/ping":             http.StatusOK,
+		"/":                 http.StatusNotFound,
+		"/readyz":           http.StatusNotFound,
+		"/router-test":      http.StatusNotFound,
+	} {
+		req, err := http.NewRequest("GET", path, nil)
+		is.NoErr(err)
+
+		w := httptest.NewRecorder()
+		svr.ServeHTTP(w, req)
+		is.Equal(w.Code, status) // unexpected status for path
+
+		if status == http.StatusFound {
+			is.Equal(w.Header().Get("Location"), "/hub3/version")
+		}
+	}
+
+	_, err = newServer(SetAPIPrefix("/{org}"))
+	is.True(err != nil)
+}
+
 func TestSetMiddleware(t *testing.T) {
 	is := is.New(t)
 	svr, err := newServer()
@@ -135,3 +227,224 @@ func TestSetRouters(t *testing.T) {
 	is.Equal(w.Code, http.StatusOK)
 	is.Equal(w.Body.String(), "router-test")
 }
+
+func TestSetOrganizationResolver(t *testing.T) {
+	is := is.New(t)
+
+	// the resolver requires an organization.Service
+	_, err := newServer(
+		SetOrganizationResolver(organization.HeaderResolver("")),
+	)
+	is.True(err != nil)
+
+	orgs, err := organization.NewService(memory.NewOrganizationStore())
+	is.NoErr(err)
+	is.NoErr(orgs.Put(context.TODO(), domain.Organization{ID: "demo"}))
+
+	svr, err := newServer(
+		SetOrganisationService(orgs),
+		SetOrganizationResolver(organization.HeaderResolver("")),
+		SetRouters(
+			func(r chi.Router) {
+				r.Get("/org-test", func(w http.ResponseWriter, r *http.Request) {
+					org, _ := organization.FromRequest(r)
+					fmt.Fprint(w, org.ID)
+				})
+			},
+		),
+		SetDisableRequestLogger(),
+	)
+	is.NoErr(err)
+
+	req, err := http.NewRequest("GET", "/org-test", nil)
+	is.NoErr(err)
+	req.Header.Set(organization.DefaultOrgIDHeader, "demo")
+
+	w := httptest.NewRecorder()
+	svr.ServeHTTP(w, req)
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Body.String(), "demo")
+
+	// unknown organizations return a 404
+	req.Header.Set(organization.DefaultOrgIDHeader, "unknown")
+
+	w = httptest.NewRecorder()
+	svr.ServeHTTP(w, req)
+	is.Equal(w.Code, http.StatusNotFound)
+}
+
+func TestSetReadinessCheck(t *testing.T) {
+	is := is.New(t)
+
+	ready := true
+
+	svr, err := newServer(
+		SetReadinessCheck("elasticsearch", func(ctx context.Context) error {
+			if !ready {
+				return errors.New("cluster is red")
+			}
+
+			return nil
+		}),
+		SetReadinessCheck("store", func(ctx context.Context) error { return nil }),
+		SetDisableRequestLogger(),
+	)
+	is.NoErr(err)
+
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	is.NoErr(err)
+
+	w := httptest.NewRecorder()
+	svr.ServeHTTP(w, req)
+	is.Equal(w.Code, http.StatusOK)
+	is.True(strings.Contains(w.Body.String(), `"elasticsearch":{"ready":true}`))
+
+	// a failing check is reported separately
+	ready = false
+
+	w = httptest.NewRecorder()
+	svr.ServeHTTP(w, req)
+	is.Equal(w.Code, http.StatusServiceUnavailable)
+	is.True(strings.Contains(w.Body.String(), `"elasticsearch":{"ready":false,"error":"cluster is red"}`))
+	is.True(strings.Contains(w.Body.String(), `"store":{"ready":true}`))
+}
+
+func TestSetMaxConcurrentRequests(t *testing.T) {
+	is := is.New(t)
+
+	var (
+		entered = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	svr, err := newServer(
+		SetMaxConcurrentRequests(2, "/api/scroll"),
+		SetRouters(func(r chi.Router) {
+			r.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+				entered <- struct{}{}
+				<-release
+			})
+			r.Get("/api/scroll", func(w http.ResponseWriter, r *http.Request) {})
+		}),
+		SetDisableRequestLogger(),
+	)
+	is.NoErr(err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		svr.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		return w
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			get("/slow")
+		}()
+
+		<-entered
+	}
+
+	// the third simultaneous request is rejected
+	w := get("/slow")
+	is.Equal(w.Code, http.StatusServiceUnavailable)
+	is.Equal(w.Header().Get("Retry-After"), "1")
+
+	// unlimited prefixes are still served
+	is.Equal(get("/api/scroll").Code, http.StatusOK)
+
+	close(release)
+	wg.Wait()
+
+	// the slots are released when the requests are done
+	go func() { <-entered }()
+	is.Equal(get("/slow").Code, http.StatusOK)
+
+	_, err = newServer(SetMaxConcurrentRequests(-1))
+	is.True(err != nil)
+}
+
+type testWorker struct {
+	startErr error
+	started  bool
+	stopped  bool
+}
+
+func (tw *testWorker) Start(ctx context.Context) error {
+	tw.started = true
+	return tw.startErr
+}
+
+func (tw *testWorker) Shutdown(ctx context.Context) error {
+	tw.stopped = true
+	return nil
+}
+
+func TestSetWorkerServices(t *testing.T) {
+	is := is.New(t)
+
+	worker := &testWorker{}
+
+	svr, err := newServer(
+		SetWorkerServices(worker),
+		SetDisableRequestLogger(),
+	)
+	is.NoErr(err)
+	is.True(!worker.started)
+
+	err = svr.listenAndServe(syscall.SIGTERM)
+	is.NoErr(err)
+	is.True(worker.started)
+	is.True(worker.stopped)
+
+	// the server does not start when a worker fails to start
+	failing := &testWorker{startErr: errors.New("no connection")}
+
+	svr, err = newServer(
+		SetWorkerServices(failing),
+		SetDisableRequestLogger(),
+	)
+	is.NoErr(err)
+
+	err = svr.listenAndServe()
+	is.True(errors.Is(err, failing.startErr))
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	is := is.New(t)
+
+	_, err := newServer(SetTrustedProxies([]string{"invalid"}))
+	is.True(err != nil)
+
+	svr, err := newServer(
+		SetTrustedProxies([]string{"10.0.0.0/8"}),
+		SetRouters(func(r chi.Router) {
+			r.Get("/ip", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(middleware.ClientIP(r)))
+			})
+		}),
+		SetDisableRequestLogger(),
+	)
+	is.NoErr(err)
+	is.Equal(len(svr.trustedProxies), 1)
+
+	get := func(remoteAddr string) string {
+		req := httptest.NewRequest("GET", "/ip", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.5")
+
+		w := httptest.NewRecorder()
+		svr.ServeHTTP(w, req)
+
+		return w.Body.String()
+	}
+
+	is.Equal(get("10.0.0.2:1234"), "203.0.113.5")
+	// the header of an untrusted source is ignored
+	is.Equal(get("198.51.100.7:1234"), "198.51.100.7")
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ikuzo
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout is the maximum duration of a single ReadinessCheck.
+const readinessTimeout = 5 * time.Second
+
+// ReadinessCheck returns an error when a dependency of the server is not ready
+// to serve requests.
+type ReadinessCheck func(ctx context.Context) error
+
+// checkStatus is the result of a single ReadinessCheck.
+type checkStatus struct {
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleReadiness runs all ReadinessChecks and reports their status separately.
+// It returns a 503 when one of the checks fails.
+func (s *server) handleReadiness() http.HandlerFunc {
+	type response struct {
+		Ready  bool                   `json:"ready"`
+		Checks map[string]checkStatus `json:"checks"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		resp := response{
+			Ready:  true,
+			Checks: make(map[string]checkStatus, len(s.readinessChecks)),
+		}
+
+		for name, check := range s.readinessChecks {
+			status := checkStatus{Ready: true}
+
+			if err := check(ctx); err != nil {
+				status = checkStatus{Error: err.Error()}
+				resp.Ready = false
+
+				s.requestLogger(r).Warn().Err(err).Str("check", name).Msg("readiness check failed")
+			}
+
+			resp.Checks[name] = status
+		}
+
+		code := http.StatusOK
+		if !resp.Ready {
+			code = http.StatusServiceUnavailable
+		}
+
+		s.respond(w, r, resp, code)
+	}
+}
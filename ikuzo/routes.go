@@ -35,12 +35,17 @@ func DefaultMiddleware() []func(http.Handler) http.Handler {
 }
 
 // routes are the default routes for ikuzo.
-// These can be overwritten using SetRouters.
+// These can be overwritten using SetRouters. They are mounted under the
+// prefix that is set with SetAPIPrefix.
 //
 // All handlers should have lazy initialization, so when they are not called
 // no connections should be initialized.
 func (s *server) routes() {
-	s.router.Get("/", s.handleIndex())
+	if !s.disableIndexRoute {
+		s.apiRouter.Get("/", s.handleIndex())
+	}
+
+	s.apiRouter.Get("/readyz", s.handleReadiness())
 
 	s.fileServer("/static", assets.FileSystem)
 }
@@ -58,11 +63,11 @@ func (s *server) fileServer(path string, root http.FileSystem) {
 
 	fsServer := http.FileServer(root)
 
-	fs := http.StripPrefix(path, fsServer)
+	fs := http.StripPrefix(s.apiPrefix+path, fsServer)
 
 	path += "*"
 
-	s.router.Get(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	s.apiRouter.Get(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fs.ServeHTTP(w, r)
 	}))
 }
@@ -19,10 +19,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -45,6 +48,9 @@ import (
 const (
 	defaultServerPort      = 3000
 	defaultShutdownTimeout = 10
+	// retryAfterSeconds is the Retry-After of the requests that are rejected
+	// by SetMaxConcurrentRequests
+	retryAfterSeconds = 1
 )
 
 type Service interface {
@@ -67,6 +73,11 @@ type Shutdown interface {
 type server struct {
 	// router is compatible with http.Mux
 	router chi.Router
+	// apiRouter is the router where the default and custom routes are mounted.
+	// It is the router itself unless an apiPrefix is set.
+	apiRouter chi.Router
+	// apiPrefix is the path prefix of all the routes
+	apiPrefix string
 	// port is where the server will listen to TCP requests
 	port int
 	// metricsPort is the port where expvar is hosted
@@ -83,6 +94,14 @@ type server struct {
 	gracefulTimeout time.Duration
 	// disableRequestLogger stops logging of request information to the global logger
 	disableRequestLogger bool
+	// disableIndexRoute stops mounting handleIndex on '/'
+	disableIndexRoute bool
+	// requestSlots is a semaphore that limits the number of concurrent requests
+	requestSlots chan struct{}
+	// unlimitedPrefixes are the path prefixes that are not limited by requestSlots
+	unlimitedPrefixes []string
+	// trustedProxies are the proxies whose X-Forwarded-For header is used to resolve the client IP
+	trustedProxies []*net.IPNet
 	// logger is the custom zerolog logger
 	logger *logger.CustomLogger
 	// middleware is an array of middleware options that will be applied.
@@ -93,10 +112,14 @@ type server struct {
 	routerFuncs []RouterFunc
 	// service to access the organization store
 	organizations *organization.Service
+	// orgResolver resolves the organization of each request
+	orgResolver organization.OrgIDResolver
 	// revision gives access to the file storage
 	revision *revision.Service
 	// shutdownHooks are called on server shutdown
 	shutdownHooks map[string]Shutdown
+	// readinessChecks are reported by the '/readyz' endpoint
+	readinessChecks map[string]ReadinessCheck
 	// service context
 	ctx context.Context
 	// dataNodeProxy is the httputil.ReverseProxy for the datanode
@@ -119,6 +142,7 @@ func newServer(options ...Option) (*server, error) {
 		workers:         newWorkerPool(ctx),
 		gracefulTimeout: defaultShutdownTimeout * time.Second,
 		shutdownHooks:   make(map[string]Shutdown),
+		readinessChecks: make(map[string]ReadinessCheck),
 		ctx:             ctx,
 	}
 
@@ -146,20 +170,43 @@ func newServer(options ...Option) (*server, error) {
 	// recover is not optional
 	s.router.Use(s.recoverer)
 
+	// the client IP is resolved before all middleware that depends on it
+	s.router.Use(middleware.ResolveClientIP(s.trustedProxies))
+
+	if s.requestSlots != nil {
+		s.router.Use(s.limitConcurrency)
+	}
+
 	// setting up request logging middleware
 	if !s.disableRequestLogger {
 		s.router.Use(middleware.RequestLogger(&log.Logger))
 	}
 
+	// resolve organization after request logging so unknown organizations are logged
+	if s.orgResolver != nil {
+		if s.organizations == nil {
+			return nil, fmt.Errorf("organization resolver requires an organization.Service")
+		}
+
+		s.router.Use(s.organizations.ResolveOrganization(s.orgResolver))
+	}
+
 	// setting default services
 	s.setDefaultServices()
 
+	s.apiRouter = s.router
+	if s.apiPrefix != "" {
+		s.router.Route(s.apiPrefix, func(r chi.Router) {
+			s.apiRouter = r
+		})
+	}
+
 	// apply default routes
 	s.routes()
 
 	// apply custom routes
 	for _, f := range s.routerFuncs {
-		f(s.router)
+		f(s.apiRouter)
 	}
 
 	// s.logger.Debug().Msg(docgen.JSONRoutesDoc(s.router))
@@ -208,6 +255,10 @@ func (s *server) listenAndServe(testSignals ...interface{}) error {
 		}()
 	}
 
+	if err := s.workers.start(); err != nil {
+		return err
+	}
+
 	// start web-server
 	server := http.Server{Addr: fmt.Sprintf(":%d", s.port), Handler: s}
 
@@ -255,11 +306,12 @@ func (s *server) listenAndServe(testSignals ...interface{}) error {
 func (s *server) shutdown(server *http.Server) error {
 	log.Info().Msg("sending stop signal to background processes")
 
-	// cancel context to shutdown background processes and connections
-	s.cancelFunc()
+	// cancel context to shutdown background processes and connections, after
+	// the shutdown hooks have finished the queued work
+	defer s.cancelFunc()
 
 	// set maximum duration for graceful shutdown
-	ctx, cancel := context.WithTimeout(s.ctx, s.gracefulTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), s.gracefulTimeout)
 	defer cancel()
 
 	log.Info().Msg("stopping web-server")
@@ -275,6 +327,12 @@ func (s *server) shutdown(server *http.Server) error {
 		g.Go(func() error { return h.Shutdown(ctx) })
 	}
 
+	for _, w := range s.workers.services {
+		w := w
+
+		g.Go(func() error { return w.Shutdown(ctx) })
+	}
+
 	// wait until all background workers are finished
 	if err := g.Wait(); err != nil {
 		return fmt.Errorf("unable to shutdown all workers; %w", err)
@@ -298,7 +356,7 @@ func (s *server) handle404(w http.ResponseWriter, r *http.Request) {
 // handleIndex returns default information about the deployment
 func (s *server) handleIndex() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/version", http.StatusFound)
+		http.Redirect(w, r, s.apiPrefix+"/version", http.StatusFound)
 	}
 }
 
@@ -373,6 +431,34 @@ func (s *server) recoverer(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// limitConcurrency returns a 503 with a Retry-After header when all the
+// requestSlots are taken. Requests that match one of the unlimitedPrefixes
+// are always served.
+func (s *server) limitConcurrency(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range s.unlimitedPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		select {
+		case s.requestSlots <- struct{}{}:
+			defer func() { <-s.requestSlots }()
+		default:
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+			s.respondWithError(w, r, errors.New("too many concurrent requests"), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
 func (s *server) proxyDataNode(w http.ResponseWriter, r *http.Request) {
 	if s.dataNodeProxy == nil {
 		s.logger.Warn().Str("url", r.URL.String()).Msg("requesting proxy URL when proxy is not set")
@@ -227,6 +227,47 @@ func Test_server_Shutdown(t *testing.T) {
 	}
 }
 
+// queuedWork is a shutdown hook that finishes its queued work before it returns.
+type queuedWork struct {
+	serverCtx context.Context
+	finished  bool
+}
+
+func (q *queuedWork) Shutdown(ctx context.Context) error {
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// the server context is only canceled after the queued work is finished
+	if q.serverCtx.Err() != nil {
+		return q.serverCtx.Err()
+	}
+
+	q.finished = true
+
+	return nil
+}
+
+func Test_server_Shutdown_queuedWork(t *testing.T) {
+	is := is.New(t)
+
+	work := &queuedWork{}
+
+	svr, err := newServer(
+		SetShutdownHook("bulk", work),
+	)
+	is.NoErr(err)
+
+	work.serverCtx = svr.ctx
+
+	err = svr.shutdown(&http.Server{Handler: svr})
+	is.NoErr(err)
+	is.True(work.finished)
+	is.True(svr.ctx.Err() != nil) // background processes are stopped
+}
+
 func Test_server_ListenAndServe(t *testing.T) {
 	is := is.New(t)
 
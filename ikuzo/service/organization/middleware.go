@@ -0,0 +1,162 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package organization
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+)
+
+// DefaultOrgIDHeader is the request header that is read by HeaderResolver
+// when no header name is given.
+const DefaultOrgIDHeader = "X-Hub3-OrgID"
+
+type contextKey struct{}
+
+// orgContextKey is the key for the domain.Organization in the request context.
+var orgContextKey = contextKey{}
+
+// OrgIDResolver extracts the domain.OrganizationID from the request.
+// It returns an empty OrganizationID when the request does not contain one.
+type OrgIDResolver func(r *http.Request) domain.OrganizationID
+
+// HeaderResolver resolves the domain.OrganizationID from the request header.
+// When header is empty the DefaultOrgIDHeader is used.
+func HeaderResolver(header string) OrgIDResolver {
+	if header == "" {
+		header = DefaultOrgIDHeader
+	}
+
+	return func(r *http.Request) domain.OrganizationID {
+		return domain.OrganizationID(strings.TrimSpace(r.Header.Get(header)))
+	}
+}
+
+// SubdomainResolver resolves the domain.OrganizationID from the first label of
+// the request host, e.g. 'demo' for 'demo.example.com'. Hosts with less than
+// three labels and IP addresses do not contain an OrganizationID.
+func SubdomainResolver() OrgIDResolver {
+	return func(r *http.Request) domain.OrganizationID {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if net.ParseIP(host) != nil {
+			return ""
+		}
+
+		labels := strings.Split(host, ".")
+		if len(labels) < 3 {
+			return ""
+		}
+
+		return domain.OrganizationID(strings.ToLower(labels[0]))
+	}
+}
+
+// ResolveOrganization is a middleware that loads the domain.Organization
+// identified by the resolver and stores it in the request context.
+// It can be retrieved with FromRequest.
+//
+// Requests without an OrganizationID are passed on unchanged. When the
+// Organization is unknown a 404 is returned. The lookup is bound by the
+// request context and the lookup timeout, see SetLookupTimeout. When the
+// timeout is exceeded a 504 is returned.
+//
+// The OrganizationID is added as 'org' to the request logger.
+func (s *Service) ResolveOrganization(resolver OrgIDResolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := resolver(r)
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := id.Valid(); err != nil {
+				http.Error(w, domain.ErrOrgNotFound.Error(), http.StatusNotFound)
+				return
+			}
+
+			ctx := r.Context()
+
+			if s.lookupTimeout > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, s.lookupTimeout)
+				defer cancel()
+			}
+
+			org, err := s.Get(ctx, id)
+			if err != nil {
+				switch {
+				case errors.Is(err, domain.ErrOrgNotFound):
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				case errors.Is(err, context.DeadlineExceeded):
+					hlog.FromRequest(r).Warn().Err(err).
+						Str("orgID", string(id)).
+						Msg("organization lookup timed out")
+					http.Error(w, err.Error(), http.StatusGatewayTimeout)
+
+					return
+				case errors.Is(err, context.Canceled):
+					// the client has gone away
+					return
+				}
+
+				hlog.FromRequest(r).Error().Err(err).
+					Str("orgID", string(id)).
+					Msg("unable to resolve organization")
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+
+				return
+			}
+
+			// the request logger shares the logger, so 'org' is added to the request log line
+			hlog.FromRequest(r).UpdateContext(func(c zerolog.Context) zerolog.Context {
+				return c.Str("org", string(org.ID))
+			})
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), org)))
+		})
+	}
+}
+
+// NewContext returns a copy of ctx that contains the domain.Organization.
+func NewContext(ctx context.Context, org domain.Organization) context.Context {
+	return context.WithValue(ctx, orgContextKey, org)
+}
+
+// FromContext returns the domain.Organization stored in ctx.
+// The boolean is false when no Organization was resolved.
+func FromContext(ctx context.Context) (domain.Organization, bool) {
+	org, ok := ctx.Value(orgContextKey).(domain.Organization)
+	return org, ok
+}
+
+// FromRequest returns the domain.Organization that was resolved by the
+// ResolveOrganization middleware.
+func FromRequest(r *http.Request) (domain.Organization, bool) {
+	return FromContext(r.Context())
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// nolint:gocritic
+package organization_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/delving/hub3/ikuzo/service/organization"
+	"github.com/delving/hub3/ikuzo/storage/memory"
+	"github.com/matryer/is"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+)
+
+func TestService_ResolveOrganization(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := organization.NewService(memory.NewOrganizationStore())
+	is.NoErr(err)
+	is.NoErr(svc.Put(context.TODO(), domain.Organization{ID: "demo"}))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org, ok := organization.FromRequest(r)
+		fmt.Fprintf(w, "%s:%t", org.ID, ok)
+	})
+
+	tests := []struct {
+		name     string
+		resolver organization.OrgIDResolver
+		host     string
+		header   string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "known org from header",
+			resolver: organization.HeaderResolver(""),
+			header:   "demo",
+			wantCode: http.StatusOK,
+			wantBody: "demo:true",
+		},
+		{
+			name:     "unknown org from header",
+			resolver: organization.HeaderResolver(""),
+			header:   "unknown",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "invalid org from header",
+			resolver: organization.HeaderResolver(""),
+			header:   "Demo1",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "no header",
+			resolver: organization.HeaderResolver(""),
+			wantCode: http.StatusOK,
+			wantBody: ":false",
+		},
+		{
+			name:     "known org from subdomain",
+			resolver: organization.SubdomainResolver(),
+			host:     "demo.example.com:3000",
+			wantCode: http.StatusOK,
+			wantBody: "demo:true",
+		},
+		{
+			name:     "unknown org from subdomain",
+			resolver: organization.SubdomainResolver(),
+			host:     "unknown.example.com",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "host without subdomain",
+			resolver: organization.SubdomainResolver(),
+			host:     "example.com",
+			wantCode: http.StatusOK,
+			wantBody: ":false",
+		},
+		{
+			name:     "ip address",
+			resolver: organization.SubdomainResolver(),
+			host:     "127.0.0.1:3000",
+			wantCode: http.StatusOK,
+			wantBody: ":false",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+
+			if tt.header != "" {
+				req.Header.Set(organization.DefaultOrgIDHeader, tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			svc.ResolveOrganization(tt.resolver)(handler).ServeHTTP(w, req)
+
+			is.Equal(w.Code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				is.Equal(w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestService_ResolveOrganization_timeout(t *testing.T) {
+	is := is.New(t)
+
+	store := &slowStore{OrganizationStore: memory.NewOrganizationStore(), delay: 50 * time.Millisecond}
+
+	svc, err := organization.NewService(store, organization.SetLookupTimeout(5*time.Millisecond))
+	is.NoErr(err)
+	is.NoErr(svc.Put(context.TODO(), domain.Organization{ID: "demo"}))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called when the lookup times out")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(organization.DefaultOrgIDHeader, "demo")
+
+	w := httptest.NewRecorder()
+	svc.ResolveOrganization(organization.HeaderResolver(""))(handler).ServeHTTP(w, req)
+
+	is.Equal(w.Code, http.StatusGatewayTimeout)
+}
+
+func TestService_ResolveOrganization_logsOrg(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := organization.NewService(memory.NewOrganizationStore())
+	is.NoErr(err)
+	is.NoErr(svc.Put(context.TODO(), domain.Organization{ID: "demo"}))
+
+	var buf bytes.Buffer
+
+	logger := zerolog.New(&buf)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hlog.FromRequest(r).Info().Msg("")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(organization.DefaultOrgIDHeader, "demo")
+
+	h := hlog.NewHandler(logger)(svc.ResolveOrganization(organization.HeaderResolver(""))(handler))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	is.True(strings.Contains(buf.String(), `"org":"demo"`))
+}
@@ -16,7 +16,9 @@ package organization
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/delving/hub3/ikuzo/domain"
 )
@@ -40,16 +42,44 @@ type Store interface {
 // Service manages all interactions with domain.Organization Store
 type Service struct {
 	store Store
+	// lookupTimeout is the maximum duration of a lookup by the middleware.
+	lookupTimeout time.Duration
+}
+
+// ServiceOptionFunc is a function that configures the organization.Service.
+type ServiceOptionFunc func(*Service) error
+
+// SetLookupTimeout sets the maximum duration of the Organization lookup in
+// the ResolveOrganization middleware. When it is exceeded a 504 is returned.
+// The default is zero, i.e. the lookup is only bound by the request context.
+func SetLookupTimeout(timeout time.Duration) ServiceOptionFunc {
+	return func(s *Service) error {
+		if timeout < 0 {
+			return fmt.Errorf("lookup timeout cannot be negative: %s", timeout)
+		}
+
+		s.lookupTimeout = timeout
+
+		return nil
+	}
 }
 
 // NewService creates an organization.Service.
 // The organization.Store implementation is the storage backend for the service.
-func NewService(store Store) (*Service, error) {
+func NewService(store Store, options ...ServiceOptionFunc) (*Service, error) {
 	if store == nil {
 		return nil, fmt.Errorf("organization.Store implementation cannot be nil")
 	}
 
-	return &Service{store: store}, nil
+	s := &Service{store: store}
+
+	for _, option := range options {
+		if err := option(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
 }
 
 // Delete removes the domain.Organization from the Organization Store.
@@ -57,10 +87,42 @@ func (s *Service) Delete(ctx context.Context, id domain.OrganizationID) error {
 	return s.store.Delete(ctx, id)
 }
 
-// Get returns an domain.Organization and returns  ErrOrgNotFound when the Organization
-// is not found.
+// Get returns an domain.Organization and returns a *domain.OrgNotFoundError,
+// which wraps domain.ErrOrgNotFound, when the Organization is not found.
+//
+// Get returns ctx.Err() as soon as ctx is done, also when the Store ignores
+// the context, like the in-memory store.
 func (s *Service) Get(ctx context.Context, id domain.OrganizationID) (domain.Organization, error) {
-	return s.store.Get(ctx, id)
+	if err := ctx.Err(); err != nil {
+		return domain.Organization{}, err
+	}
+
+	type result struct {
+		org domain.Organization
+		err error
+	}
+
+	// buffered so the lookup can finish after ctx is done
+	done := make(chan result, 1)
+
+	go func() {
+		org, err := s.store.Get(ctx, id)
+		done <- result{org: org, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return domain.Organization{}, ctx.Err()
+	case res := <-done:
+		if errors.Is(res.err, domain.ErrOrgNotFound) {
+			var notFound *domain.OrgNotFoundError
+			if !errors.As(res.err, &notFound) {
+				res.err = &domain.OrgNotFoundError{ID: id}
+			}
+		}
+
+		return res.org, res.err
+	}
 }
 
 // Filter returns a list of domain.Organization based on the filterOptions.
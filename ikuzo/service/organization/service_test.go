@@ -19,6 +19,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/delving/hub3/ikuzo/domain"
 	"github.com/delving/hub3/ikuzo/service/organization"
@@ -154,3 +155,58 @@ func TestService(t *testing.T) {
 	getOrgID, err = svc.Get(ctx, orgID)
 	is.True(errors.Is(err, domain.ErrOrgNotFound))
 }
+
+// slowStore is an organization.Store that ignores the context, like the
+// in-memory store, and blocks each Get for delay.
+type slowStore struct {
+	*memory.OrganizationStore
+	delay time.Duration
+}
+
+func (s *slowStore) Get(ctx context.Context, id domain.OrganizationID) (domain.Organization, error) {
+	time.Sleep(s.delay)
+	return s.OrganizationStore.Get(ctx, id)
+}
+
+func TestService_Get(t *testing.T) {
+	is := is.New(t)
+
+	store := &slowStore{OrganizationStore: memory.NewOrganizationStore(), delay: 50 * time.Millisecond}
+
+	svc, err := organization.NewService(store)
+	is.NoErr(err)
+	is.NoErr(svc.Put(context.TODO(), domain.Organization{ID: "demo"}))
+
+	org, err := svc.Get(context.TODO(), "demo")
+	is.NoErr(err)
+	is.Equal(org.ID, domain.OrganizationID("demo"))
+
+	// not found is returned as a typed error
+	_, err = svc.Get(context.TODO(), "unknown")
+	is.True(errors.Is(err, domain.ErrOrgNotFound))
+
+	var notFound *domain.OrgNotFoundError
+	is.True(errors.As(err, &notFound))
+	is.Equal(notFound.ID, domain.OrganizationID("unknown"))
+
+	// the context is honoured even when the store ignores it
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Millisecond)
+	defer cancel()
+
+	_, err = svc.Get(ctx, "demo")
+	is.True(errors.Is(err, context.DeadlineExceeded))
+
+	// a lookup with a done context does not reach the store
+	_, err = svc.Get(ctx, "demo")
+	is.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestSetLookupTimeout(t *testing.T) {
+	is := is.New(t)
+
+	_, err := organization.NewService(memory.NewOrganizationStore(), organization.SetLookupTimeout(-time.Second))
+	is.True(err != nil)
+
+	_, err = organization.NewService(memory.NewOrganizationStore(), organization.SetLookupTimeout(time.Second))
+	is.NoErr(err)
+}
@@ -1,6 +1,7 @@
 package bulk
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/delving/hub3/hub3/fragments"
@@ -9,7 +10,9 @@ import (
 // PostHookItem holds the input data that a PostHookService can manipulate
 // before submitting it to the endpoint
 type PostHookItem struct {
-	Graph   *fragments.SortedGraph
+	Graph *fragments.SortedGraph
+	// Deleted removes the record with HubID from the endpoint.
+	// When HubID is empty the revision of the whole dataset is dropped.
 	Deleted bool
 	Subject string
 
@@ -23,8 +26,9 @@ type PostHookItem struct {
 type PostHookService interface {
 	// Add adds PostHookItems to the processing queue
 	// Add(item ...PostHookItem) error
-	// Publish pushes all the submitted jobs to PostHook endpoint
-	Publish(item ...*PostHookItem) error
+	// Publish pushes all the submitted jobs to PostHook endpoint.
+	// Publishing must stop when the context is cancelled.
+	Publish(ctx context.Context, item ...*PostHookItem) error
 	Valid(datasetID string) bool
 	DropDataset(id string, revision int) (*http.Response, error)
 	// Metrics()
@@ -16,26 +16,52 @@ package bulk
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/hub3/models"
 	"github.com/delving/hub3/ikuzo/service/x/index"
+	"github.com/gammazero/workerpool"
 	"github.com/go-chi/render"
 	"github.com/rs/zerolog/log"
 )
 
+const defaultPostHookWorkers = 4
+
+// ErrShutdown is returned when posthooks are submitted after the shutdown of the Service started.
+var ErrShutdown = errors.New("bulk service is shutting down")
+
+// postHookFlusher is implemented by PostHookServices that buffer items before publishing them.
+type postHookFlusher interface {
+	Flush(ctx context.Context) error
+}
+
 type Option func(*Service) error
 
 type Service struct {
-	index      *index.Service
-	indexTypes []string
-	postHooks  map[string][]PostHookService
+	index           *index.Service
+	indexTypes      []string
+	postHooks       map[string][]PostHookService
+	postHookWorkers int
+	wp              *workerpool.WorkerPool
+	wg              sync.WaitGroup
+	m               sync.Mutex
+	postHookErrs    []error
+	closing         sync.RWMutex
+	closed          bool
+	ctx             context.Context
+	cancel          context.CancelFunc
 }
 
 func NewService(options ...Option) (*Service, error) {
 	s := &Service{
-		indexTypes: []string{"v2"},
-		postHooks:  map[string][]PostHookService{},
+		indexTypes:      []string{"v2"},
+		postHooks:       map[string][]PostHookService{},
+		postHookWorkers: defaultPostHookWorkers,
 	}
 
 	// apply options
@@ -45,6 +71,9 @@ func NewService(options ...Option) (*Service, error) {
 		}
 	}
 
+	s.wp = workerpool.New(s.postHookWorkers)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
 	return s, nil
 }
 
@@ -72,6 +101,19 @@ func SetPostHookService(hooks ...PostHookService) Option {
 	}
 }
 
+// SetPostHookWorkers sets the maximum number of posthooks that are published concurrently.
+func SetPostHookWorkers(workers int) Option {
+	return func(s *Service) error {
+		if workers < 1 {
+			return fmt.Errorf("posthook workers must be at least 1: %d", workers)
+		}
+
+		s.postHookWorkers = workers
+
+		return nil
+	}
+}
+
 // bulkApi receives bulkActions in JSON form (1 per line) and processes them in
 // ingestion pipeline.
 func (s *Service) Handle(w http.ResponseWriter, r *http.Request) {
@@ -82,25 +124,9 @@ func (s *Service) Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(s.postHooks) != 0 && len(p.postHooks) != 0 {
-		applyHooks, ok := s.postHooks[p.stats.OrgID]
-		if ok {
-			go func() {
-				for _, hook := range applyHooks {
-					validHooks := []*PostHookItem{}
-
-					for _, ph := range p.postHooks {
-						if hook.Valid(ph.DatasetID) {
-							validHooks = append(validHooks, ph)
-						}
-					}
-
-					if err := hook.Publish(validHooks...); err != nil {
-						log.Error().Err(err).Msg("unable to submit posthooks")
-					}
-				}
-
-				log.Debug().Msg("submitted posthooks")
-			}()
+		if err := s.applyPostHooks(p.stats.OrgID, p.postHooks); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
 		}
 	}
 
@@ -109,6 +135,108 @@ func (s *Service) Handle(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, p.stats)
 }
 
+// applyPostHooks submits the items to each PostHookService of the organization.
+// Each PostHookService is published by the workerpool, so a slow or failing endpoint
+// does not block the others.
+//
+// ErrShutdown is returned when the Service is shutting down.
+func (s *Service) applyPostHooks(orgID string, items []*PostHookItem) error {
+	// the read lock guarantees that no posthooks are added to the waitgroup
+	// after Shutdown has started waiting for it.
+	s.closing.RLock()
+	defer s.closing.RUnlock()
+
+	if s.closed {
+		log.Warn().Str("orgID", orgID).Int("items", len(items)).Msg("rejected posthooks during shutdown")
+		return ErrShutdown
+	}
+
+	for _, hook := range s.postHooks[orgID] {
+		validHooks := []*PostHookItem{}
+
+		for _, ph := range items {
+			if hook.Valid(ph.DatasetID) {
+				validHooks = append(validHooks, ph)
+			}
+		}
+
+		if len(validHooks) == 0 {
+			continue
+		}
+
+		hook := hook
+
+		s.wg.Add(1)
+		s.wp.Submit(func() {
+			defer s.wg.Done()
+
+			if err := hook.Publish(s.ctx, validHooks...); err != nil {
+				log.Error().Err(err).Str("orgID", orgID).Msg("unable to submit posthooks")
+				s.addPostHookError(err)
+
+				return
+			}
+
+			log.Debug().Str("orgID", orgID).Int("items", len(validHooks)).Msg("submitted posthooks")
+		})
+	}
+
+	return nil
+}
+
+// RecordDeleted publishes the deletion of the RDFRecord to the posthooks of its organization.
+// It can be used as models.RecordDeleteHook.
+func (s *Service) RecordDeleted(r *models.RDFRecord) {
+	orgID, _, _, err := r.ExtractHubID()
+	if err != nil {
+		log.Error().Err(err).Str("hubID", r.HubID).Msg("unable to publish deleted record to posthooks")
+		return
+	}
+
+	err = s.applyPostHooks(orgID, []*PostHookItem{
+		{
+			Deleted:   true,
+			Subject:   strings.TrimSuffix(r.NamedGraphURI, "/graph"),
+			OrgID:     orgID,
+			DatasetID: r.Spec,
+			HubID:     r.HubID,
+			Revision:  r.Revision,
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Str("hubID", r.HubID).Msg("unable to publish deleted record to posthooks")
+	}
+}
+
+func (s *Service) addPostHookError(err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.postHookErrs = append(s.postHookErrs, err)
+}
+
+// Wait blocks until all submitted posthooks are published.
+// It returns the errors of the posthooks that failed since the previous call to Wait.
+func (s *Service) Wait() error {
+	s.wg.Wait()
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if len(s.postHookErrs) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(s.postHookErrs))
+	for _, err := range s.postHookErrs {
+		msgs = append(msgs, err.Error())
+	}
+
+	s.postHookErrs = nil
+
+	return fmt.Errorf("%d posthooks failed: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
 func (s *Service) NewParser() *Parser {
 	p := &Parser{
 		stats:         &Stats{},
@@ -124,10 +252,54 @@ func (s *Service) NewParser() *Parser {
 	return p
 }
 
+// flushPostHooks publishes the buffered items of all PostHookServices.
+func (s *Service) flushPostHooks(ctx context.Context) {
+	for orgID, hooks := range s.postHooks {
+		for _, hook := range hooks {
+			flusher, ok := hook.(postHookFlusher)
+			if !ok {
+				continue
+			}
+
+			if err := flusher.Flush(ctx); err != nil {
+				log.Error().Err(err).Str("orgID", orgID).Msg("unable to flush posthooks")
+				s.addPostHookError(err)
+			}
+		}
+	}
+}
+
 func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// added to implement ikuzo service interface
 }
 
+// Shutdown drains the posthooks in the following order:
+//
+//  1. new posthooks are rejected with ErrShutdown
+//  2. the submitted posthooks are published
+//  3. the buffered items of PostHookServices that implement Flush are published
+//  4. the workerpool is stopped
+//
+// When ctx is done before the posthooks are drained, the remaining posthooks are cancelled.
 func (s *Service) Shutdown(ctx context.Context) error {
-	return nil
+	s.closing.Lock()
+	s.closed = true
+	s.closing.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		s.flushPostHooks(ctx)
+		s.wp.StopWait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return fmt.Errorf("unable to finish publishing posthooks; %w", ctx.Err())
+	}
 }
@@ -0,0 +1,220 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/hub3/models"
+	"github.com/matryer/is"
+)
+
+type fakePostHook struct {
+	orgID     string
+	err       error
+	excluded  string
+	started   chan struct{}
+	release   chan struct{}
+	m         sync.Mutex
+	published []*PostHookItem
+}
+
+func (f *fakePostHook) Publish(ctx context.Context, items ...*PostHookItem) error {
+	if f.started != nil {
+		f.started <- struct{}{}
+	}
+
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	f.m.Lock()
+	f.published = append(f.published, items...)
+	f.m.Unlock()
+
+	return f.err
+}
+
+func (f *fakePostHook) Valid(datasetID string) bool {
+	return datasetID != f.excluded
+}
+
+func (f *fakePostHook) DropDataset(id string, revision int) (*http.Response, error) {
+	return nil, nil
+}
+
+func (f *fakePostHook) OrgID() string {
+	return f.orgID
+}
+
+func TestService_applyPostHooks(t *testing.T) {
+	is := is.New(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	slow := &fakePostHook{orgID: "hub3", started: started, release: release}
+	failing := &fakePostHook{orgID: "hub3", err: errors.New("endpoint down"), started: started, release: release}
+	excluding := &fakePostHook{orgID: "hub3", excluded: "spec1"}
+	other := &fakePostHook{orgID: "other"}
+
+	s, err := NewService(
+		SetPostHookWorkers(2),
+		SetPostHookService(slow, failing, excluding, other),
+	)
+	is.NoErr(err)
+
+	items := []*PostHookItem{
+		{DatasetID: "spec1", HubID: "hub3_spec1_1"},
+		{DatasetID: "spec1", HubID: "hub3_spec1_2"},
+	}
+
+	s.applyPostHooks("hub3", items)
+
+	// both workers must be publishing at the same time
+	<-started
+	<-started
+	close(release)
+
+	err = s.Wait()
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "endpoint down"))
+
+	is.Equal(len(slow.published), 2)
+	is.Equal(len(failing.published), 2)
+	is.Equal(len(excluding.published), 0) // all items are excluded
+	is.Equal(len(other.published), 0)     // posthook of another organization
+
+	is.NoErr(s.Wait()) // errors are reset after Wait
+}
+
+func TestService_Shutdown(t *testing.T) {
+	is := is.New(t)
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	hook := &fakePostHook{orgID: "hub3", started: started, release: release}
+
+	s, err := NewService(SetPostHookService(hook))
+	is.NoErr(err)
+
+	items := []*PostHookItem{{DatasetID: "spec1", HubID: "hub3_spec1_1"}}
+
+	// drain pending posthooks
+	s.applyPostHooks("hub3", items)
+	<-started
+	close(release)
+
+	is.NoErr(s.Shutdown(context.Background()))
+	is.Equal(len(hook.published), 1)
+
+	// new posthooks are rejected once shutdown has started
+	err = s.applyPostHooks("hub3", items)
+	is.True(errors.Is(err, ErrShutdown))
+	is.Equal(len(hook.published), 1)
+
+	// cancel pending posthooks when the graceful timeout expires
+	hook.release = make(chan struct{})
+
+	s, err = NewService(SetPostHookService(hook))
+	is.NoErr(err)
+
+	is.NoErr(s.applyPostHooks("hub3", items))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = s.Shutdown(ctx)
+	is.True(errors.Is(err, context.DeadlineExceeded))
+
+	err = s.Wait()
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), context.Canceled.Error()))
+	is.Equal(len(hook.published), 1)
+}
+
+type flushingPostHook struct {
+	fakePostHook
+	flushed bool
+}
+
+func (f *flushingPostHook) Flush(ctx context.Context) error {
+	f.flushed = true
+	return nil
+}
+
+func TestService_Shutdown_flush(t *testing.T) {
+	is := is.New(t)
+
+	hook := &flushingPostHook{fakePostHook: fakePostHook{orgID: "hub3"}}
+
+	s, err := NewService(SetPostHookService(hook))
+	is.NoErr(err)
+
+	is.NoErr(s.applyPostHooks("hub3", []*PostHookItem{{DatasetID: "spec1", HubID: "hub3_spec1_1"}}))
+	is.NoErr(s.Shutdown(context.Background()))
+
+	is.Equal(len(hook.published), 1)
+	is.True(hook.flushed) // buffered items are published after the submitted posthooks
+}
+
+func TestService_RecordDeleted(t *testing.T) {
+	is := is.New(t)
+
+	hook := &fakePostHook{orgID: "hub3"}
+
+	s, err := NewService(SetPostHookService(hook))
+	is.NoErr(err)
+
+	s.RecordDeleted(&models.RDFRecord{
+		HubID:         "hub3_spec1_123",
+		Spec:          "spec1",
+		NamedGraphURI: "http://data.hub3.org/resource/aggregation/spec1/123/graph",
+		Revision:      2,
+	})
+	is.NoErr(s.Wait())
+
+	is.Equal(len(hook.published), 1)
+
+	item := hook.published[0]
+	is.True(item.Deleted)
+	is.Equal(item.HubID, "hub3_spec1_123")
+	is.Equal(item.DatasetID, "spec1")
+	is.Equal(item.Subject, "http://data.hub3.org/resource/aggregation/spec1/123")
+
+	// records with an invalid hubID are not published
+	s.RecordDeleted(&models.RDFRecord{HubID: "spec1_123"})
+	is.NoErr(s.Wait())
+	is.Equal(len(hook.published), 1)
+}
+
+func TestSetPostHookWorkers(t *testing.T) {
+	is := is.New(t)
+
+	_, err := NewService(SetPostHookWorkers(0))
+	is.True(err != nil)
+}
@@ -52,3 +52,12 @@ func SetWorkers(workers int) Option {
 		return nil
 	}
 }
+
+// SetMaxFileSize sets the maximum size in bytes of an uploaded EAD file.
+// Larger uploads are rejected with a 413. When size is 0 there is no limit.
+func SetMaxFileSize(size int64) Option {
+	return func(s *Service) error {
+		s.maxFileSize = size
+		return nil
+	}
+}
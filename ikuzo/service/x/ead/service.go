@@ -85,6 +85,7 @@ type Service struct {
 	tasks        map[string]*Task
 	rw           sync.RWMutex
 	workers      int
+	maxFileSize  int64
 	cancel       context.CancelFunc
 	group        *errgroup.Group
 }
@@ -139,9 +140,17 @@ func (s *Service) findAvailableTask() *Task {
 	return tasks[0]
 }
 
+// StartWorkers starts the workers that process the EAD tasks.
+// They run until Shutdown is called.
 func (s *Service) StartWorkers() error {
+	return s.Start(context.Background())
+}
+
+// Start starts the workers that process the EAD tasks. The workers stop when
+// ctx is canceled or Shutdown is called. It implements ikuzo.WorkerService.
+func (s *Service) Start(ctx context.Context) error {
 	// create errgroup and add cancel to service
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	g, gctx := errgroup.WithContext(ctx)
 	_ = gctx
 
@@ -220,6 +229,9 @@ func (s *Service) findTask(orgID, datasetID string, filterActive bool) (*Task, e
 	return nil, ErrTaskNotFound
 }
 
+// GetTask returns the Task with the 'id' that is returned by Upload. The Meta
+// of the Task reports the processed inventories, published records and the
+// DAO retrieve errors.
 func (s *Service) GetTask(w http.ResponseWriter, r *http.Request) {
 	s.rw.RLock()
 	defer s.rw.RUnlock()
@@ -260,6 +272,11 @@ func (s *Service) CancelTask(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) Shutdown(ctx context.Context) error {
+	// the workers were never started
+	if s.cancel == nil {
+		return nil
+	}
+
 	// cancel workers.
 	s.cancel()
 
@@ -595,9 +612,24 @@ type taskResponse struct {
 }
 
 func (s *Service) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if s.maxFileSize > 0 {
+		if r.ContentLength > s.maxFileSize {
+			http.Error(w, ErrFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxFileSize)
+	}
+
 	in, header, err := r.FormFile("ead")
 	if err != nil {
+		if isBodyTooLarge(err) {
+			http.Error(w, ErrFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
 		http.Error(w, "cannot find ead form file", http.StatusBadRequest)
+
 		return
 	}
 
@@ -644,6 +676,12 @@ func (s *Service) handleUpload(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// isBodyTooLarge returns true when err is returned by the http.MaxBytesReader
+// because the request body exceeds the limit.
+func isBodyTooLarge(err error) bool {
+	return strings.Contains(err.Error(), "request body too large")
+}
+
 func (s *Service) SaveEAD(r io.Reader, size int64) (*bytes.Buffer, Meta, error) {
 	var meta Meta
 
@@ -19,6 +19,9 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -181,3 +184,48 @@ func TestService_GetName(t *testing.T) {
 		})
 	}
 }
+
+func TestService_Upload_maxFileSize(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := getTestService()
+	is.NoErr(err)
+
+	// remove test tmpDir
+	defer os.RemoveAll(svc.dataDir)
+
+	is.NoErr(SetMaxFileSize(1024)(svc))
+
+	f, _, err := getReader("4.ZHPB2.xml")
+	is.NoErr(err)
+
+	defer f.Close()
+
+	var body bytes.Buffer
+
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("ead", "4.ZHPB2.xml")
+	is.NoErr(err)
+
+	_, err = io.Copy(fw, f)
+	is.NoErr(err)
+	is.NoErr(mw.Close())
+
+	upload := func(contentLength int64) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/ead", bytes.NewReader(body.Bytes()))
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.ContentLength = contentLength
+
+		w := httptest.NewRecorder()
+		svc.Upload(w, r)
+
+		return w
+	}
+
+	// rejected on the Content-Length
+	is.Equal(upload(int64(body.Len())).Code, http.StatusRequestEntityTooLarge)
+
+	// rejected while reading a body with an unknown length
+	is.Equal(upload(-1).Code, http.StatusRequestEntityTooLarge)
+	is.Equal(svc.Metrics().Submitted, uint64(0))
+}
@@ -28,6 +28,7 @@ import (
 var (
 	ErrTaskNotFound         = errors.New("task not found")
 	ErrTaskAlreadySubmitted = errors.New("task already submitted")
+	ErrFileTooLarge         = errors.New("ead file exceeds the maximum upload size")
 )
 
 type ProcessingState string
@@ -0,0 +1,190 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/delving/hub3/ikuzo/storage/memory"
+)
+
+// PrefixBase is a prefix and base-URI pair that is added with AddBatch.
+type PrefixBase struct {
+	Prefix string
+	Base   string
+}
+
+// BatchStore is a Store that can persist multiple namespaces in a single
+// transaction.
+type BatchStore interface {
+	Store
+
+	// SetBatch persists all the NameSpace objects or none of them when an
+	// error is returned.
+	SetBatch(namespaces []*domain.NameSpace) error
+}
+
+// AddBatch adds the prefix and base-URI pairs like Add and returns the
+// NameSpace for each pair.
+//
+// The namespaces are resolved against the Store first and then written
+// together. When the Store implements BatchStore they are written in a single
+// transaction, so a failure rolls back the whole batch. Otherwise they are set
+// one by one. Nothing is written when one of the pairs is not valid.
+func (s *Service) AddBatch(pairs []PrefixBase) ([]*domain.NameSpace, error) {
+	namespaces, _, err := s.addBatch(pairs, false)
+	return namespaces, err
+}
+
+// addBatch adds the pairs like AddBatch and also returns the number of
+// namespaces that were created or updated in the Store. When relaxed is true
+// the base-URIs don't need to end with a namespace delimiter.
+func (s *Service) addBatch(pairs []PrefixBase, relaxed bool) (namespaces []*domain.NameSpace, written int, err error) {
+	s.checkStore()
+
+	for _, pair := range pairs {
+		if relaxed {
+			err = domain.ValidateBaseURI(pair.Prefix, pair.Base)
+		} else {
+			err = s.validateBase(pair.Prefix, pair.Base)
+		}
+
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	staged := newStagedStore(s.store)
+
+	namespaces = make([]*domain.NameSpace, 0, len(pairs))
+
+	for _, pair := range pairs {
+		ns, _, err := s.add(staged, pair.Prefix, pair.Base)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to add namespace %s; %w", pair.Prefix, err)
+		}
+
+		namespaces = append(namespaces, ns)
+	}
+
+	if len(staged.written) == 0 {
+		return namespaces, 0, nil
+	}
+
+	if bs, ok := s.store.(BatchStore); ok {
+		if err := bs.SetBatch(staged.written); err != nil {
+			return nil, 0, fmt.Errorf("unable to store namespace batch; %w", err)
+		}
+	} else {
+		for _, ns := range staged.written {
+			if err := s.store.Set(ns); err != nil {
+				return nil, 0, fmt.Errorf("unable to store namespace %s; %w", ns.Prefix, err)
+			}
+		}
+	}
+
+	for _, ns := range staged.written {
+		s.touch(ns)
+		s.notify(EventAdd, ns)
+	}
+
+	return namespaces, len(staged.written), nil
+}
+
+// stagedStore collects the mutations of AddBatch on top of a Store.
+// Namespaces that are read from the underlying Store are copied, so they
+// are only changed in the Store when the batch is written.
+type stagedStore struct {
+	Store
+	staged  *memory.NameSpaceStore
+	written []*domain.NameSpace
+}
+
+func newStagedStore(store Store) *stagedStore {
+	return &stagedStore{
+		Store:  store,
+		staged: memory.NewNameSpaceStore(),
+	}
+}
+
+func (ss *stagedStore) Set(ns *domain.NameSpace) error {
+	if err := ss.staged.Set(ns); err != nil {
+		return err
+	}
+
+	for i, written := range ss.written {
+		if written.GetID() == ns.GetID() {
+			ss.written[i] = ns
+			return nil
+		}
+	}
+
+	ss.written = append(ss.written, ns)
+
+	return nil
+}
+
+func (ss *stagedStore) Delete(ns *domain.NameSpace) error {
+	return errors.New("namespaces can't be deleted in a batch")
+}
+
+func (ss *stagedStore) GetWithPrefix(prefix string) (*domain.NameSpace, error) {
+	return ss.get(prefix, ss.staged.GetWithPrefix, ss.Store.GetWithPrefix)
+}
+
+func (ss *stagedStore) GetWithBase(base string) (*domain.NameSpace, error) {
+	return ss.get(base, ss.staged.GetWithBase, ss.Store.GetWithBase)
+}
+
+// get returns the staged NameSpace for key. Otherwise a copy of the
+// NameSpace in the underlying Store is staged and returned.
+func (ss *stagedStore) get(key string, staged, stored func(string) (*domain.NameSpace, error)) (*domain.NameSpace, error) {
+	ns, err := staged(key)
+	if err == nil || !errors.Is(err, domain.ErrNameSpaceNotFound) {
+		return ns, err
+	}
+
+	ns, err = stored(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ns = clone(ns)
+
+	if err := ss.staged.Set(ns); err != nil {
+		return nil, err
+	}
+
+	return ns, nil
+}
+
+// clone returns a copy of the NameSpace that shares no slices or maps with ns.
+func clone(ns *domain.NameSpace) *domain.NameSpace {
+	c := *ns
+
+	c.BaseAlt = append([]string(nil), ns.BaseAlt...)
+	c.PrefixAlt = append([]string(nil), ns.PrefixAlt...)
+
+	if ns.Meta != nil {
+		c.Meta = make(map[string]string, len(ns.Meta))
+		for k, v := range ns.Meta {
+			c.Meta[k] = v
+		}
+	}
+
+	return &c
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/delving/hub3/ikuzo/storage/memory"
+	"github.com/matryer/is"
+)
+
+// failingBatchStore is a BatchStore whose SetBatch always fails.
+type failingBatchStore struct {
+	*memory.NameSpaceStore
+}
+
+func (fs *failingBatchStore) SetBatch(namespaces []*domain.NameSpace) error {
+	return errors.New("transaction failed")
+}
+
+func TestService_AddBatch(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	events := svc.Subscribe()
+
+	namespaces, err := svc.AddBatch([]PrefixBase{
+		{Prefix: "skos", Base: "http://www.w3.org/2004/02/skos/core#"},
+		{Prefix: "dce", Base: "http://purl.org/dc/elements/1.1/"},
+		{Prefix: "dc", Base: "http://purl.org/dc/elements/1.1/"},
+		{Prefix: "skos", Base: "http://www.w3.org/2004/02/skos/core#"},
+	})
+	is.NoErr(err)
+	is.Equal(len(namespaces), 4)
+	is.Equal(namespaces[0].Prefix, "skos")
+	is.Equal(namespaces[1].Prefix, "dc")
+	is.Equal(namespaces[1].PrefixAlt, []string{"dce"})
+	is.Equal(namespaces[2], namespaces[1])
+	is.Equal(namespaces[3], namespaces[0])
+	is.Equal(svc.Len(), 2)
+
+	ns, err := svc.GetWithPrefix("dce")
+	is.NoErr(err)
+	is.Equal(ns.Base, "http://purl.org/dc/elements/1.1/")
+
+	// one event for each stored namespace
+	is.Equal(len(events), 2)
+}
+
+func TestService_AddBatch_rollback(t *testing.T) {
+	t.Run("invalid pair", func(t *testing.T) {
+		is := is.New(t)
+
+		svc, err := NewService()
+		is.NoErr(err)
+
+		_, err = svc.AddBatch([]PrefixBase{
+			{Prefix: "dc", Base: "http://purl.org/dc/elements/1.1/"},
+			{Prefix: "invalid", Base: "not a uri"},
+		})
+		is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+		is.Equal(svc.Len(), 0)
+	})
+
+	t.Run("failed transaction", func(t *testing.T) {
+		is := is.New(t)
+
+		store := &failingBatchStore{memory.NewNameSpaceStore()}
+
+		svc, err := NewService(SetStore(store))
+		is.NoErr(err)
+
+		_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+		is.NoErr(err)
+
+		_, err = svc.AddBatch([]PrefixBase{
+			{Prefix: "dce", Base: "http://purl.org/dc/elements/1.1/"},
+			{Prefix: "skos", Base: "http://www.w3.org/2004/02/skos/core#"},
+		})
+		is.True(err != nil)
+		is.Equal(svc.Len(), 1)
+
+		// the stored namespace is not modified by the failed batch
+		ns, err := svc.GetWithPrefix("dc")
+		is.NoErr(err)
+		is.Equal(len(ns.PrefixAlt), 0)
+
+		_, err = svc.GetWithPrefix("dce")
+		is.True(errors.Is(err, domain.ErrNameSpaceNotFound))
+	})
+}
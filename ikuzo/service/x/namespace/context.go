@@ -0,0 +1,127 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const contextKey = "@context"
+
+// ImportContext adds the namespaces declared in a JSON-LD @context document.
+//
+// Both a full document with an "@context" key and a bare context object are
+// supported. Only prefix to base-URI string entries are imported; term
+// definitions, non-string values, reserved keywords like @vocab and @base and
+// term aliases like "title": "dc:title" are skipped. A string value is a
+// base-URI when it ends with '/' or '#'.
+//
+// The namespaces are added in a single batch, see AddBatch. The number of
+// namespaces that were created or updated is returned, so importing the same
+// context again returns 0.
+func (s *Service) ImportContext(r io.Reader) (added int, err error) {
+	s.checkStore()
+
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return 0, fmt.Errorf("unable to decode JSON-LD context; %w", err)
+	}
+
+	if ctx, ok := doc[contextKey]; ok {
+		doc = map[string]json.RawMessage{}
+		if err := json.Unmarshal(ctx, &doc); err != nil {
+			return 0, fmt.Errorf("unable to decode @context object; %w", err)
+		}
+	}
+
+	prefixes := make([]string, 0, len(doc))
+	for prefix := range doc {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Strings(prefixes)
+
+	pairs := []PrefixBase{}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(prefix, "@") {
+			continue
+		}
+
+		var base string
+		if err := json.Unmarshal(doc[prefix], &base); err != nil {
+			// term definitions are not namespaces
+			continue
+		}
+
+		if !strings.HasSuffix(base, "/") && !strings.HasSuffix(base, "#") {
+			// term aliases and compact IRIs are not namespaces
+			continue
+		}
+
+		pairs = append(pairs, PrefixBase{Prefix: prefix, Base: base})
+	}
+
+	_, added, err = s.addBatch(pairs, false)
+	if err != nil {
+		return 0, err
+	}
+
+	return added, nil
+}
+
+// ExportContext writes a JSON object that maps the default prefix of each
+// NameSpace to its base-URI. The output can be used as a JSON-LD @context.
+//
+// Temporary namespaces are excluded. Use ExportContextWithTemporary to include them.
+func (s *Service) ExportContext(w io.Writer) error {
+	return s.exportContext(w, false)
+}
+
+// ExportContextWithTemporary is the same as ExportContext but also includes
+// the temporary namespaces.
+func (s *Service) ExportContextWithTemporary(w io.Writer) error {
+	return s.exportContext(w, true)
+}
+
+func (s *Service) exportContext(w io.Writer, withTemporary bool) error {
+	s.checkStore()
+
+	namespaces, err := s.store.List()
+	if err != nil {
+		return err
+	}
+
+	ctx := make(map[string]string, len(namespaces))
+
+	for _, ns := range namespaces {
+		if ns.Temporary && !withTemporary {
+			continue
+		}
+
+		ctx[ns.Prefix] = ns.Base
+	}
+
+	// encoding/json sorts map keys so the output is stable
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(ctx)
+}
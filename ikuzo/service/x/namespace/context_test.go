@@ -0,0 +1,158 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+// nolint:gocritic
+func TestService_ImportContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		added   int
+		stored  int
+		wantErr bool
+	}{
+		{
+			"full document",
+			`{"@context": {
+				"dc": "http://purl.org/dc/elements/1.1/",
+				"skos": "http://www.w3.org/2004/02/skos/core#"
+			}}`,
+			2,
+			2,
+			false,
+		},
+		{
+			"bare context object",
+			`{"dc": "http://purl.org/dc/elements/1.1/"}`,
+			1,
+			1,
+			false,
+		},
+		{
+			"skip reserved keys and term definitions",
+			`{"@context": {
+				"@vocab": "http://schema.org/",
+				"@base": "http://example.com/",
+				"dc": "http://purl.org/dc/elements/1.1/",
+				"title": {"@id": "http://purl.org/dc/elements/1.1/title"},
+				"version": 1.1
+			}}`,
+			1,
+			1,
+			false,
+		},
+		{
+			"skip term aliases",
+			`{"@context": {
+				"dc": "http://purl.org/dc/elements/1.1/",
+				"title": "dc:title",
+				"name": "http://xmlns.com/foaf/0.1/name",
+				"skos": "http://www.w3.org/2004/02/skos/core#"
+			}}`,
+			2,
+			2,
+			false,
+		},
+		{
+			"prefixes sharing a base",
+			`{"@context": {
+				"dc": "http://purl.org/dc/elements/1.1/",
+				"dce": "http://purl.org/dc/elements/1.1/"
+			}}`,
+			1,
+			1,
+			false,
+		},
+		{
+			"invalid json",
+			`{"@context": `,
+			0,
+			0,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			svc, err := NewService()
+			is.NoErr(err)
+
+			added, err := svc.ImportContext(strings.NewReader(tt.doc))
+			is.Equal(err != nil, tt.wantErr)
+			is.Equal(added, tt.added)
+			is.Equal(svc.Len(), tt.stored)
+
+			// importing again should not add anything
+			if !tt.wantErr {
+				added, err = svc.ImportContext(strings.NewReader(tt.doc))
+				is.NoErr(err)
+				is.Equal(added, 0)
+			}
+		})
+	}
+}
+
+// nolint:gocritic
+func TestService_ExportContext(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	_, err = svc.Add("skos", "http://www.w3.org/2004/02/skos/core#")
+	is.NoErr(err)
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	tmp, err := svc.Add("", "http://example.com/temporary/")
+	is.NoErr(err)
+
+	var buf strings.Builder
+
+	err = svc.ExportContext(&buf)
+	is.NoErr(err)
+
+	want := `{
+  "dc": "http://purl.org/dc/elements/1.1/",
+  "skos": "http://www.w3.org/2004/02/skos/core#"
+}
+`
+	is.Equal(buf.String(), want)
+
+	buf.Reset()
+
+	err = svc.ExportContextWithTemporary(&buf)
+	is.NoErr(err)
+	is.True(strings.Contains(buf.String(), tmp.Prefix))
+
+	// exported context can be imported again
+	other, err := NewService()
+	is.NoErr(err)
+
+	added, err := other.ImportContext(strings.NewReader(want))
+	is.NoErr(err)
+	is.Equal(added, 2)
+}
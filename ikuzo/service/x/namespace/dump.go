@@ -0,0 +1,95 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/delving/hub3/ikuzo/domain"
+)
+
+// Dump writes all the namespaces as a single JSON array to w. The alternative
+// prefixes and base-URIs, the Temporary flag, the Meta and the LastUsed time are
+// included, so Load can restore the exact store in another Service.
+func (s *Service) Dump(w io.Writer) error {
+	namespaces, err := s.List()
+	if err != nil {
+		return fmt.Errorf("unable to list namespaces; %w", err)
+	}
+
+	// the stored namespaces are shared, so the LastUsed time is set on a copy
+	for i, ns := range namespaces {
+		namespaces[i] = clone(ns)
+
+		if lastUsed := s.lastUsedAt(ns); !lastUsed.IsZero() {
+			namespaces[i].LastUsed = &lastUsed
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(namespaces); err != nil {
+		return fmt.Errorf("unable to encode namespaces; %w", err)
+	}
+
+	return nil
+}
+
+// Load restores the namespaces that are written by Dump and returns the number
+// of namespaces that are stored. Each NameSpace is validated and stored like
+// Set, but its LastUsed time is kept. Loading stops at the first invalid
+// NameSpace.
+func (s *Service) Load(r io.Reader) (int, error) {
+	s.checkStore()
+
+	var namespaces []*domain.NameSpace
+	if err := json.NewDecoder(r).Decode(&namespaces); err != nil {
+		return 0, fmt.Errorf("unable to decode namespaces; %w", err)
+	}
+
+	for i, ns := range namespaces {
+		if err := s.restore(ns); err != nil {
+			return i, fmt.Errorf("unable to load namespace %d; %w", i, err)
+		}
+	}
+
+	return len(namespaces), nil
+}
+
+// restore validates and stores the NameSpace like Set without marking it as used.
+func (s *Service) restore(ns *domain.NameSpace) error {
+	if ns == nil {
+		return &domain.ValidationError{Reason: "namespace is required"}
+	}
+
+	if err := s.validateBase(ns.Prefix, ns.Base); err != nil {
+		return err
+	}
+
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.store.Set(ns); err != nil {
+		return err
+	}
+
+	s.notify(EventSet, ns)
+
+	return nil
+}
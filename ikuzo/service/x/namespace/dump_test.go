@@ -0,0 +1,126 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/matryer/is"
+)
+
+func TestService_DumpLoad(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(WithDefaults())
+	is.NoErr(err)
+
+	// operator edits: alternatives and temporary namespaces
+	_, err = svc.Add("dce", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	dc, err := svc.GetWithPrefix("dc")
+	is.NoErr(err)
+	is.NoErr(dc.AddBase("http://purl.org/dc/elements/1.2/"))
+	is.NoErr(svc.Set(dc))
+
+	_, err = svc.Add("", "http://example.org/temporary/")
+	is.NoErr(err)
+
+	var buf bytes.Buffer
+	is.NoErr(svc.Dump(&buf))
+
+	restored, err := NewService()
+	is.NoErr(err)
+
+	n, err := restored.Load(bytes.NewReader(buf.Bytes()))
+	is.NoErr(err)
+	is.Equal(n, svc.Len())
+
+	want, err := svc.List()
+	is.NoErr(err)
+
+	got, err := restored.List()
+	is.NoErr(err)
+
+	// the LastUsed time is only set on the dumped namespaces
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(domain.NameSpace{}, "LastUsed")); diff != "" {
+		t.Errorf("Service.Load() mismatch (-want +got):\n%s", diff)
+	}
+
+	for i, ns := range got {
+		is.True(ns.LastUsed != nil)
+		is.True(ns.LastUsed.Equal(svc.lastUsedAt(want[i])))
+	}
+
+	var again bytes.Buffer
+	is.NoErr(restored.Dump(&again))
+	is.Equal(again.String(), buf.String()) // round-tripping is stable
+
+	for _, base := range []string{"http://purl.org/dc/elements/1.2/", "http://example.org/temporary/"} {
+		ns, err := restored.GetWithBase(base)
+		is.NoErr(err)
+
+		orig, err := svc.GetWithBase(base)
+		is.NoErr(err)
+		is.Equal(ns.GetID(), orig.GetID())
+	}
+}
+
+func TestService_Load_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantN   int
+		wantErr error
+	}{
+		{"malformed json", `[{"prefix": "dc"`, 0, nil},
+		{
+			"invalid namespace",
+			`[{"prefix": "dc", "base": "http://purl.org/dc/elements/1.1/"}, {"prefix": "dc_terms", "base": "http://purl.org/dc/terms/"}]`,
+			1,
+			domain.ErrNameSpaceNotValid,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := NewService()
+			if err != nil {
+				t.Fatalf("NewService() unexpected error = %v", err)
+			}
+
+			n, err := svc.Load(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatalf("Service.Load() expected an error")
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.Load() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if n != tt.wantN || svc.Len() != tt.wantN {
+				t.Errorf("Service.Load() = %d and stored %d, want %d", n, svc.Len(), tt.wantN)
+			}
+		})
+	}
+}
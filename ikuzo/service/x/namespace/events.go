@@ -0,0 +1,83 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"github.com/delving/hub3/ikuzo/domain"
+)
+
+// subscriberBuffer is the number of events that are buffered for each subscriber.
+const subscriberBuffer = 64
+
+// EventOperation is the type of mutation of a NameSpace.
+type EventOperation string
+
+const (
+	EventAdd    EventOperation = "add"
+	EventSet    EventOperation = "set"
+	EventDelete EventOperation = "delete"
+)
+
+// NamespaceEvent is sent to subscribers when a NameSpace is mutated.
+type NamespaceEvent struct {
+	Operation EventOperation
+	NameSpace *domain.NameSpace
+}
+
+// Subscribe returns a channel that receives a NamespaceEvent after each
+// successful Add, Set or Delete.
+//
+// Events are delivered without blocking the writer. When the buffer of a
+// subscriber is full, the event is dropped for that subscriber so a slow
+// consumer can't stall writes.
+func (s *Service) Subscribe() <-chan NamespaceEvent {
+	ch := make(chan NamespaceEvent, subscriberBuffer)
+
+	s.subMu.Lock()
+	s.subscribers = append(s.subscribers, ch)
+	s.subMu.Unlock()
+
+	return ch
+}
+
+// Unsubscribe removes the subscription and closes the channel.
+func (s *Service) Unsubscribe(sub <-chan NamespaceEvent) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for i, ch := range s.subscribers {
+		if ch == sub {
+			s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+			close(ch)
+
+			return
+		}
+	}
+}
+
+// notify sends the event to all subscribers without blocking.
+func (s *Service) notify(op EventOperation, ns *domain.NameSpace) {
+	s.subMu.RLock()
+	defer s.subMu.RUnlock()
+
+	event := NamespaceEvent{Operation: op, NameSpace: ns}
+
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/matryer/is"
+)
+
+// nolint:gocritic
+func TestService_Subscribe(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	first := svc.Subscribe()
+	second := svc.Subscribe()
+
+	ns, err := svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	// adding the same pair again is not a mutation
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	err = svc.Set(ns)
+	is.NoErr(err)
+
+	err = svc.Delete(ns)
+	is.NoErr(err)
+
+	// failed writes are not sent
+	_, err = svc.Add("dc", "")
+	is.True(err != nil)
+
+	for _, sub := range []<-chan NamespaceEvent{first, second} {
+		for _, op := range []EventOperation{EventAdd, EventSet, EventDelete} {
+			event := <-sub
+			is.Equal(event.Operation, op)
+			is.Equal(event.NameSpace, ns)
+		}
+
+		is.Equal(len(sub), 0)
+	}
+
+	svc.Unsubscribe(first)
+
+	_, ok := <-first
+	is.True(!ok)
+
+	// a slow subscriber must not block writes
+	for i := 0; i < subscriberBuffer*2; i++ {
+		err = svc.Set(&domain.NameSpace{Prefix: "skos", Base: "http://www.w3.org/2004/02/skos/core#"})
+		is.NoErr(err)
+	}
+
+	is.Equal(len(second), subscriberBuffer)
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/rs/zerolog/log"
+)
+
+// PrefixGenerator returns the prefix for a new temporary NameSpace.
+//
+// exists reports if the prefix is already used in the Store, so the generator
+// can skip prefixes that are taken.
+type PrefixGenerator func(ns *domain.NameSpace, exists func(prefix string) (bool, error)) (string, error)
+
+// SetPrefixGenerator sets the PrefixGenerator for temporary namespaces.
+//
+// By default the generated ID of the NameSpace is used as prefix, see
+// domain.NameSpace.GetID. When the PrefixGenerator returns an error the
+// generated ID is used as fallback.
+func SetPrefixGenerator(gen PrefixGenerator) ServiceOptionFunc {
+	return func(s *Service) error {
+		s.prefixGenerator = gen
+		return nil
+	}
+}
+
+// SequentialPrefixes returns a PrefixGenerator that generates readable
+// prefixes by appending a sequence number to base, e.g. 'ns1', 'ns2'.
+// Prefixes that are already in the Store are skipped.
+func SequentialPrefixes(base string) PrefixGenerator {
+	var (
+		mu  sync.Mutex
+		seq int
+	)
+
+	return func(ns *domain.NameSpace, exists func(prefix string) (bool, error)) (string, error) {
+		if base == "" {
+			return "", errors.New("base for sequential prefixes is empty")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for {
+			seq++
+			prefix := fmt.Sprintf("%s%d", base, seq)
+
+			ok, err := exists(prefix)
+			if err != nil {
+				return "", err
+			}
+
+			if !ok {
+				return prefix, nil
+			}
+		}
+	}
+}
+
+// temporaryPrefix returns the prefix for a new temporary NameSpace.
+// It falls back to the generated ID of the NameSpace when no PrefixGenerator
+// is set or when it fails.
+func (s *Service) temporaryPrefix(st Store, ns *domain.NameSpace) string {
+	id := ns.GetID()
+
+	if s.prefixGenerator == nil {
+		return id
+	}
+
+	prefix, err := s.prefixGenerator(ns, prefixExists(st))
+	if err != nil || prefix == "" {
+		log.Warn().
+			Err(err).
+			Str("base", ns.Base).
+			Msg("unable to generate temporary prefix; falling back to the namespace ID")
+
+		return id
+	}
+
+	return prefix
+}
+
+// prefixExists returns a func that reports if the prefix is used by a
+// NameSpace in st.
+func prefixExists(st Store) func(prefix string) (bool, error) {
+	return func(prefix string) (bool, error) {
+		_, err := st.GetWithPrefix(prefix)
+		if err != nil {
+			if errors.Is(err, domain.ErrNameSpaceNotFound) {
+				return false, nil
+			}
+
+			return false, err
+		}
+
+		return true, nil
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/matryer/is"
+)
+
+func TestSequentialPrefixes(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(SetPrefixGenerator(SequentialPrefixes("ns")))
+	is.NoErr(err)
+
+	// ns2 is already taken, so it is skipped
+	_, err = svc.Add("ns2", "http://example.com/taken/")
+	is.NoErr(err)
+
+	var prefixes []string
+
+	for _, base := range []string{
+		"http://example.com/a/",
+		"http://example.com/b/",
+		"http://example.com/c/",
+	} {
+		ns, err := svc.Add("", base)
+		is.NoErr(err)
+		is.True(ns.Temporary)
+		is.True(ns.GetID() != "")
+
+		prefixes = append(prefixes, ns.Prefix)
+	}
+
+	is.Equal(prefixes, []string{"ns1", "ns3", "ns4"})
+
+	label, err := svc.SearchLabel("http://example.com/b/title")
+	is.NoErr(err)
+	is.Equal(label, "ns3_title")
+}
+
+func TestSetPrefixGenerator_fallback(t *testing.T) {
+	is := is.New(t)
+
+	failing := func(ns *domain.NameSpace, exists func(string) (bool, error)) (string, error) {
+		return "", errors.New("no prefix")
+	}
+
+	svc, err := NewService(SetPrefixGenerator(failing))
+	is.NoErr(err)
+
+	ns, err := svc.Add("", "http://example.com/a/")
+	is.NoErr(err)
+	is.Equal(ns.Prefix, ns.GetID())
+}
+
+func TestSequentialPrefixes_existingIDs(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(SetPrefixGenerator(SequentialPrefixes("ns")))
+	is.NoErr(err)
+
+	// temporary namespaces that were stored with a generated ID as prefix
+	legacy := &domain.NameSpace{Base: "http://example.com/legacy/", Temporary: true}
+	legacy.Prefix = legacy.GetID()
+
+	is.NoErr(svc.Set(legacy))
+
+	ns, err := svc.GetWithPrefix(legacy.Prefix)
+	is.NoErr(err)
+	is.Equal(ns.Base, legacy.Base)
+
+	label, err := svc.SearchLabel(legacy.Base + "title")
+	is.NoErr(err)
+	is.Equal(label, legacy.Prefix+"_title")
+
+	ns, err = svc.Add("", "http://example.com/new/")
+	is.NoErr(err)
+	is.Equal(ns.Prefix, "ns1")
+}
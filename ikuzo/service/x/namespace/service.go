@@ -15,13 +15,24 @@
 package namespace
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/delving/hub3/ikuzo/domain"
 	"github.com/delving/hub3/ikuzo/storage/memory"
+	"github.com/rs/zerolog/log"
 )
 
 // Store provides functionality to query and persist namespaces.
+//
+// Stores that need to flush or release resources can also implement io.Closer.
+// They are closed when the Service is shut down.
 type Store interface {
 
 	// Set persists the NameSpace object.
@@ -64,6 +75,26 @@ type Service struct {
 	// loadDefaults determines if the defaults are loaded into the store
 	// when it is empty.
 	loadDefaults bool
+
+	// seed are the configured namespaces that are added after the defaults.
+	seed map[string]string
+
+	// strictDelimiter rejects base-URIs that don't end with '#' or '/'.
+	// When false only a warning is logged.
+	strictDelimiter bool
+
+	// prefixGenerator generates the prefix of temporary namespaces.
+	// When nil the ID of the NameSpace is used.
+	prefixGenerator PrefixGenerator
+
+	// subscribers receive a NamespaceEvent for each mutation
+	subscribers []chan NamespaceEvent
+	subMu       sync.RWMutex
+
+	// lastUsed is the last time each NameSpace, by ID, was stored or looked up.
+	// It is kept apart from the stored namespaces, so lookups don't modify them.
+	lastUsed map[string]time.Time
+	usedMu   sync.Mutex
 }
 
 // NewService creates a new client to work with namespaces.
@@ -94,18 +125,57 @@ func NewService(options ...ServiceOptionFunc) (*Service, error) {
 	}
 
 	if s.loadDefaults {
+		pairs := []PrefixBase{}
+
 		for _, nsMap := range []map[string]string{defaultNS, customNS} {
 			for prefix, base := range nsMap {
-				if _, err := s.Add(prefix, base); err != nil {
-					return nil, err
-				}
+				pairs = append(pairs, PrefixBase{Prefix: prefix, Base: base})
 			}
 		}
+
+		// some of the curated defaults don't end with a namespace delimiter
+		if _, _, err := s.addBatch(pairs, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.seed) > 0 {
+		if err := s.addSeed(); err != nil {
+			return nil, err
+		}
 	}
 
 	return s, nil
 }
 
+// addSeed adds the namespaces of WithNameSpaces sorted by prefix.
+// Namespaces with a base-URI that is not valid are skipped with a warning.
+func (s *Service) addSeed() error {
+	prefixes := make([]string, 0, len(s.seed))
+	for prefix := range s.seed {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Strings(prefixes)
+
+	pairs := make([]PrefixBase, 0, len(prefixes))
+
+	for _, prefix := range prefixes {
+		base := s.seed[prefix]
+
+		if err := domain.ValidateBaseURI(prefix, base); err != nil {
+			log.Warn().Err(err).Str("prefix", prefix).Msg("skipping configured namespace")
+			continue
+		}
+
+		pairs = append(pairs, PrefixBase{Prefix: prefix, Base: base})
+	}
+
+	_, _, err := s.addBatch(pairs, true)
+
+	return err
+}
+
 // SetStore sets the persistence store for the namespace.Service.
 func SetStore(store Store) ServiceOptionFunc {
 	return func(s *Service) error {
@@ -122,6 +192,33 @@ func WithDefaults() ServiceOptionFunc {
 	}
 }
 
+// WithNameSpaces adds the prefix to base-URI pairs, e.g. the namespaces from
+// the configuration, when the Service is created. They are added after the
+// defaults, so a configured prefix for a default base-URI is stored as an
+// alternative prefix.
+func WithNameSpaces(prefix2base map[string]string) ServiceOptionFunc {
+	return func(s *Service) error {
+		if s.seed == nil {
+			s.seed = make(map[string]string, len(prefix2base))
+		}
+
+		for prefix, base := range prefix2base {
+			s.seed[prefix] = base
+		}
+
+		return nil
+	}
+}
+
+// WithStrictBaseValidation rejects base-URIs that don't end with a '#' or '/'
+// namespace delimiter. By default only a warning is logged.
+func WithStrictBaseValidation() ServiceOptionFunc {
+	return func(s *Service) error {
+		s.strictDelimiter = true
+		return nil
+	}
+}
+
 // checkStore sets the default store when no store is set.
 // This makes the default useful when the struct is directly initialized.
 // The preferred way to initialize Service is by using NewService()
@@ -131,36 +228,101 @@ func (s *Service) checkStore() {
 	}
 }
 
+// Shutdown closes the Store when it implements io.Closer, so persistent stores
+// are flushed on a graceful shutdown of the server. It returns the ctx error
+// when the ctx is done before the Store is closed.
+func (s *Service) Shutdown(ctx context.Context) error {
+	closer, ok := s.store.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- closer.Close()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("unable to close namespace store; %w", err)
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Add adds the prefix and base-URI to the namespace service.
 // When either the prefix or the base-URI is already present in the service the
 // unknown is stored as an alternative. If neither is present a new NameSpace
 // is created.
+//
+// A *domain.ValidationError is returned when the base-URI is not valid.
 func (s *Service) Add(prefix, base string) (*domain.NameSpace, error) {
 	s.checkStore()
 
-	if base == "" {
-		return nil, domain.ErrNameSpaceNotValid
+	return s.addTo(s.store, prefix, base)
+}
+
+// addTo validates and adds the prefix and base-URI to st like Add.
+// The subscribers are notified when st is modified.
+func (s *Service) addTo(st Store, prefix, base string) (*domain.NameSpace, error) {
+	if err := s.validateBase(prefix, base); err != nil {
+		return nil, err
+	}
+
+	ns, stored, err := s.add(st, prefix, base)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored {
+		s.touch(ns)
+		s.notify(EventAdd, ns)
+	}
+
+	return ns, nil
+}
+
+// add resolves the prefix and base-URI against st like Add and stores the
+// new or updated NameSpace in st. stored reports if st was modified.
+// The subscribers are not notified.
+func (s *Service) add(st Store, prefix, base string) (ns *domain.NameSpace, stored bool, err error) {
+	save := func(ns *domain.NameSpace) (*domain.NameSpace, bool, error) {
+		if err := ns.Validate(); err != nil {
+			return nil, false, err
+		}
+
+		ns.GetID()
+
+		if err := st.Set(ns); err != nil {
+			return nil, false, err
+		}
+
+		return ns, true, nil
 	}
 
 	if prefix == "" {
-		ns := &domain.NameSpace{
+		ns = &domain.NameSpace{
 			Base:      base,
 			Temporary: true,
 		}
-		ns.Prefix = ns.GetID()
-
-		err := s.store.Set(ns)
-		if err != nil {
-			return nil, err
-		}
+		ns.Prefix = s.temporaryPrefix(st, ns)
 
-		return ns, nil
+		return save(ns)
 	}
 
-	ns, err := s.store.GetWithPrefix(prefix)
+	ns, err = st.GetWithPrefix(prefix)
 	if err != nil {
 		if err != domain.ErrNameSpaceNotFound {
-			return nil, err
+			return nil, false, err
 		}
 	}
 
@@ -173,54 +335,355 @@ func (s *Service) Add(prefix, base string) (*domain.NameSpace, error) {
 				PrefixAlt: []string{prefix},
 				Temporary: true,
 			}
-			ns.Prefix = ns.GetID()
+			ns.Prefix = s.temporaryPrefix(st, ns)
 
-			err = s.store.Set(ns)
-			if err != nil {
-				return nil, err
-			}
+			return save(ns)
 		}
 
-		return ns, nil
+		return ns, false, nil
 	}
 
-	ns, err = s.store.GetWithBase(base)
+	ns, err = st.GetWithBase(base)
 	if err != nil {
 		if err != domain.ErrNameSpaceNotFound {
-			return nil, err
+			return nil, false, err
 		}
 	}
 
 	if ns != nil {
-		err = ns.AddPrefix(prefix)
-		if err != nil {
-			return nil, err
-		}
+		// the NameSpace can be shared with the Store, so only the copy is changed
+		ns = clone(ns)
 
-		err = s.store.Set(ns)
+		err = ns.AddPrefix(prefix)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
-		return ns, nil
+		return save(ns)
 	}
 
-	ns = &domain.NameSpace{
+	return save(&domain.NameSpace{
 		Prefix: prefix,
 		Base:   base,
+	})
+}
+
+// UniqueStore is a Store that can check for conflicting namespaces and
+// persist a NameSpace under a single lock.
+type UniqueStore interface {
+	Store
+
+	// SetUnique persists the NameSpace like Set, but returns a
+	// *domain.DuplicateEntryError when one of its prefixes or base-URIs is
+	// linked to another NameSpace.
+	SetUnique(ns *domain.NameSpace) error
+}
+
+// AddStrict adds the prefix and base-URI to the namespace service like Add, but
+// instead of silently storing conflicting entries as temporary alternatives
+// it returns a *domain.DuplicateEntryError when the prefix is already linked
+// to a different base-URI or the base-URI is already linked to a different
+// prefix.
+//
+// Adding a pair that is already present is not an error, also when the prefix
+// is an alternative prefix of the NameSpace. When the Store implements
+// UniqueStore the conflicts are checked again when the NameSpace is stored, so
+// a conflicting NameSpace that is added concurrently is not overwritten.
+func (s *Service) AddStrict(prefix, base string) (*domain.NameSpace, error) {
+	s.checkStore()
+
+	if prefix == "" || base == "" {
+		return nil, &domain.ValidationError{Prefix: prefix, Base: base, Reason: "prefix and base are required"}
+	}
+
+	if err := checkStrict(s.store, prefix, base); err != nil {
+		return nil, err
+	}
+
+	us, ok := s.store.(UniqueStore)
+	if !ok {
+		return s.addTo(s.store, prefix, base)
+	}
+
+	return s.addTo(&uniqueStore{UniqueStore: us, prefix: prefix, base: base}, prefix, base)
+}
+
+// checkStrict returns a *domain.DuplicateEntryError when the prefix or
+// base-URI is linked to another NameSpace in st.
+func checkStrict(st Store, prefix, base string) error {
+	ns, err := st.GetWithPrefix(prefix)
+	if err != nil && err != domain.ErrNameSpaceNotFound {
+		return err
+	}
+
+	if ns != nil && ns.Base != base {
+		return &domain.DuplicateEntryError{Prefix: prefix, Base: base, Existing: ns}
+	}
+
+	ns, err = st.GetWithBase(base)
+	if err != nil && err != domain.ErrNameSpaceNotFound {
+		return err
+	}
+
+	if ns != nil && !ns.Temporary && !hasPrefix(ns, prefix) {
+		return &domain.DuplicateEntryError{Prefix: prefix, Base: base, Existing: ns}
+	}
+
+	return nil
+}
+
+// hasPrefix returns true when prefix is the default or an alternative prefix of ns.
+func hasPrefix(ns *domain.NameSpace, prefix string) bool {
+	for _, p := range ns.Prefixes() {
+		if p == prefix {
+			return true
+		}
 	}
 
-	err = s.store.Set(ns)
+	return false
+}
+
+// uniqueStore adds the prefix and base-URI of AddStrict to a UniqueStore.
+// The namespaces it returns are copies, so a NameSpace is only changed in the
+// UniqueStore when it is stored without conflicts.
+type uniqueStore struct {
+	UniqueStore
+	prefix string
+	base   string
+}
+
+// Set only stores the NameSpace when it has the prefix and base-URI of
+// AddStrict as default. Otherwise they were added concurrently to another
+// NameSpace and stored as alternatives.
+func (us *uniqueStore) Set(ns *domain.NameSpace) error {
+	if ns.Prefix != us.prefix || ns.Base != us.base {
+		if err := checkStrict(us.UniqueStore, us.prefix, us.base); err != nil {
+			return err
+		}
+
+		return &domain.DuplicateEntryError{Prefix: us.prefix, Base: us.base}
+	}
+
+	return us.SetUnique(ns)
+}
+
+func (us *uniqueStore) GetWithPrefix(prefix string) (*domain.NameSpace, error) {
+	ns, err := us.UniqueStore.GetWithPrefix(prefix)
 	if err != nil {
 		return nil, err
 	}
 
-	return ns, nil
+	return clone(ns), nil
+}
+
+func (us *uniqueStore) GetWithBase(base string) (*domain.NameSpace, error) {
+	ns, err := us.UniqueStore.GetWithBase(base)
+	if err != nil {
+		return nil, err
+	}
+
+	return clone(ns), nil
+}
+
+// validateBase returns a *domain.ValidationError when the base is not an
+// absolute URI with a scheme and host.
+//
+// Base-URIs must end with '#' or '/' to be a valid namespace delimiter.
+// Otherwise a warning is logged, or an error is returned when the Service is
+// configured with WithStrictBaseValidation.
+func (s *Service) validateBase(prefix, base string) error {
+	if err := domain.ValidateBaseURI(prefix, base); err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(base, "#") || strings.HasSuffix(base, "/") {
+		return nil
+	}
+
+	if s.strictDelimiter {
+		return &domain.ValidationError{Prefix: prefix, Base: base, Reason: fmt.Sprintf("base %s must end with '#' or '/'", base)}
+	}
+
+	log.Warn().Str("base", base).Msg("namespace base does not end with '#' or '/'")
+
+	return nil
 }
 
 // Delete removes a namespace from the store
 func (s *Service) Delete(ns *domain.NameSpace) error {
-	return s.store.Delete(ns)
+	s.checkStore()
+
+	if err := s.store.Delete(ns); err != nil {
+		return err
+	}
+
+	s.forget(ns)
+
+	s.notify(EventDelete, ns)
+
+	return nil
+}
+
+// Merge consolidates two NameSpace entries for the same namespace.
+// The prefixes and base-URIs of drop are added as alternatives to a copy of
+// keep, after which drop is deleted and the copy is stored so all its prefixes
+// and base-URIs resolve to keep. The NameSpace objects that are passed in are
+// not modified. When the merged NameSpace cannot be stored, drop is restored.
+//
+// The default prefix of a permanent drop is demoted to an alternative prefix of
+// keep. The generated prefix of a temporary drop is discarded. When keep is
+// temporary and drop is not, keep takes the prefix of drop as its default.
+func (s *Service) Merge(keep, drop *domain.NameSpace) error {
+	s.checkStore()
+
+	if keep == nil || drop == nil || keep.GetID() == drop.GetID() {
+		return fmt.Errorf("merge requires two different namespaces; %w", domain.ErrNameSpaceNotValid)
+	}
+
+	prefixes := []string{}
+	if !drop.Temporary {
+		prefixes = append(prefixes, drop.Prefix)
+	}
+
+	prefixes = append(prefixes, drop.PrefixAlt...)
+
+	bases := append([]string{drop.Base}, drop.BaseAlt...)
+
+	merged := clone(keep)
+
+	for _, prefix := range prefixes {
+		if prefix == "" || prefix == merged.Prefix {
+			continue
+		}
+
+		if err := merged.AddPrefix(prefix); err != nil {
+			return err
+		}
+	}
+
+	for _, base := range bases {
+		if base == "" || base == merged.Base {
+			continue
+		}
+
+		if err := merged.AddBase(base); err != nil {
+			return err
+		}
+	}
+
+	if err := merged.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.store.Delete(drop); err != nil {
+		return fmt.Errorf("unable to delete merged namespace %s; %w", drop.Prefix, err)
+	}
+
+	if err := s.store.Set(merged); err != nil {
+		if restoreErr := s.store.Set(drop); restoreErr != nil {
+			return fmt.Errorf("unable to restore namespace %s after %s; %w", drop.Prefix, err, restoreErr)
+		}
+
+		return fmt.Errorf("unable to store merged namespace %s; %w", merged.Prefix, err)
+	}
+
+	s.forget(drop)
+
+	s.touch(merged)
+	s.notify(EventDelete, drop)
+	s.notify(EventSet, merged)
+
+	return nil
+}
+
+// GetWithPrefix returns the NameSpace for a given prefix.
+// When the prefix is not found, an ErrNameSpaceNotFound error is returned.
+func (s *Service) GetWithPrefix(prefix string) (*domain.NameSpace, error) {
+	s.checkStore()
+
+	ns, err := s.store.GetWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	s.touch(ns)
+
+	return ns, nil
+}
+
+// SetMeta replaces the Meta of the NameSpace with the given prefix.
+// An empty meta removes all annotations.
+// When the prefix is not found, an ErrNameSpaceNotFound error is returned.
+func (s *Service) SetMeta(prefix string, meta map[string]string) error {
+	s.checkStore()
+
+	ns, err := s.store.GetWithPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	var annotations map[string]string
+
+	if len(meta) != 0 {
+		annotations = make(map[string]string, len(meta))
+
+		for key, value := range meta {
+			if strings.TrimSpace(key) == "" {
+				return &domain.ValidationError{Prefix: prefix, Reason: "meta key is required"}
+			}
+
+			annotations[key] = value
+		}
+	}
+
+	// the NameSpace can be shared with the Store, so only the copy is changed
+	updated := clone(ns)
+	updated.Meta = annotations
+
+	return s.set(updated, EventSet)
+}
+
+// GetWithBase returns the NameSpace for a given base-URI.
+// When the base-URI is not found, an ErrNameSpaceNotFound error is returned.
+func (s *Service) GetWithBase(base string) (*domain.NameSpace, error) {
+	s.checkStore()
+
+	ns, err := s.store.GetWithBase(base)
+	if err != nil {
+		return nil, err
+	}
+
+	s.touch(ns)
+
+	return ns, nil
+}
+
+// PrefixesForBase returns all the prefixes of the NameSpace of the base-URI.
+// The default prefix is first, followed by the alternative prefixes in the
+// order they were added. This makes it possible to read search labels with
+// prefixes that are no longer the default.
+//
+// domain.ErrNameSpaceNotFound is returned when the base-URI is unknown.
+func (s *Service) PrefixesForBase(base string) ([]string, error) {
+	ns, err := s.GetWithBase(base)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixes := make([]string, 0, len(ns.PrefixAlt)+1)
+	seen := map[string]bool{}
+
+	for _, prefix := range append([]string{ns.Prefix}, ns.PrefixAlt...) {
+		if prefix == "" || seen[prefix] {
+			continue
+		}
+
+		seen[prefix] = true
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
 }
 
 // Len returns the number of namespaces in the Service
@@ -229,12 +692,238 @@ func (s *Service) Len() int {
 	return s.store.Len()
 }
 
-// List returns a list of all stored NameSpace objects.
+// List returns a list of all stored NameSpace objects in the order of the Store.
+// The default in-memory store sorts them by prefix and base.
 // An error is returned when the underlying storage can't be accessed.
 func (s *Service) List() ([]*domain.NameSpace, error) {
+	s.checkStore()
 	return s.store.List()
 }
 
+// ListOptions configures the page of namespaces returned by ListPage.
+type ListOptions struct {
+	// Offset is the number of namespaces that are skipped
+	Offset int
+	// Limit is the maximum number of namespaces returned. When zero all
+	// namespaces after the Offset are returned.
+	Limit int
+	// Temporary only returns the namespaces with a matching Temporary flag
+	// when it is not nil.
+	Temporary *bool
+}
+
+// ListPage returns a page of the stored NameSpace objects sorted by prefix and
+// the total number of namespaces that match the filter of the ListOptions.
+func (s *Service) ListPage(opts ListOptions) (namespaces []*domain.NameSpace, total int, err error) {
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, 0, fmt.Errorf("offset and limit must not be negative; %w", domain.ErrNameSpaceNotValid)
+	}
+
+	all, err := s.List()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	namespaces = []*domain.NameSpace{}
+
+	for _, ns := range all {
+		if opts.Temporary != nil && ns.Temporary != *opts.Temporary {
+			continue
+		}
+
+		namespaces = append(namespaces, ns)
+	}
+
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Prefix < namespaces[j].Prefix
+	})
+
+	total = len(namespaces)
+
+	if opts.Offset >= total {
+		return []*domain.NameSpace{}, total, nil
+	}
+
+	namespaces = namespaces[opts.Offset:]
+
+	if opts.Limit != 0 && opts.Limit < len(namespaces) {
+		namespaces = namespaces[:opts.Limit]
+	}
+
+	return namespaces, total, nil
+}
+
+// ListPermanent returns the namespaces with a curated prefix sorted by prefix.
+func (s *Service) ListPermanent() ([]*domain.NameSpace, error) {
+	temporary := false
+
+	namespaces, _, err := s.ListPage(ListOptions{Temporary: &temporary})
+
+	return namespaces, err
+}
+
+// ListTemporary returns the namespaces with a generated prefix sorted by
+// prefix, see domain.NameSpace.Temporary.
+func (s *Service) ListTemporary() ([]*domain.NameSpace, error) {
+	temporary := true
+
+	namespaces, _, err := s.ListPage(ListOptions{Temporary: &temporary})
+
+	return namespaces, err
+}
+
+// Counts returns the number of permanent and temporary namespaces.
+func (s *Service) Counts() (permanent, temporary int, err error) {
+	namespaces, err := s.List()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, ns := range namespaces {
+		if ns.Temporary {
+			temporary++
+			continue
+		}
+
+		permanent++
+	}
+
+	return permanent, temporary, nil
+}
+
+// NameSpaceStats contains the diagnostics of the namespaces in the Service.
+type NameSpaceStats struct {
+	// StoreReachable is false when the namespaces could not be listed from the Store.
+	StoreReachable bool `json:"storeReachable"`
+	NameSpaces     int  `json:"namespaces"`
+	Temporary      int  `json:"temporary"`
+	// AltPrefixes is the total number of alternative prefixes, excluding the default prefixes.
+	AltPrefixes int `json:"altPrefixes"`
+	// AltBases is the total number of alternative base-URIs, excluding the default base-URIs.
+	AltBases int `json:"altBases"`
+}
+
+// Stats returns the NameSpaceStats of the Service. When the Store cannot be
+// reached, the returned stats only have StoreReachable set to false and the
+// error of the Store is returned.
+func (s *Service) Stats() (NameSpaceStats, error) {
+	var stats NameSpaceStats
+
+	namespaces, err := s.List()
+	if err != nil {
+		return stats, fmt.Errorf("unable to list namespaces; %w", err)
+	}
+
+	stats.StoreReachable = true
+	stats.NameSpaces = len(namespaces)
+
+	for _, ns := range namespaces {
+		if ns.Temporary {
+			stats.Temporary++
+		}
+
+		for _, prefix := range ns.PrefixAlt {
+			if prefix != ns.Prefix {
+				stats.AltPrefixes++
+			}
+		}
+
+		for _, base := range ns.BaseAlt {
+			if base != ns.Base {
+				stats.AltBases++
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// PruneTemporary deletes the temporary namespaces that have not been used for
+// longer than olderThan. It returns the number of removed namespaces.
+//
+// A NameSpace is used when it is stored or returned by one of the lookup
+// methods of the Service. Namespaces that are not used since they were loaded
+// keep their domain.NameSpace.LastUsed time.
+func (s *Service) PruneTemporary(olderThan time.Duration) (removed int, err error) {
+	if olderThan <= 0 {
+		return 0, fmt.Errorf("olderThan must be positive; %w", domain.ErrNameSpaceNotValid)
+	}
+
+	namespaces, err := s.ListTemporary()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+
+	for _, ns := range namespaces {
+		if s.lastUsedAt(ns).After(cutoff) {
+			continue
+		}
+
+		if err := s.Delete(ns); err != nil {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	log.Info().
+		Int("removed", removed).
+		Dur("olderThan", olderThan).
+		Msg("pruned temporary namespaces")
+
+	return removed, nil
+}
+
+// touch marks the NameSpace as used. The NameSpace itself is not modified,
+// because it is shared by the Store.
+func (s *Service) touch(ns *domain.NameSpace) {
+	s.setLastUsed(ns, time.Now())
+}
+
+// setLastUsed records t as the last time the NameSpace was used.
+func (s *Service) setLastUsed(ns *domain.NameSpace, t time.Time) {
+	if ns.UUID == "" {
+		return
+	}
+
+	s.usedMu.Lock()
+	defer s.usedMu.Unlock()
+
+	if s.lastUsed == nil {
+		s.lastUsed = make(map[string]time.Time)
+	}
+
+	s.lastUsed[ns.UUID] = t
+}
+
+// forget removes the last use of a deleted NameSpace.
+func (s *Service) forget(ns *domain.NameSpace) {
+	s.usedMu.Lock()
+	defer s.usedMu.Unlock()
+
+	delete(s.lastUsed, ns.UUID)
+}
+
+// lastUsedAt returns the last time the NameSpace was used. It falls back to
+// the persisted domain.NameSpace.LastUsed when it is not used by this Service.
+func (s *Service) lastUsedAt(ns *domain.NameSpace) time.Time {
+	s.usedMu.Lock()
+	t, ok := s.lastUsed[ns.UUID]
+	s.usedMu.Unlock()
+
+	if ok {
+		return t
+	}
+
+	if ns.LastUsed != nil {
+		return *ns.LastUsed
+	}
+
+	return time.Time{}
+}
+
 // SearchLabel returns the URI in a short namespaced form.
 // The string is formatted as namespace prefix
 // and label joined with an underscore, e.g. "dc_title".
@@ -252,15 +941,125 @@ func (s *Service) SearchLabel(uri string) (string, error) {
 		return "", fmt.Errorf("unable to retrieve namespace for %s; %w", base, err)
 	}
 
+	s.touch(ns)
+
 	return fmt.Sprintf("%s_%s", ns.Prefix, label), nil
 }
 
+// SearchLabelByLongestBase returns the search label for the URI like
+// SearchLabel. When the base-URI of an exact split is not registered, it falls
+// back to the registered base-URI that is the longest prefix of the URI.
+//
+// This supports vocabularies where the local name is not separated from the
+// base-URI by a clean '#' or '/'.
+func (s *Service) SearchLabelByLongestBase(uri string) (string, error) {
+	label, err := s.SearchLabel(uri)
+	if err == nil || !errors.Is(err, domain.ErrNameSpaceNotFound) {
+		return label, err
+	}
+
+	namespaces, err := s.store.List()
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		match *domain.NameSpace
+		base  string
+	)
+
+	for _, ns := range namespaces {
+		for _, b := range ns.BaseURIs() {
+			if len(b) > len(base) && len(b) < len(uri) && strings.HasPrefix(uri, b) {
+				match, base = ns, b
+			}
+		}
+	}
+
+	if match == nil {
+		return "", fmt.Errorf("unable to retrieve namespace for %s; %w", uri, domain.ErrNameSpaceNotFound)
+	}
+
+	s.touch(match)
+
+	return fmt.Sprintf("%s_%s", match.Prefix, uri[len(base):]), nil
+}
+
+// DecodeSearchLabel is the inverse of SearchLabel. It parses a search label
+// like "dc_title" and returns the full URI it was created from.
+//
+// When the prefix is unknown ErrNameSpaceNotFound is returned.
+func (s *Service) DecodeSearchLabel(label string) (string, error) {
+	// URI also accepts a colon, but a search label must be joined with an underscore
+	if idx := strings.IndexAny(label, "_:"); idx <= 0 || label[idx] != '_' {
+		return "", fmt.Errorf("unable to decode search label %s; %w", label, domain.ErrNameSpaceNotValid)
+	}
+
+	return s.URI(label)
+}
+
+// URI expands a CURIE or search label back to the full URI, e.g. "dc:title"
+// or "dc_title" becomes "http://purl.org/dc/elements/1.1/title".
+//
+// The CURIE is split on the first underscore or colon, so the label itself
+// may contain underscores. When the prefix is unknown ErrNameSpaceNotFound is
+// returned.
+func (s *Service) URI(curie string) (string, error) {
+	s.checkStore()
+
+	idx := strings.IndexAny(curie, "_:")
+	if idx <= 0 {
+		return "", fmt.Errorf("unable to split %s into prefix and label; %w", curie, domain.ErrNameSpaceNotValid)
+	}
+
+	prefix, label := curie[:idx], curie[idx+1:]
+
+	ns, err := s.store.GetWithPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	s.touch(ns)
+
+	return ns.Base + label, nil
+}
+
 // Set sets the default prefix and base-URI for a namespace.
 // When the namespace is already present it will be overwritten.
 // When the NameSpace contains an unknown prefix and base-URI pair but one of them
 // is found in the NameSpace service, the current default is stored in PrefixAlt
 // or BaseAlt and the new default set.
+//
+// A *domain.ValidationError is returned when the NameSpace is nil or not
+// valid, see domain.NameSpace.Validate.
 func (s *Service) Set(ns *domain.NameSpace) error {
 	s.checkStore()
-	return s.store.Set(ns)
+
+	if ns == nil {
+		return &domain.ValidationError{Reason: "namespace is required"}
+	}
+
+	if err := s.validateBase(ns.Prefix, ns.Base); err != nil {
+		return err
+	}
+
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+
+	return s.set(ns, EventSet)
+}
+
+// set persists the NameSpace and notifies the subscribers when successful.
+func (s *Service) set(ns *domain.NameSpace, op EventOperation) error {
+	ns.GetID()
+
+	if err := s.store.Set(ns); err != nil {
+		return err
+	}
+
+	s.touch(ns)
+	s.notify(op, ns)
+
+	return nil
 }
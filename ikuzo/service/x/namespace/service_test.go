@@ -15,9 +15,19 @@
 package namespace
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/delving/hub3/ikuzo/domain"
+	"github.com/delving/hub3/ikuzo/storage/memory"
+	"github.com/google/go-cmp/cmp"
 	"github.com/matryer/is"
 )
 
@@ -251,3 +261,1072 @@ func TestListDelete(t *testing.T) {
 
 	is.Equal(len(namespaces), 2014)
 }
+
+func TestService_List(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(WithDefaults())
+	is.NoErr(err)
+
+	first, err := svc.List()
+	is.NoErr(err)
+
+	is.True(sort.SliceIsSorted(first, func(i, j int) bool {
+		return first[i].Prefix < first[j].Prefix
+	}))
+
+	for i := 0; i < 5; i++ {
+		namespaces, err := svc.List()
+		is.NoErr(err)
+		is.Equal(namespaces, first) // repeated calls return the same order
+	}
+}
+
+func TestService_PrefixesForBase(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	// the alternative prefixes sort before the default prefix
+	_, err = svc.Add("a-dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	_, err = svc.Add("dce", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	_, err = svc.Add("skos", "http://www.w3.org/2004/02/skos/core#")
+	is.NoErr(err)
+
+	tests := []struct {
+		name    string
+		base    string
+		want    []string
+		wantErr error
+	}{
+		{"default first", "http://purl.org/dc/elements/1.1/", []string{"dc", "a-dc", "dce"}, nil},
+		{"single prefix", "http://www.w3.org/2004/02/skos/core#", []string{"skos"}, nil},
+		{"unknown base", "http://example.org/unknown/", nil, domain.ErrNameSpaceNotFound},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.PrefixesForBase(tt.base)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Service.PrefixesForBase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Service.PrefixesForBase() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestService_URI(t *testing.T) {
+	svc, err := NewService()
+	if err != nil {
+		t.Fatalf("Unable to start namespace Service; %#v", err)
+	}
+
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	if err != nil {
+		t.Fatalf("Unable to add namespace; %#v", err)
+	}
+
+	tests := []struct {
+		name    string
+		curie   string
+		want    string
+		wantErr error
+	}{
+		{
+			"search label",
+			"dc_title",
+			"http://purl.org/dc/elements/1.1/title",
+			nil,
+		},
+		{
+			"curie",
+			"dc:title",
+			"http://purl.org/dc/elements/1.1/title",
+			nil,
+		},
+		{
+			"label with underscores",
+			"dc_date_created",
+			"http://purl.org/dc/elements/1.1/date_created",
+			nil,
+		},
+		{
+			"unknown prefix",
+			"unknown_title",
+			"",
+			domain.ErrNameSpaceNotFound,
+		},
+		{
+			"no separator",
+			"title",
+			"",
+			domain.ErrNameSpaceNotValid,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.URI(tt.curie)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.URI() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("Service.URI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// nolint:gocritic
+func TestService_DecodeSearchLabel(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(WithDefaults())
+	is.NoErr(err)
+
+	namespaces, err := svc.List()
+	is.NoErr(err)
+
+	// the bases of these namespaces do not end with a namespace delimiter and
+	// the base-URI that their URIs are split into is not a known namespace.
+	wantUnsplittable := []string{"abm", "condition", "lgt", "wimpo"}
+
+	// custom is the prefix of two different default base-URIs. The second one
+	// is stored as a temporary namespace with custom as alternative prefix.
+	ambiguous := map[string]bool{"custom": true}
+
+	var unsplittable []string
+
+	for _, ns := range namespaces {
+		if ambiguous[ns.Prefix] {
+			continue
+		}
+
+		uri := ns.Base + "title"
+
+		got, err := svc.DecodeSearchLabel(ns.Prefix + "_title")
+		is.NoErr(err)
+		is.Equal(got, uri)
+
+		label, err := svc.SearchLabel(uri)
+		if err != nil {
+			is.True(errors.Is(err, domain.ErrNameSpaceNotFound))
+			unsplittable = append(unsplittable, ns.Prefix)
+
+			continue
+		}
+
+		got, err = svc.DecodeSearchLabel(label)
+		is.NoErr(err)
+		is.Equal(got, uri)
+	}
+
+	sort.Strings(unsplittable)
+	is.Equal(unsplittable, wantUnsplittable)
+
+	_, err = svc.DecodeSearchLabel("dc:title")
+	is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+
+	_, err = svc.DecodeSearchLabel("unknownprefix_title")
+	is.True(errors.Is(err, domain.ErrNameSpaceNotFound))
+
+	_, err = svc.DecodeSearchLabel("title")
+	is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+}
+
+func TestNewService_WithNameSpaces(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(
+		WithDefaults(),
+		WithNameSpaces(map[string]string{
+			"musip": "http://www.musip.nl/terms/",
+			"hubdc": "http://purl.org/dc/elements/1.1/",
+			"bad":   "not a base-URI",
+		}),
+	)
+	is.NoErr(err)
+
+	uri, err := svc.DecodeSearchLabel("musip_title")
+	is.NoErr(err)
+	is.Equal(uri, "http://www.musip.nl/terms/title")
+
+	// a configured prefix for a default base-URI is an alternative
+	ns, err := svc.GetWithPrefix("hubdc")
+	is.NoErr(err)
+	is.Equal(ns.Base, "http://purl.org/dc/elements/1.1/")
+	is.True(ns.Prefix != "hubdc")
+
+	_, err = svc.GetWithPrefix("bad")
+	is.True(errors.Is(err, domain.ErrNameSpaceNotFound))
+}
+
+func TestService_AddStrict(t *testing.T) {
+	svc, err := NewService()
+	if err != nil {
+		t.Fatalf("Unable to start namespace Service; %#v", err)
+	}
+
+	_, err = svc.Add("", "http://www.w3.org/2004/02/skos/core#")
+	if err != nil {
+		t.Fatalf("Unable to add temporary namespace; %#v", err)
+	}
+
+	type args struct {
+		prefix string
+		base   string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		stored  int
+		wantErr error
+	}{
+		{
+			"new namespace pair",
+			args{prefix: "dc", base: "http://purl.org/dc/elements/1.1/"},
+			2,
+			nil,
+		},
+		{
+			"adding the same pair again",
+			args{prefix: "dc", base: "http://purl.org/dc/elements/1.1/"},
+			2,
+			nil,
+		},
+		{
+			"setting default over temporary",
+			args{prefix: "skos", base: "http://www.w3.org/2004/02/skos/core#"},
+			2,
+			nil,
+		},
+		{
+			"prefix conflict",
+			args{prefix: "dc", base: "http://purl.org/dc/terms/"},
+			2,
+			domain.ErrNameSpaceDuplicateEntry,
+		},
+		{
+			"base conflict",
+			args{prefix: "dce", base: "http://purl.org/dc/elements/1.1/"},
+			2,
+			domain.ErrNameSpaceDuplicateEntry,
+		},
+		{
+			"empty base",
+			args{prefix: "dc", base: ""},
+			2,
+			domain.ErrNameSpaceNotValid,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			ns, err := svc.AddStrict(tt.args.prefix, tt.args.base)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Service.AddStrict() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err == nil && (ns.Prefix != tt.args.prefix || ns.Temporary) {
+				t.Errorf("Service.AddStrict() = %v, want default prefix %s", ns, tt.args.prefix)
+			}
+
+			if svc.Len() != tt.stored {
+				t.Errorf("Service.AddStrict() stored %d, want %d", svc.Len(), tt.stored)
+			}
+		})
+	}
+}
+
+func TestService_AddStrict_alternativePrefix(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	err = svc.Set(&domain.NameSpace{
+		Prefix:    "dc",
+		Base:      "http://purl.org/dc/elements/1.1/",
+		PrefixAlt: []string{"dce"},
+	})
+	is.NoErr(err)
+
+	ns, err := svc.AddStrict("dce", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+	is.Equal(ns.Prefix, "dc")
+	is.Equal(svc.Len(), 1)
+
+	_, err = svc.AddStrict("dce", "http://purl.org/dc/terms/")
+	is.True(errors.Is(err, domain.ErrNameSpaceDuplicateEntry))
+	is.Equal(svc.Len(), 1)
+}
+
+func TestService_Add_invalidAlternativePrefix(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	stored, err := svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	_, err = svc.Add("1dc", "http://purl.org/dc/elements/1.1/")
+	is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+
+	// the stored NameSpace is not changed by the rejected prefix
+	is.Equal(len(stored.PrefixAlt), 0)
+
+	ns, err := svc.GetWithBase("http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+	is.Equal(ns.Prefixes(), []string{"dc"})
+}
+
+func TestService_AddStrict_concurrent(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	// the default store is created on first use
+	is.Equal(svc.Len(), 0)
+
+	const workers = 20
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		added     int
+		conflicts int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := svc.AddStrict("dc", fmt.Sprintf("http://example.org/%d/", i))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err == nil:
+				added++
+			case errors.Is(err, domain.ErrNameSpaceDuplicateEntry):
+				conflicts++
+			default:
+				t.Errorf("Service.AddStrict() unexpected error = %v", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	is.Equal(added, 1)
+	is.Equal(conflicts, workers-1)
+	is.Equal(svc.Len(), 1)
+}
+
+func TestService_AddStrict_checkedByStore(t *testing.T) {
+	is := is.New(t)
+
+	// dc is added to the store after AddStrict checked for conflicts
+	store := &interleavedStore{
+		NameSpaceStore: memory.NewNameSpaceStore(),
+		concurrent:     &domain.NameSpace{Prefix: "dc", Base: "http://purl.org/dc/terms/"},
+	}
+
+	svc, err := NewService(SetStore(store))
+	is.NoErr(err)
+
+	_, err = svc.AddStrict("dc", "http://purl.org/dc/elements/1.1/")
+	is.True(errors.Is(err, domain.ErrNameSpaceDuplicateEntry))
+	is.Equal(svc.Len(), 1)
+
+	ns, err := svc.GetWithPrefix("dc")
+	is.NoErr(err)
+	is.Equal(ns.Base, "http://purl.org/dc/terms/")
+}
+
+func TestService_validateBase(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		strict  bool
+		wantErr bool
+	}{
+		{"valid slash", "http://purl.org/dc/elements/1.1/", false, false},
+		{"valid hash", "http://www.w3.org/2004/02/skos/core#", true, false},
+		{"empty base", "", false, true},
+		{"missing host", "http:/example", false, true},
+		{"not an URI", "dc", false, true},
+		{"relative URI", "/dc/elements/", false, true},
+		{"no delimiter", "http://purl.org/abm/sen", false, false},
+		{"no delimiter strict", "http://purl.org/abm/sen", true, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			options := []ServiceOptionFunc{}
+			if tt.strict {
+				options = append(options, WithStrictBaseValidation())
+			}
+
+			svc, err := NewService(options...)
+			if err != nil {
+				t.Fatalf("Unable to start namespace Service; %#v", err)
+			}
+
+			_, err = svc.Add("ns", tt.base)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Service.Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, domain.ErrNameSpaceNotValid) {
+				t.Errorf("Service.Add() error = %v, want %v", err, domain.ErrNameSpaceNotValid)
+			}
+
+			err = svc.Set(&domain.NameSpace{Prefix: "ns", Base: tt.base})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Service.Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_typedErrors(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(WithStrictBaseValidation())
+	is.NoErr(err)
+
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1")
+
+	var verr *domain.ValidationError
+	is.True(errors.As(err, &verr))
+	is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+	is.Equal(verr.Prefix, "dc")
+	is.Equal(verr.Base, "http://purl.org/dc/elements/1.1")
+	is.Equal(err.Error(), "base http://purl.org/dc/elements/1.1 must end with '#' or '/'; prefix or base not valid")
+
+	err = svc.Set(&domain.NameSpace{Prefix: "dc", Base: "dc"})
+	is.True(errors.As(err, &verr))
+	is.Equal(verr.Prefix, "dc")
+
+	is.True(errors.As(svc.Set(nil), &verr))
+
+	err = svc.Set(&domain.NameSpace{Prefix: "dc_terms", Base: "http://purl.org/dc/terms/"})
+	is.True(errors.As(err, &verr))
+	is.Equal(verr.Prefix, "dc_terms")
+	is.Equal(svc.Len(), 0)
+
+	dc, err := svc.AddStrict("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	_, err = svc.AddStrict("dc", "http://purl.org/dc/terms/")
+
+	var derr *domain.DuplicateEntryError
+	is.True(errors.As(err, &derr))
+	is.True(errors.Is(err, domain.ErrNameSpaceDuplicateEntry))
+	is.Equal(derr.Existing, dc)
+	is.Equal(err.Error(), "prefix dc is linked to http://purl.org/dc/elements/1.1/; prefix and base stored in different entries")
+
+	_, err = svc.AddStrict("dce", "http://purl.org/dc/elements/1.1/")
+	is.True(errors.As(err, &derr))
+	is.Equal(err.Error(), "base http://purl.org/dc/elements/1.1/ is linked to dc; prefix and base stored in different entries")
+}
+
+func TestNewService_strictWithDefaults(t *testing.T) {
+	svc, err := NewService(WithDefaults(), WithStrictBaseValidation())
+	if err != nil {
+		t.Fatalf("NewService() unexpected error = %v", err)
+	}
+
+	if svc.Len() != 2015 {
+		t.Errorf("NewService() = %v, want %v", svc.Len(), 2015)
+	}
+}
+
+func TestService_SearchLabelByLongestBase(t *testing.T) {
+	svc, err := NewService()
+	if err != nil {
+		t.Fatalf("Unable to start namespace Service; %#v", err)
+	}
+
+	for prefix, base := range map[string]string{
+		"abm":  "http://purl.org/abm/sen",
+		"ex":   "http://example.com/",
+		"exv":  "http://example.com/vocab/",
+		"exvt": "http://example.com/vocab/terms/",
+	} {
+		if _, err := svc.Add(prefix, base); err != nil {
+			t.Fatalf("Unable to add namespace; %#v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		{"exact match", "http://example.com/vocab/title", "exv_title", false},
+		{"exact match preferred over longer base", "http://example.com/vocab/terms/title", "exvt_title", false},
+		{"no clean delimiter", "http://purl.org/abm/sentitle", "abm_title", false},
+		{"longest registered base", "http://example.com/vocab/terms/sub/title", "exvt_sub/title", false},
+		{"unknown base", "http://unknown.org/title", "", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.SearchLabelByLongestBase(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Service.SearchLabelByLongestBase() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("Service.SearchLabelByLongestBase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_ListPage(t *testing.T) {
+	svc, err := NewService()
+	if err != nil {
+		t.Fatalf("NewService() unexpected error = %v", err)
+	}
+
+	for prefix, base := range map[string]string{
+		"dc":   "http://purl.org/dc/elements/1.1/",
+		"skos": "http://www.w3.org/2004/02/skos/core#",
+		"edm":  "http://www.europeana.eu/schemas/edm/",
+		"":     "http://example.org/temporary/",
+	} {
+		if _, err := svc.Add(prefix, base); err != nil {
+			t.Fatalf("Service.Add() unexpected error = %v", err)
+		}
+	}
+
+	temporary, permanent := true, false
+
+	tests := []struct {
+		name      string
+		opts      ListOptions
+		want      []string
+		wantTotal int
+		wantErr   bool
+	}{
+		{"all", ListOptions{Temporary: &permanent}, []string{"dc", "edm", "skos"}, 3, false},
+		{"first page", ListOptions{Limit: 2, Temporary: &permanent}, []string{"dc", "edm"}, 3, false},
+		{"second page", ListOptions{Offset: 2, Limit: 2, Temporary: &permanent}, []string{"skos"}, 3, false},
+		{"offset beyond total", ListOptions{Offset: 10}, []string{}, 4, false},
+		{"temporary only", ListOptions{Temporary: &temporary}, nil, 1, false},
+		{"negative limit", ListOptions{Limit: -1}, nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := svc.ListPage(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Service.ListPage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if total != tt.wantTotal {
+				t.Errorf("Service.ListPage() total = %d, want %d", total, tt.wantTotal)
+			}
+
+			if tt.want == nil {
+				return
+			}
+
+			prefixes := []string{}
+			for _, ns := range got {
+				prefixes = append(prefixes, ns.Prefix)
+			}
+
+			if diff := cmp.Diff(tt.want, prefixes); diff != "" {
+				t.Errorf("Service.ListPage() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestService_ListTemporary(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+	_, err = svc.Add("", "http://example.org/one/")
+	is.NoErr(err)
+	_, err = svc.Add("", "http://example.org/two/")
+	is.NoErr(err)
+
+	permanent, err := svc.ListPermanent()
+	is.NoErr(err)
+	is.Equal(len(permanent), 1)
+	is.Equal(permanent[0].Prefix, "dc")
+
+	temporary, err := svc.ListTemporary()
+	is.NoErr(err)
+	is.Equal(len(temporary), 2)
+
+	for _, ns := range temporary {
+		is.True(ns.Temporary)
+	}
+
+	nrPermanent, nrTemporary, err := svc.Counts()
+	is.NoErr(err)
+	is.Equal(nrPermanent, 1)
+	is.Equal(nrTemporary, 2)
+}
+
+func TestService_PruneTemporary(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	dc, err := svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+	unused, err := svc.Add("", "http://example.org/unused/")
+	is.NoErr(err)
+	used, err := svc.Add("", "http://example.org/used/")
+	is.NoErr(err)
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, ns := range []*domain.NameSpace{dc, unused, used} {
+		svc.setLastUsed(ns, old)
+	}
+
+	// a lookup marks the namespace as used without modifying it
+	_, err = svc.SearchLabel("http://example.org/used/title")
+	is.NoErr(err)
+	is.True(svc.lastUsedAt(used).After(old))
+	is.True(used.LastUsed == nil)
+
+	_, err = svc.PruneTemporary(0)
+	is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+
+	removed, err := svc.PruneTemporary(24 * time.Hour)
+	is.NoErr(err)
+	is.Equal(removed, 1)
+
+	_, err = svc.GetWithBase(unused.Base)
+	is.True(errors.Is(err, domain.ErrNameSpaceNotFound))
+
+	// permanent namespaces are never pruned
+	_, err = svc.GetWithPrefix("dc")
+	is.NoErr(err)
+	is.Equal(svc.Len(), 2)
+}
+
+func TestService_PruneTemporary_loaded(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	old := time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+
+	_, err = svc.Load(strings.NewReader(`[
+		{"uuid": "1", "prefix": "ns1", "base": "http://example.org/old/", "temporary": true, "lastUsed": "` + old + `"},
+		{"uuid": "2", "prefix": "ns2", "base": "http://example.org/recent/", "temporary": true}
+	]`))
+	is.NoErr(err)
+
+	// a namespace without a LastUsed time is used when it is loaded
+	_, err = svc.GetWithPrefix("ns2")
+	is.NoErr(err)
+
+	removed, err := svc.PruneTemporary(24 * time.Hour)
+	is.NoErr(err)
+	is.Equal(removed, 1)
+
+	_, err = svc.GetWithPrefix("ns1")
+	is.True(errors.Is(err, domain.ErrNameSpaceNotFound))
+}
+
+// TestService_lookupConcurrency must be run with -race to prove that the
+// lookups don't modify the shared namespaces.
+func TestService_lookupConcurrency(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, _ = svc.GetWithPrefix("dc")
+			_, _ = svc.SearchLabel("http://purl.org/dc/elements/1.1/title")
+			_, _ = svc.List()
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestService_Merge(t *testing.T) {
+	t.Run("temporary into permanent", func(t *testing.T) {
+		is := is.New(t)
+
+		svc, err := NewService()
+		is.NoErr(err)
+
+		keep, err := svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+		is.NoErr(err)
+
+		// same prefix with a different base creates a temporary duplicate
+		drop, err := svc.Add("dc", "http://purl.org/dc/elements/1.1#")
+		is.NoErr(err)
+		is.True(drop.Temporary)
+		is.Equal(svc.Len(), 2)
+
+		is.NoErr(svc.Merge(keep, drop))
+		is.Equal(svc.Len(), 1)
+
+		ns, err := svc.GetWithBase("http://purl.org/dc/elements/1.1#")
+		is.NoErr(err)
+		is.Equal(ns.GetID(), keep.GetID())
+		is.Equal(ns.Prefix, "dc")
+		is.Equal(ns.PrefixAlt, nil)
+
+		// the generated prefix of the temporary namespace is discarded
+		_, err = svc.GetWithPrefix(drop.Prefix)
+		is.True(errors.Is(err, domain.ErrNameSpaceNotFound))
+	})
+
+	t.Run("permanent prefix is demoted", func(t *testing.T) {
+		is := is.New(t)
+
+		svc, err := NewService()
+		is.NoErr(err)
+
+		keep, err := svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+		is.NoErr(err)
+		drop, err := svc.Add("dce", "http://purl.org/dc/elements/")
+		is.NoErr(err)
+
+		is.NoErr(svc.Merge(keep, drop))
+
+		ns, err := svc.GetWithPrefix("dce")
+		is.NoErr(err)
+		is.Equal(ns.Prefix, "dc")
+		is.Equal(ns.PrefixAlt, []string{"dce"})
+		is.Equal(ns.BaseAlt, []string{"http://purl.org/dc/elements/"})
+
+		label, err := svc.SearchLabel("http://purl.org/dc/elements/title")
+		is.NoErr(err)
+		is.Equal(label, "dc_title")
+	})
+
+	t.Run("temporary keep takes the permanent prefix", func(t *testing.T) {
+		is := is.New(t)
+
+		svc, err := NewService()
+		is.NoErr(err)
+
+		keep, err := svc.Add("", "http://example.org/ns/")
+		is.NoErr(err)
+		drop, err := svc.Add("ex", "http://example.org/ns#")
+		is.NoErr(err)
+
+		is.NoErr(svc.Merge(keep, drop))
+
+		ns, err := svc.GetWithPrefix("ex")
+		is.NoErr(err)
+		is.Equal(ns.GetID(), keep.GetID())
+		is.Equal(ns.Prefix, "ex")
+		is.True(!ns.Temporary)
+		is.Equal(ns.Base, "http://example.org/ns/")
+
+		// the namespace that is passed in is not modified
+		is.True(keep.Temporary)
+	})
+
+	t.Run("failed store keeps both namespaces", func(t *testing.T) {
+		is := is.New(t)
+
+		store := &failingSetStore{NameSpaceStore: memory.NewNameSpaceStore()}
+
+		svc, err := NewService(SetStore(store))
+		is.NoErr(err)
+
+		keep, err := svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+		is.NoErr(err)
+		drop, err := svc.Add("dce", "http://purl.org/dc/elements/")
+		is.NoErr(err)
+
+		store.failBase = "http://purl.org/dc/elements/1.1/"
+
+		is.True(svc.Merge(keep, drop) != nil)
+		is.Equal(svc.Len(), 2)
+
+		ns, err := svc.GetWithPrefix("dce")
+		is.NoErr(err)
+		is.Equal(ns.GetID(), drop.GetID())
+
+		ns, err = svc.GetWithPrefix("dc")
+		is.NoErr(err)
+		is.Equal(len(ns.PrefixAlt), 0)
+		is.Equal(len(ns.BaseAlt), 0)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		is := is.New(t)
+
+		svc, err := NewService()
+		is.NoErr(err)
+
+		ns, err := svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+		is.NoErr(err)
+
+		is.True(errors.Is(svc.Merge(ns, ns), domain.ErrNameSpaceNotValid))
+		is.True(errors.Is(svc.Merge(ns, nil), domain.ErrNameSpaceNotValid))
+	})
+}
+
+// failingSetStore is a Store that fails to store the NameSpace with failBase.
+type failingSetStore struct {
+	*memory.NameSpaceStore
+	failBase string
+}
+
+func (fs *failingSetStore) Set(ns *domain.NameSpace) error {
+	if fs.failBase != "" && ns.Base == fs.failBase {
+		return errors.New("store unavailable")
+	}
+
+	return fs.NameSpaceStore.Set(ns)
+}
+
+// interleavedStore is a UniqueStore that sets the concurrent NameSpace just
+// before the first call to SetUnique, like a concurrent writer would.
+type interleavedStore struct {
+	*memory.NameSpaceStore
+	concurrent *domain.NameSpace
+}
+
+func (st *interleavedStore) SetUnique(ns *domain.NameSpace) error {
+	if st.concurrent != nil {
+		if err := st.NameSpaceStore.Set(st.concurrent); err != nil {
+			return err
+		}
+
+		st.concurrent = nil
+	}
+
+	return st.NameSpaceStore.SetUnique(ns)
+}
+
+// unreachableStore is a Store that cannot be listed.
+type unreachableStore struct {
+	Store
+}
+
+func (unreachableStore) List() ([]*domain.NameSpace, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestService_Stats(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	keep, err := svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+	drop, err := svc.Add("dce", "http://purl.org/dc/elements/")
+	is.NoErr(err)
+	is.NoErr(svc.Merge(keep, drop))
+
+	_, err = svc.Add("", "http://example.org/one/")
+	is.NoErr(err)
+
+	stats, err := svc.Stats()
+	is.NoErr(err)
+
+	want := NameSpaceStats{
+		StoreReachable: true,
+		NameSpaces:     2,
+		Temporary:      1,
+		AltPrefixes:    1,
+		AltBases:       1,
+	}
+
+	if diff := cmp.Diff(want, stats); diff != "" {
+		t.Errorf("Service.Stats() mismatch (-want +got):\n%s", diff)
+	}
+
+	svc, err = NewService(SetStore(unreachableStore{}))
+	is.NoErr(err)
+
+	stats, err = svc.Stats()
+	is.True(err != nil)
+	is.True(!stats.StoreReachable)
+}
+
+// closerStore is a Store that records if it is closed.
+type closerStore struct {
+	Store
+	closed bool
+	err    error
+}
+
+func (cs *closerStore) Close() error {
+	cs.closed = true
+	return cs.err
+}
+
+func TestService_Shutdown(t *testing.T) {
+	t.Run("memory store", func(t *testing.T) {
+		is := is.New(t)
+
+		svc, err := NewService()
+		is.NoErr(err)
+
+		is.NoErr(svc.Shutdown(context.Background()))
+	})
+
+	t.Run("closes the store", func(t *testing.T) {
+		is := is.New(t)
+
+		store := &closerStore{}
+
+		svc, err := NewService(SetStore(store))
+		is.NoErr(err)
+
+		is.NoErr(svc.Shutdown(context.Background()))
+		is.True(store.closed)
+	})
+
+	t.Run("close error", func(t *testing.T) {
+		is := is.New(t)
+
+		errClose := errors.New("flush failed")
+		store := &closerStore{err: errClose}
+
+		svc, err := NewService(SetStore(store))
+		is.NoErr(err)
+
+		err = svc.Shutdown(context.Background())
+		is.True(errors.Is(err, errClose))
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		is := is.New(t)
+
+		store := &closerStore{}
+
+		svc, err := NewService(SetStore(store))
+		is.NoErr(err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = svc.Shutdown(ctx)
+		is.True(errors.Is(err, context.Canceled))
+		is.True(!store.closed)
+	})
+}
+
+func TestService_SetMeta(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+
+	meta := map[string]string{
+		"label": "Dublin Core Elements",
+		"docs":  "https://www.dublincore.org/specifications/dublin-core/dcmi-terms/",
+	}
+
+	is.NoErr(svc.SetMeta("dc", meta))
+
+	// the stored annotations are a copy
+	meta["label"] = "changed"
+
+	namespaces, err := svc.List()
+	is.NoErr(err)
+	is.Equal(len(namespaces), 1)
+	is.Equal(namespaces[0].Meta["label"], "Dublin Core Elements")
+
+	// the annotations are persisted by Dump and Load
+	var buf bytes.Buffer
+	is.NoErr(svc.Dump(&buf))
+
+	restored, err := NewService()
+	is.NoErr(err)
+
+	_, err = restored.Load(&buf)
+	is.NoErr(err)
+
+	ns, err := restored.GetWithPrefix("dc")
+	is.NoErr(err)
+	is.Equal(ns.Meta, namespaces[0].Meta)
+
+	err = svc.SetMeta("unknown", meta)
+	is.True(errors.Is(err, domain.ErrNameSpaceNotFound))
+
+	// the previously returned NameSpace is not changed
+	before, err := svc.GetWithPrefix("dc")
+	is.NoErr(err)
+	is.NoErr(svc.SetMeta("dc", map[string]string{"label": "DC"}))
+	is.Equal(before.Meta["label"], "Dublin Core Elements")
+
+	err = svc.SetMeta("dc", map[string]string{" ": "empty"})
+	is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+
+	is.NoErr(svc.SetMeta("dc", nil))
+
+	ns, err = svc.GetWithPrefix("dc")
+	is.NoErr(err)
+	is.Equal(len(ns.Meta), 0)
+}
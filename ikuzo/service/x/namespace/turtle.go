@@ -0,0 +1,92 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/delving/hub3/ikuzo/domain"
+)
+
+// ExportTurtlePrefixes writes a Turtle @prefix declaration for every
+// non-temporary NameSpace, sorted alphabetically by prefix, e.g.
+//
+//   @prefix dc: <http://purl.org/dc/elements/1.1/> .
+func (s *Service) ExportTurtlePrefixes(w io.Writer) error {
+	return s.writePrefixes(w, "@prefix %s: <%s> .\n")
+}
+
+// SPARQLPrefixes returns the SPARQL PREFIX declarations for every
+// non-temporary NameSpace, sorted alphabetically by prefix, e.g.
+//
+//   PREFIX dc: <http://purl.org/dc/elements/1.1/>
+func (s *Service) SPARQLPrefixes() (string, error) {
+	var sb strings.Builder
+
+	if err := s.writePrefixes(&sb, "PREFIX %s: <%s>\n"); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+func (s *Service) writePrefixes(w io.Writer, format string) error {
+	s.checkStore()
+
+	namespaces, err := s.store.List()
+	if err != nil {
+		return err
+	}
+
+	permanent := []*domain.NameSpace{}
+
+	for _, ns := range namespaces {
+		if !ns.Temporary {
+			permanent = append(permanent, ns)
+		}
+	}
+
+	sort.Slice(permanent, func(i, j int) bool {
+		return permanent[i].Prefix < permanent[j].Prefix
+	})
+
+	for _, ns := range permanent {
+		if _, err := fmt.Fprintf(w, format, ns.Prefix, escapeIRI(ns.Base)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// escapeIRI escapes the characters that are not allowed in a Turtle or SPARQL
+// IRIREF with a numeric UCHAR escape sequence.
+func escapeIRI(iri string) string {
+	var sb strings.Builder
+
+	for _, r := range iri {
+		if r <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", r) {
+			fmt.Fprintf(&sb, "\\u%04X", r)
+			continue
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+// nolint:gocritic
+func TestService_ExportTurtlePrefixes(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	_, err = svc.Add("skos", "http://www.w3.org/2004/02/skos/core#")
+	is.NoErr(err)
+	_, err = svc.Add("dc", "http://purl.org/dc/elements/1.1/")
+	is.NoErr(err)
+	_, err = svc.Add("ex", "http://example.com/{special} ns/")
+	is.NoErr(err)
+	_, err = svc.Add("", "http://example.com/temporary/")
+	is.NoErr(err)
+
+	var sb strings.Builder
+
+	err = svc.ExportTurtlePrefixes(&sb)
+	is.NoErr(err)
+
+	is.Equal(sb.String(), `@prefix dc: <http://purl.org/dc/elements/1.1/> .
+@prefix ex: <http://example.com/\u007Bspecial\u007D\u0020ns/> .
+@prefix skos: <http://www.w3.org/2004/02/skos/core#> .
+`)
+
+	sparql, err := svc.SPARQLPrefixes()
+	is.NoErr(err)
+
+	is.Equal(sparql, `PREFIX dc: <http://purl.org/dc/elements/1.1/>
+PREFIX ex: <http://example.com/\u007Bspecial\u007D\u0020ns/>
+PREFIX skos: <http://www.w3.org/2004/02/skos/core#>
+`)
+}
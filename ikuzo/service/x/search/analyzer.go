@@ -14,34 +14,379 @@
 
 package search
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	snowballRuntime "github.com/blevesearch/snowballstem"
+	"github.com/blevesearch/snowballstem/danish"
+	"github.com/blevesearch/snowballstem/dutch"
+	"github.com/blevesearch/snowballstem/french"
+	"github.com/blevesearch/snowballstem/german"
+	"github.com/blevesearch/snowballstem/norwegian"
+	"github.com/blevesearch/snowballstem/porter"
+	"github.com/blevesearch/snowballstem/swedish"
+	"golang.org/x/text/unicode/norm"
+)
 
 const (
 	trimCharacters = "\".,;:[]()?'`"
 )
 
+// stemmers are the Snowball stemmers that match the 'stemmer' token filter
+// of ElasticSearch for the same language. Note that ElasticSearch uses the
+// original Porter stemmer for English. For French and German these match the
+// 'french' and 'german2' stemmers of ElasticSearch.
+var stemmers = map[string]func(env *snowballRuntime.Env) bool{
+	"danish":    danish.Stem,
+	"dutch":     dutch.Stem,
+	"english":   porter.Stem,
+	"french":    french.Stem,
+	"german":    german.Stem,
+	"norwegian": norwegian.Stem,
+	"swedish":   swedish.Stem,
+}
+
 // Analyzer is the default analyzer for Search actions.
 // It folds unicode to ASCII characters and lowercases them all.
 //
 // The goal is to have this analyzer behave similarly to the ElasticSearch
 // Analyzer that Ikuzo comes preconfigured with.
-type Analyzer struct{}
+//
+// The zero value is ready to use. Use NewAnalyzer to configure optional filters.
+type Analyzer struct {
+	stopwords map[string]bool
+	stemmer   func(env *snowballRuntime.Env) bool
+	normalize func(text string) string
+	minLength int
+	keepNums  bool
+
+	// the names of the stemmer language and normalization form, see Config
+	stemmerLang string
+	normForm    string
+}
+
+// AnalyzerOption configures the Analyzer.
+type AnalyzerOption func(a *Analyzer) error
+
+// NewAnalyzer returns an Analyzer configured with the options.
+// An error is returned when one of the options is invalid.
+func NewAnalyzer(options ...AnalyzerOption) (*Analyzer, error) {
+	a := &Analyzer{}
+
+	for _, option := range options {
+		if err := option(a); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// WithStopwords removes the stopwords from the analyzed text.
+// The stopwords are folded and lowercased, so they match regardless of case
+// or diacritics.
+func WithStopwords(words []string) AnalyzerOption {
+	return func(a *Analyzer) error {
+		if a.stopwords == nil {
+			a.stopwords = make(map[string]bool, len(words))
+		}
+
+		for _, word := range words {
+			if word = a.fold(word); word != "" {
+				a.stopwords[word] = true
+			}
+		}
+
+		return nil
+	}
+}
+
+// WithUnicodeNormalization normalizes the text to the Unicode form before it is folded.
+// By default the text is not normalized.
+//
+// Use norm.NFC or norm.NFKC to compose decomposed characters, so that for example
+// 'e' followed by a combining acute accent is folded to 'e'. With norm.NFD and
+// norm.NFKD the combining marks are kept in the folded text.
+func WithUnicodeNormalization(form norm.Form) AnalyzerOption {
+	return func(a *Analyzer) error {
+		name, ok := normForms[form]
+		if !ok {
+			return fmt.Errorf("unsupported unicode normalization form: %d", form)
+		}
+
+		a.normalize = form.String
+		a.normForm = name
+
+		return nil
+	}
+}
+
+// WithMinTokenLength drops the words that are shorter than n characters after folding.
+// By default no words are dropped. Use WithKeepNumbers to keep short numbers.
+func WithMinTokenLength(n int) AnalyzerOption {
+	return func(a *Analyzer) error {
+		if n < 0 {
+			return fmt.Errorf("minimum token length must not be negative: %d", n)
+		}
+
+		a.minLength = n
+
+		return nil
+	}
+}
+
+// WithKeepNumbers exempts words that only contain digits from WithMinTokenLength.
+func WithKeepNumbers() AnalyzerOption {
+	return func(a *Analyzer) error {
+		a.keepNums = true
 
+		return nil
+	}
+}
+
+// WithStemmer stems each word after it is folded and lowercased.
+// The stopwords are removed before stemming.
+//
+// The supported languages are 'danish', 'dutch', 'english', 'french', 'german',
+// 'norwegian' and 'swedish'. These use the same stemmer as ElasticSearch does
+// for the language.
+func WithStemmer(lang string) AnalyzerOption {
+	return func(a *Analyzer) error {
+		stemmer, ok := stemmers[strings.ToLower(lang)]
+		if !ok {
+			return fmt.Errorf("unsupported stemmer language: %q", lang)
+		}
+
+		a.stemmer = stemmer
+		a.stemmerLang = strings.ToLower(lang)
+
+		return nil
+	}
+}
+
+// Transform folds and lowercases the text.
+//
+// Without stopwords the text is treated as a single token, so only the
+// punctuation at the start and end of the text is trimmed.
+//
+// When stopwords or a stemmer are configured the folded text is split on whitespace.
+// Each word is trimmed, the stopwords are removed and the remaining words are
+// stemmed and joined with a single space. When all words are stopwords an empty
+// string is returned.
 func (a *Analyzer) Transform(text string) string {
-	return strings.Trim(
-		strings.ToLower(
-			LuceneASCIIFolding(text),
-		),
-		trimCharacters,
-	)
+	if !a.hasFilters() {
+		return a.fold(text)
+	}
+
+	words := []string{}
+
+	for _, word := range strings.Fields(text) {
+		if word = a.analyze(word); word != "" {
+			words = append(words, word)
+		}
+	}
+
+	return strings.Join(words, " ")
 }
 
+// TransformPhrase applies Transform to each whitespace separated word of the text.
+// Stopwords are removed from the phrase.
 func (a *Analyzer) TransformPhrase(text string) string {
 	cleanWords := []string{}
 
 	for _, word := range strings.Fields(text) {
-		cleanWords = append(cleanWords, a.Transform(word))
+		word = a.Transform(word)
+		if word == "" && a.hasFilters() {
+			continue
+		}
+
+		cleanWords = append(cleanWords, word)
 	}
 
 	return strings.Join(cleanWords, " ")
 }
+
+// AnalyzedToken is a single term produced by Analyzer.Tokenize.
+//
+// Start and End are byte offsets in the original text, so text[Start:End]
+// returns the unfolded word without the trimmed punctuation.
+// Position is the 1-based position of the word in the text.
+type AnalyzedToken struct {
+	Term     string
+	Start    int
+	End      int
+	Position int
+}
+
+// Tokenize splits the text on whitespace and returns a token for each word.
+// The Term of each token is folded and stemmed in the same way as Transform.
+//
+// Words that only contain punctuation are dropped and do not take a position.
+// Stopwords are dropped as well but keep their position, so the positions of
+// the remaining tokens can still be used for phrase matching.
+func (a *Analyzer) Tokenize(text string) []AnalyzedToken {
+	tokens := []AnalyzedToken{}
+	position := 0
+
+	for start := 0; start < len(text); {
+		r, size := utf8.DecodeRuneInString(text[start:])
+		if unicode.IsSpace(r) {
+			start += size
+			continue
+		}
+
+		end := start
+		for end < len(text) {
+			r, size = utf8.DecodeRuneInString(text[end:])
+			if unicode.IsSpace(r) {
+				break
+			}
+
+			end += size
+		}
+
+		wordStart, wordEnd := trimOffsets(text, start, end)
+		start = end
+
+		if wordStart == wordEnd {
+			continue
+		}
+
+		position++
+
+		term := a.analyze(text[wordStart:wordEnd])
+		if term == "" {
+			continue
+		}
+
+		tokens = append(tokens, AnalyzedToken{
+			Term:     term,
+			Start:    wordStart,
+			End:      wordEnd,
+			Position: position,
+		})
+	}
+
+	return tokens
+}
+
+// AnalysisResult contains the output of each stage of the Analyzer for a text.
+// It is returned by Analyzer.Explain.
+type AnalysisResult struct {
+	// Original is the text as it was given
+	Original string `json:"original"`
+	// Normalized is the text after Unicode normalization, see WithUnicodeNormalization
+	Normalized string `json:"normalized"`
+	// Folded is the text after LuceneASCIIFolding and lowercasing
+	Folded string `json:"folded"`
+	// Trimmed is the folded text without the punctuation at the start and end
+	Trimmed string `json:"trimmed"`
+	// Tokens are the terms that are produced by Tokenize
+	Tokens []AnalyzedToken `json:"tokens"`
+}
+
+// Explain returns the output of each stage of the Analyzer for the text,
+// similar to the '_analyze' API of ElasticSearch. It is meant for debugging why
+// two strings do or don't match and is not used by Transform or Tokenize.
+func (a *Analyzer) Explain(text string) AnalysisResult {
+	result := AnalysisResult{
+		Original:   text,
+		Normalized: text,
+	}
+
+	if a.normalize != nil {
+		result.Normalized = a.normalize(text)
+	}
+
+	result.Folded = strings.ToLower(LuceneASCIIFolding(result.Normalized))
+	result.Trimmed = strings.Trim(result.Folded, trimCharacters)
+	result.Tokens = a.Tokenize(text)
+
+	return result
+}
+
+// trimOffsets moves start and end inwards past the runes that are removed by
+// the trimCharacters after folding.
+func trimOffsets(text string, start, end int) (trimmedStart, trimmedEnd int) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !isTrimRune(r) {
+			break
+		}
+
+		start += size
+	}
+
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !isTrimRune(r) {
+			break
+		}
+
+		end -= size
+	}
+
+	return start, end
+}
+
+func isTrimRune(r rune) bool {
+	folded := LuceneASCIIFolding(string(r))
+
+	return folded != "" && strings.Trim(folded, trimCharacters) == ""
+}
+
+func (a *Analyzer) hasFilters() bool {
+	return len(a.stopwords) != 0 || a.stemmer != nil || a.minLength > 0
+}
+
+// analyze folds and stems a single word. An empty string is returned when the
+// word is a stopword or too short.
+func (a *Analyzer) analyze(word string) string {
+	word = a.fold(word)
+	if word == "" || a.stopwords[word] || a.isTooShort(word) {
+		return ""
+	}
+
+	if a.stemmer != nil {
+		env := snowballRuntime.NewEnv(word)
+		a.stemmer(env)
+
+		word = env.Current()
+	}
+
+	return word
+}
+
+func (a *Analyzer) isTooShort(word string) bool {
+	if utf8.RuneCountInString(word) >= a.minLength {
+		return false
+	}
+
+	return !a.keepNums || !isNumber(word)
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a *Analyzer) fold(text string) string {
+	if a.normalize != nil {
+		text = a.normalize(text)
+	}
+
+	return strings.Trim(
+		strings.ToLower(
+			LuceneASCIIFolding(text),
+		),
+		trimCharacters,
+	)
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import "fmt"
+
+// AnalyzerChain holds the Analyzer that is used when text is indexed and the
+// Analyzer that is used when a query is parsed.
+//
+// This mirrors the 'analyzer' and 'search_analyzer' of a field in the
+// ElasticSearch mapping. Both analyzers always fold with LuceneASCIIFolding and
+// are built from the same AnalyzerOption functions, so the stages that should
+// behave the same, e.g. stemming and Unicode normalization, must be given to both.
+//
+// The stages that are typically asymmetric are:
+//   - stopwords: removed at index time, but kept at query time so a phrase
+//     like 'to be or not to be' still matches.
+//   - synonyms: expanded at query time only, so the index does not have to be
+//     rebuilt when the synonyms change.
+type AnalyzerChain struct {
+	IndexAnalyzer *Analyzer
+	QueryAnalyzer *Analyzer
+}
+
+// NewAnalyzerChain creates an AnalyzerChain with separate options for the
+// index-time and the query-time Analyzer.
+func NewAnalyzerChain(index, query []AnalyzerOption) (*AnalyzerChain, error) {
+	indexAnalyzer, err := NewAnalyzer(index...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create index analyzer; %w", err)
+	}
+
+	queryAnalyzer, err := NewAnalyzer(query...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create query analyzer; %w", err)
+	}
+
+	return &AnalyzerChain{
+		IndexAnalyzer: indexAnalyzer,
+		QueryAnalyzer: queryAnalyzer,
+	}, nil
+}
+
+// Index analyzes the text with the IndexAnalyzer.
+func (ac *AnalyzerChain) Index(text string) string {
+	return ac.IndexAnalyzer.TransformPhrase(text)
+}
+
+// Query analyzes the text with the QueryAnalyzer.
+func (ac *AnalyzerChain) Query(text string) string {
+	return ac.QueryAnalyzer.TransformPhrase(text)
+}
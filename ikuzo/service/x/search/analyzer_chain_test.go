@@ -0,0 +1,94 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewAnalyzerChain(t *testing.T) {
+	type args struct {
+		index []AnalyzerOption
+		query []AnalyzerOption
+		text  string
+	}
+
+	tests := []struct {
+		name      string
+		args      args
+		wantIndex string
+		wantQuery string
+		wantErr   bool
+	}{
+		{
+			"no options",
+			args{text: "De Boerderij"},
+			"de boerderij",
+			"de boerderij",
+			false,
+		},
+		{
+			"stopwords only at index time",
+			args{
+				index: []AnalyzerOption{WithStopwords([]string{"de"}), WithStemmer("dutch")},
+				query: []AnalyzerOption{WithStemmer("dutch")},
+				text:  "De Boeken",
+			},
+			"boek",
+			"de boek",
+			false,
+		},
+		{
+			"invalid index option",
+			args{index: []AnalyzerOption{WithStemmer("klingon")}},
+			"",
+			"",
+			true,
+		},
+		{
+			"invalid query option",
+			args{query: []AnalyzerOption{WithMinTokenLength(-1)}},
+			"",
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			ac, err := NewAnalyzerChain(tt.args.index, tt.args.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAnalyzerChain() %s = error %v, wantErr %v", tt.name, err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(tt.wantIndex, ac.Index(tt.args.text)); diff != "" {
+				t.Errorf("AnalyzerChain.Index(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+
+			if diff := cmp.Diff(tt.wantQuery, ac.Query(tt.args.text)); diff != "" {
+				t.Errorf("AnalyzerChain.Query(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
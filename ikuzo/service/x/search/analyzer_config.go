@@ -0,0 +1,109 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normForms are the names of the Unicode normalization forms in AnalyzerConfig.
+var normForms = map[norm.Form]string{
+	norm.NFC:  "NFC",
+	norm.NFD:  "NFD",
+	norm.NFKC: "NFKC",
+	norm.NFKD: "NFKD",
+}
+
+// AnalyzerConfig is the serializable configuration of an Analyzer.
+// It is returned by Analyzer.Config and restored with NewAnalyzerFromConfig.
+type AnalyzerConfig struct {
+	// Stopwords are the folded stopwords, see WithStopwords
+	Stopwords []string `json:"stopwords,omitempty"`
+	// Stemmer is the language of the stemmer, see WithStemmer
+	Stemmer string `json:"stemmer,omitempty"`
+	// Normalization is the name of the Unicode normalization form, e.g. 'NFC',
+	// see WithUnicodeNormalization
+	Normalization string `json:"normalization,omitempty"`
+	// MinTokenLength is the minimum length of a word, see WithMinTokenLength
+	MinTokenLength int `json:"minTokenLength,omitempty"`
+	// KeepNumbers exempts numbers from MinTokenLength, see WithKeepNumbers
+	KeepNumbers bool `json:"keepNumbers,omitempty"`
+}
+
+// Config returns the configuration of the Analyzer. The stopwords are sorted.
+func (a *Analyzer) Config() AnalyzerConfig {
+	cfg := AnalyzerConfig{
+		Stemmer:        a.stemmerLang,
+		Normalization:  a.normForm,
+		MinTokenLength: a.minLength,
+		KeepNumbers:    a.keepNums,
+	}
+
+	for word := range a.stopwords {
+		cfg.Stopwords = append(cfg.Stopwords, word)
+	}
+
+	sort.Strings(cfg.Stopwords)
+
+	return cfg
+}
+
+// NewAnalyzerFromConfig returns an Analyzer with the configuration that is
+// returned by Analyzer.Config. An error is returned when the configuration
+// is invalid.
+func NewAnalyzerFromConfig(cfg AnalyzerConfig) (*Analyzer, error) {
+	options := []AnalyzerOption{}
+
+	if cfg.Normalization != "" {
+		form, ok := normFormByName(cfg.Normalization)
+		if !ok {
+			return nil, fmt.Errorf("unsupported unicode normalization form: %q", cfg.Normalization)
+		}
+
+		// the normalization is applied when the stopwords are folded
+		options = append(options, WithUnicodeNormalization(form))
+	}
+
+	if len(cfg.Stopwords) != 0 {
+		options = append(options, WithStopwords(cfg.Stopwords))
+	}
+
+	if cfg.Stemmer != "" {
+		options = append(options, WithStemmer(cfg.Stemmer))
+	}
+
+	if cfg.MinTokenLength != 0 {
+		options = append(options, WithMinTokenLength(cfg.MinTokenLength))
+	}
+
+	if cfg.KeepNumbers {
+		options = append(options, WithKeepNumbers())
+	}
+
+	return NewAnalyzer(options...)
+}
+
+func normFormByName(name string) (norm.Form, bool) {
+	for form, formName := range normForms {
+		if formName == name {
+			return form, true
+		}
+	}
+
+	return 0, false
+}
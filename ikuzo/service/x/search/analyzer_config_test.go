@@ -0,0 +1,91 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestAnalyzer_Config(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []AnalyzerOption
+		want    AnalyzerConfig
+	}{
+		{"zero value", nil, AnalyzerConfig{}},
+		{
+			"all options",
+			[]AnalyzerOption{
+				WithUnicodeNormalization(norm.NFC),
+				WithStopwords([]string{"Het", "de", "één"}),
+				WithStemmer("Dutch"),
+				WithMinTokenLength(3),
+				WithKeepNumbers(),
+			},
+			AnalyzerConfig{
+				Stopwords:      []string{"de", "een", "het"},
+				Stemmer:        "dutch",
+				Normalization:  "NFC",
+				MinTokenLength: 3,
+				KeepNumbers:    true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAnalyzer(tt.options...)
+			if err != nil {
+				t.Fatalf("NewAnalyzer() error = %v", err)
+			}
+
+			got := a.Config()
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Analyzer.Config() mismatch (-want +got):\n%s", diff)
+			}
+
+			restored, err := NewAnalyzerFromConfig(got)
+			if err != nil {
+				t.Fatalf("NewAnalyzerFromConfig() error = %v", err)
+			}
+
+			if diff := cmp.Diff(got, restored.Config()); diff != "" {
+				t.Errorf("NewAnalyzerFromConfig() mismatch (-want +got):\n%s", diff)
+			}
+
+			text := "Het archief van de 12 Gemeenten en één kaart"
+			if a.Transform(text) != restored.Transform(text) {
+				t.Errorf("Transform() = %q, want %q", restored.Transform(text), a.Transform(text))
+			}
+		})
+	}
+}
+
+func TestNewAnalyzerFromConfig_invalid(t *testing.T) {
+	for _, cfg := range []AnalyzerConfig{
+		{Stemmer: "klingon"},
+		{Normalization: "NFX"},
+		{MinTokenLength: -1},
+	} {
+		if _, err := NewAnalyzerFromConfig(cfg); err == nil {
+			t.Errorf("NewAnalyzerFromConfig(%+v) expected error", cfg)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// languageCodes maps the ISO 639-1 and ISO 639-2 (bibliographic and
+// terminology) codes to the language names of the stemmers. EAD uses ISO
+// 639-2 for the 'langcode' attribute, e.g. 'dut' for Dutch.
+var languageCodes = map[string]string{
+	"da": "danish", "dan": "danish",
+	"nl": "dutch", "dut": "dutch", "nld": "dutch",
+	"en": "english", "eng": "english",
+	"fr": "french", "fre": "french", "fra": "french",
+	"de": "german", "ger": "german", "deu": "german",
+	"no": "norwegian", "nor": "norwegian", "nb": "norwegian", "nob": "norwegian",
+	"sv": "swedish", "swe": "swedish",
+}
+
+// AnalyzerRegistry holds an Analyzer per language, so text can be analyzed
+// with the stemmer and stopwords of the language it is written in.
+//
+// The languages are looked up case-insensitive by name, e.g. 'dutch', or by
+// their ISO 639-1 or ISO 639-2 code, e.g. 'nl' or 'dut'. The fallback Analyzer
+// is returned for unknown or empty languages.
+//
+// It is safe for concurrent use.
+type AnalyzerRegistry struct {
+	rw        sync.RWMutex
+	analyzers map[string]*Analyzer
+	fallback  *Analyzer
+}
+
+// NewAnalyzerRegistry returns an empty AnalyzerRegistry. When fallback is nil
+// the zero value Analyzer is used as the fallback.
+func NewAnalyzerRegistry(fallback *Analyzer) *AnalyzerRegistry {
+	if fallback == nil {
+		fallback = &Analyzer{}
+	}
+
+	return &AnalyzerRegistry{
+		analyzers: make(map[string]*Analyzer),
+		fallback:  fallback,
+	}
+}
+
+// NewLanguageAnalyzerRegistry returns an AnalyzerRegistry with a stemming
+// Analyzer for each supported stemmer language, see WithStemmer.
+// The options are applied to each Analyzer before the stemmer, e.g. to
+// configure the Unicode normalization. The fallback Analyzer is created with
+// the options only.
+func NewLanguageAnalyzerRegistry(options ...AnalyzerOption) (*AnalyzerRegistry, error) {
+	fallback, err := NewAnalyzer(options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create fallback analyzer; %w", err)
+	}
+
+	reg := NewAnalyzerRegistry(fallback)
+
+	for lang := range stemmers {
+		a, err := NewAnalyzer(append(options[:len(options):len(options)], WithStemmer(lang))...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create analyzer for %s; %w", lang, err)
+		}
+
+		reg.Register(lang, a)
+	}
+
+	return reg, nil
+}
+
+// Register sets the Analyzer for the language. The language is either a name
+// or an ISO 639 code. It replaces the Analyzer that was registered for the
+// same language before.
+func (reg *AnalyzerRegistry) Register(lang string, a *Analyzer) {
+	reg.rw.Lock()
+	defer reg.rw.Unlock()
+
+	reg.analyzers[normalizeLanguage(lang)] = a
+}
+
+// AnalyzerFor returns the Analyzer for the language. The fallback Analyzer is
+// returned when no Analyzer is registered for the language.
+func (reg *AnalyzerRegistry) AnalyzerFor(lang string) *Analyzer {
+	reg.rw.RLock()
+	defer reg.rw.RUnlock()
+
+	if a, ok := reg.analyzers[normalizeLanguage(lang)]; ok {
+		return a
+	}
+
+	return reg.fallback
+}
+
+// normalizeLanguage returns the language name for an ISO 639 code.
+// Other values are returned lowercased. The region of a language tag is
+// ignored, so 'nl-BE' is returned as 'dutch'.
+func normalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+
+	if name, ok := languageCodes[lang]; ok {
+		return name
+	}
+
+	return lang
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAnalyzerRegistry_AnalyzerFor(t *testing.T) {
+	reg, err := NewLanguageAnalyzerRegistry()
+	if err != nil {
+		t.Fatalf("NewLanguageAnalyzerRegistry() unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		lang string
+		text string
+		want string
+	}{
+		{"dutch by name", "Dutch", "Boeken", "boek"},
+		{"dutch by EAD langcode", "dut", "Boeken", "boek"},
+		{"dutch by language tag", "nl-BE", "Boeken", "boek"},
+		{"german", "ger", "Häuser", "haus"},
+		{"french", "fre", "Archives", "archiv"},
+		{"unknown language uses the fallback", "tlh", "Boeken", "boeken"},
+		{"empty language uses the fallback", "", "Boeken", "boeken"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got := reg.AnalyzerFor(tt.lang).Transform(tt.text)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("AnalyzerRegistry.AnalyzerFor(%q); %s = mismatch (-want +got):\n%s", tt.lang, tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzerRegistry_Register(t *testing.T) {
+	fallback := &Analyzer{}
+	reg := NewAnalyzerRegistry(fallback)
+
+	nl, err := NewAnalyzer(WithStopwords([]string{"de"}), WithStemmer("dutch"))
+	if err != nil {
+		t.Fatalf("NewAnalyzer() unexpected error: %s", err)
+	}
+
+	reg.Register("nld", nl)
+
+	if got := reg.AnalyzerFor("dutch"); got != nl {
+		t.Errorf("AnalyzerRegistry.AnalyzerFor(\"dutch\") did not return the registered analyzer")
+	}
+
+	if got := reg.AnalyzerFor("en"); got != fallback {
+		t.Errorf("AnalyzerRegistry.AnalyzerFor(\"en\") did not return the fallback analyzer")
+	}
+
+	if diff := cmp.Diff("boek", reg.AnalyzerFor("dut").Transform("de boeken")); diff != "" {
+		t.Errorf("AnalyzerRegistry.AnalyzerFor(\"dut\") = mismatch (-want +got):\n%s", diff)
+	}
+}
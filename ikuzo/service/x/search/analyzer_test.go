@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/text/unicode/norm"
 )
 
 func TestAnalyzer_Transform(t *testing.T) {
@@ -79,3 +80,363 @@ func TestAnalyzer_Transform(t *testing.T) {
 		})
 	}
 }
+
+func TestAnalyzer_WithStopwords(t *testing.T) {
+	type args struct {
+		stopwords []string
+		text      string
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		want       string
+		wantPhrase string
+	}{
+		{
+			"no stopwords keeps single token",
+			args{text: " De Boerderij, van"},
+			" de boerderij, van",
+			"de boerderij van",
+		},
+		{
+			"remove stopwords",
+			args{stopwords: []string{"de", "van"}, text: "De boerderij van Jansen"},
+			"boerderij jansen",
+			"boerderij jansen",
+		},
+		{
+			"stopwords are folded",
+			args{stopwords: []string{"Ÿe"}, text: "ye olde Shoppe"},
+			"olde shoppe",
+			"olde shoppe",
+		},
+		{
+			"punctuation is trimmed per word",
+			args{stopwords: []string{"the"}, text: "(the) farm."},
+			"farm",
+			"farm",
+		},
+		{
+			"only stopwords",
+			args{stopwords: []string{"de", "van"}, text: "de van"},
+			"",
+			"",
+		},
+		{
+			"single token without stopword",
+			args{stopwords: []string{"de"}, text: "Word"},
+			"word",
+			"word",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAnalyzer(WithStopwords(tt.args.stopwords))
+			if err != nil {
+				t.Fatalf("NewAnalyzer() unexpected error: %s", err)
+			}
+
+			if diff := cmp.Diff(tt.want, a.Transform(tt.args.text)); diff != "" {
+				t.Errorf("Analyzer.Transform(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+
+			if diff := cmp.Diff(tt.wantPhrase, a.TransformPhrase(tt.args.text)); diff != "" {
+				t.Errorf("Analyzer.TransformPhrase(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Tokenize(t *testing.T) {
+	type args struct {
+		stopwords []string
+		text      string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want []AnalyzedToken
+	}{
+		{
+			"empty text",
+			args{text: "  "},
+			[]AnalyzedToken{},
+		},
+		{
+			"offsets of folded words",
+			args{text: "Café  Öland"},
+			[]AnalyzedToken{
+				{Term: "cafe", Start: 0, End: 5, Position: 1},
+				{Term: "oland", Start: 7, End: 13, Position: 2},
+			},
+		},
+		{
+			"punctuation is excluded from the offsets",
+			args{text: "(farm), «house»"},
+			[]AnalyzedToken{
+				{Term: "farm", Start: 1, End: 5, Position: 1},
+				{Term: "house", Start: 10, End: 15, Position: 2},
+			},
+		},
+		{
+			"punctuation only words take no position",
+			args{text: "farm ; house"},
+			[]AnalyzedToken{
+				{Term: "farm", Start: 0, End: 4, Position: 1},
+				{Term: "house", Start: 7, End: 12, Position: 2},
+			},
+		},
+		{
+			"stopwords keep their position",
+			args{stopwords: []string{"de"}, text: "de Boerderij"},
+			[]AnalyzedToken{
+				{Term: "boerderij", Start: 3, End: 12, Position: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAnalyzer(WithStopwords(tt.args.stopwords))
+			if err != nil {
+				t.Fatalf("NewAnalyzer() unexpected error: %s", err)
+			}
+
+			got := a.Tokenize(tt.args.text)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Analyzer.Tokenize(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+
+			for _, token := range got {
+				if a.fold(tt.args.text[token.Start:token.End]) != token.Term {
+					t.Errorf("Analyzer.Tokenize(); %s offsets %d:%d do not match term %q", tt.name, token.Start, token.End, token.Term)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_WithStemmer(t *testing.T) {
+	type args struct {
+		lang      string
+		stopwords []string
+		text      string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			"dutch plural and singular",
+			args{lang: "dutch", text: "Boeken boek"},
+			"boek boek",
+			false,
+		},
+		{
+			"english",
+			args{lang: "English", text: "running archives"},
+			"run archiv",
+			false,
+		},
+		{
+			"stemming after folding",
+			args{lang: "dutch", text: "BOÉKEN"},
+			"boek",
+			false,
+		},
+		{
+			"stopwords are removed before stemming",
+			args{lang: "dutch", stopwords: []string{"de"}, text: "de boeken"},
+			"boek",
+			false,
+		},
+		{
+			"unknown language",
+			args{lang: "klingon", text: "qapla"},
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAnalyzer(WithStopwords(tt.args.stopwords), WithStemmer(tt.args.lang))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAnalyzer() %s = error %v, wantErr %v", tt.name, err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, a.TransformPhrase(tt.args.text)); diff != "" {
+				t.Errorf("Analyzer.TransformPhrase(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_WithUnicodeNormalization(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			"precomposed",
+			"\u00e9t\u00e9",
+			"ete",
+		},
+		{
+			"decomposed",
+			"e\u0301te\u0301",
+			"ete",
+		},
+		{
+			"mixed",
+			"Caf\u00e9 cafe\u0301",
+			"cafe cafe",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAnalyzer(WithUnicodeNormalization(norm.NFC))
+			if err != nil {
+				t.Fatalf("NewAnalyzer() unexpected error: %s", err)
+			}
+
+			if diff := cmp.Diff(tt.want, a.TransformPhrase(tt.text)); diff != "" {
+				t.Errorf("Analyzer.TransformPhrase(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+
+	t.Run("no normalization by default", func(t *testing.T) {
+		a := &Analyzer{}
+
+		if got := a.Transform("e\u0301"); got != "e\u0301" {
+			t.Errorf("Analyzer.Transform() = %q, want the decomposed text unchanged", got)
+		}
+	})
+}
+
+func TestAnalyzer_WithMinTokenLength(t *testing.T) {
+	type args struct {
+		minLength   int
+		keepNumbers bool
+		text        string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			"no minimum",
+			args{text: "a b farm"},
+			"a b farm",
+			false,
+		},
+		{
+			"drop short words",
+			args{minLength: 3, text: "J. de Vries, 14 mei"},
+			"vries mei",
+			false,
+		},
+		{
+			"length is measured after folding",
+			args{minLength: 3, text: "(ab) Æb"},
+			"aeb",
+			false,
+		},
+		{
+			"keep numbers",
+			args{minLength: 3, keepNumbers: true, text: "J. de Vries, 14 mei"},
+			"vries 14 mei",
+			false,
+		},
+		{
+			"negative minimum",
+			args{minLength: -1, text: "farm"},
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			options := []AnalyzerOption{WithMinTokenLength(tt.args.minLength)}
+			if tt.args.keepNumbers {
+				options = append(options, WithKeepNumbers())
+			}
+
+			a, err := NewAnalyzer(options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAnalyzer() %s = error %v, wantErr %v", tt.name, err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, a.Transform(tt.args.text)); diff != "" {
+				t.Errorf("Analyzer.Transform(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Explain(t *testing.T) {
+	a, err := NewAnalyzer(
+		WithUnicodeNormalization(norm.NFC),
+		WithStopwords([]string{"de"}),
+	)
+	if err != nil {
+		t.Fatalf("NewAnalyzer() unexpected error = %v", err)
+	}
+
+	// the first accent is a combining character that is composed by the normalization
+	text := "\"De Rue\u0301 Caf\u00e9\""
+
+	want := AnalysisResult{
+		Original:   text,
+		Normalized: "\"De Ru\u00e9 Caf\u00e9\"",
+		Folded:     "\"de rue cafe\"",
+		Trimmed:    "de rue cafe",
+		Tokens: []AnalyzedToken{
+			{Term: "rue", Start: 4, End: 9, Position: 2},
+			{Term: "cafe", Start: 10, End: 15, Position: 3},
+		},
+	}
+
+	got := a.Explain(text)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Analyzer.Explain() mismatch (-want +got):\n%s", diff)
+	}
+
+	// the final token terms match the hot path
+	if transformed := a.Transform(text); transformed != "rue cafe" {
+		t.Errorf("Analyzer.Transform() = %q, want %q", transformed, "rue cafe")
+	}
+}
@@ -35,9 +35,19 @@ func NativeASCIIFolding(text string) string {
 	return s
 }
 
-// FoldASCII converts Unicode characters to ASCII equivalent.
+// LuceneASCIIFolding converts Unicode characters to ASCII equivalent.
 // When none is found the original unicode is returned.
 //
+// The conversion table is the same as the Lucene 'ASCIIFoldingFilter' that is
+// used by the 'asciifolding' token filter of ElasticSearch. This includes the
+// ligatures, e.g. 'æ' to 'ae' and 'œ' to 'oe', the German 'ß' to 'ss' and the
+// Scandinavian 'ø' to 'o'.
+//
+// Like Lucene, the following characters are deliberately not folded:
+//   - combining marks, e.g. U+0301 after an 'e'. Use WithUnicodeNormalization
+//     with norm.NFC to compose them before folding.
+//   - letters without a Latin equivalent, e.g. Greek, Cyrillic and CJK.
+//
 // The native Go solution in NativeASCIIFolding() does not produce the exact same
 // result as the Lucene 'ASCIIFoldingFilter'.
 func LuceneASCIIFolding(str string) string {
@@ -40,6 +40,61 @@ func Test_LuceneASCIIFolding(t *testing.T) {
 	}
 }
 
+func TestLuceneASCIIFolding_words(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"ligature ae", "Ærøskøbing", "AEroskobing"},
+		{"ligature oe", "Œdipe en œuvre", "OEdipe en oeuvre"},
+		{"ligature fi", "\uFB01nancial", "financial"},
+		{"sharp s", "Straße", "Strasse"},
+		{"capital sharp s", "STRA\u1E9EE", "STRASSE"},
+		{"o with stroke", "Øresund", "Oresund"},
+		// combining marks are not folded, see WithUnicodeNormalization
+		{"combining acute", "Caf\u0065\u0301", "Caf\u0065\u0301"},
+		{"greek", "αβγ", "αβγ"},
+		{"cyrillic", "жизнь", "жизнь"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LuceneASCIIFolding(tt.text); got != tt.want {
+				t.Errorf("LuceneASCIIFolding() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test_foldRune_parity checks that every rune that is folded has an entry in
+// the foldingTests, so the folding does not diverge from the Lucene table.
+func Test_foldRune_parity(t *testing.T) {
+	tested := map[string]bool{}
+
+	for _, tt := range foldingTests {
+		text, err := strconv.Unquote("\"" + tt.escapedRune + "\"")
+		if err != nil {
+			t.Fatalf("unable to unquote %s; %s", tt.escapedRune, err)
+		}
+
+		tested[text] = true
+	}
+
+	for r := rune(0x80); r <= 0xFFFF; r++ {
+		if r >= 0xD800 && r <= 0xDFFF {
+			// surrogates are not valid runes
+			continue
+		}
+
+		if folded := foldRune(r); folded != string(r) && !tested[string(r)] {
+			t.Errorf("rune %U is folded to %q without an entry in the Lucene folding table", r, folded)
+		}
+	}
+}
+
 // full list of lucene ascii folding adapted from:
 // http://svn.apache.org/repos/asf/lucene/java/tags/lucene_solr_4_5_1/lucene/analysis/common/src/java/org/apache/lucene/analysis/miscellaneous/ASCIIFoldingFilter.java
 // nolint:lll
@@ -0,0 +1,302 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Index is a small in-memory inverted index. The fields of the documents are
+// analyzed with the same Analyzer that is used for the ElasticSearch queries,
+// so the matches are close to those of the ElasticSearch-backed search.
+//
+// When an AnalyzerRegistry is configured, each document is analyzed with the
+// Analyzer of its language and the queries are analyzed with the Analyzer of
+// each indexed language, see WithAnalyzerRegistry.
+//
+// The Index is meant for demos and tests with small datasets. It keeps all
+// postings in memory and scans the terms for prefix queries, so it is not
+// suitable for production-sized corpora.
+//
+// It is safe for concurrent use.
+type Index struct {
+	rw       sync.RWMutex
+	analyzer *Analyzer
+	registry *AnalyzerRegistry
+	// langs contains the normalized language of each document ID
+	langs map[string]string
+	// postings maps each field to the frequency of a term per document ID
+	postings map[string]map[string]map[string]int
+	// docs contains the indexed terms per field of each document ID
+	docs map[string]map[string][]string
+}
+
+// IndexOption configures the Index.
+type IndexOption func(idx *Index) error
+
+// Hit is a document that matches a query on the Index.
+type Hit struct {
+	ID string
+	// Score is the number of times the matched terms occur in the document
+	Score int
+}
+
+// NewIndex returns an empty Index configured with the options.
+// By default the zero value Analyzer is used.
+func NewIndex(options ...IndexOption) (*Index, error) {
+	idx := &Index{
+		analyzer: &Analyzer{},
+		langs:    make(map[string]string),
+		postings: make(map[string]map[string]map[string]int),
+		docs:     make(map[string]map[string][]string),
+	}
+
+	for _, option := range options {
+		if err := option(idx); err != nil {
+			return nil, err
+		}
+	}
+
+	return idx, nil
+}
+
+// WithIndexAnalyzer sets the Analyzer for the indexed fields and the queries.
+func WithIndexAnalyzer(a *Analyzer) IndexOption {
+	return func(idx *Index) error {
+		if a != nil {
+			idx.analyzer = a
+		}
+
+		return nil
+	}
+}
+
+// WithAnalyzerRegistry selects the Analyzer by the language of the document,
+// see AddWithLanguage. It replaces the Analyzer of WithIndexAnalyzer. The
+// fallback Analyzer of the registry is used for documents without a language.
+func WithAnalyzerRegistry(reg *AnalyzerRegistry) IndexOption {
+	return func(idx *Index) error {
+		idx.registry = reg
+		return nil
+	}
+}
+
+// Add indexes the fields of the document with the given ID.
+// A document that is already indexed with the same ID is replaced.
+func (idx *Index) Add(id string, fields map[string]string) {
+	idx.AddWithLanguage(id, "", fields)
+}
+
+// AddWithLanguage indexes the fields of the document like Add, but analyzes
+// them with the Analyzer of the language when an AnalyzerRegistry is
+// configured. The language is a name or an ISO 639 code, e.g. 'nl' or 'dut'.
+func (idx *Index) AddWithLanguage(id, lang string, fields map[string]string) {
+	idx.rw.Lock()
+	defer idx.rw.Unlock()
+
+	idx.deleteLocked(id)
+
+	lang = normalizeLanguage(lang)
+	analyzer := idx.analyzerFor(lang)
+
+	if lang != "" {
+		idx.langs[id] = lang
+	}
+
+	indexed := make(map[string][]string, len(fields))
+
+	for field, text := range fields {
+		terms, ok := idx.postings[field]
+		if !ok {
+			terms = make(map[string]map[string]int)
+			idx.postings[field] = terms
+		}
+
+		for _, token := range analyzer.Tokenize(text) {
+			docs, ok := terms[token.Term]
+			if !ok {
+				docs = make(map[string]int)
+				terms[token.Term] = docs
+			}
+
+			if docs[id] == 0 {
+				indexed[field] = append(indexed[field], token.Term)
+			}
+
+			docs[id]++
+		}
+	}
+
+	idx.docs[id] = indexed
+}
+
+// Delete removes the document with the given ID from the Index.
+func (idx *Index) Delete(id string) {
+	idx.rw.Lock()
+	defer idx.rw.Unlock()
+
+	idx.deleteLocked(id)
+}
+
+// deleteLocked removes the document. The caller must hold the write lock.
+func (idx *Index) deleteLocked(id string) {
+	for field, terms := range idx.docs[id] {
+		for _, term := range terms {
+			docs := idx.postings[field][term]
+			delete(docs, id)
+
+			if len(docs) == 0 {
+				delete(idx.postings[field], term)
+			}
+		}
+	}
+
+	delete(idx.docs, id)
+	delete(idx.langs, id)
+}
+
+// analyzerFor returns the Analyzer for the normalized language.
+func (idx *Index) analyzerFor(lang string) *Analyzer {
+	if idx.registry == nil {
+		return idx.analyzer
+	}
+
+	return idx.registry.AnalyzerFor(lang)
+}
+
+// Len returns the number of indexed documents.
+func (idx *Index) Len() int {
+	idx.rw.RLock()
+	defer idx.rw.RUnlock()
+
+	return len(idx.docs)
+}
+
+// TermQuery returns the documents whose field contains one of the terms of
+// the analyzed text, similar to a 'match' query of ElasticSearch. When field
+// is empty all fields are searched.
+//
+// The text is analyzed with the Analyzer of each indexed language and only
+// matches the documents of that language, so the stemmed terms of the query and
+// the documents are the same.
+//
+// The hits are ranked by the frequency of the terms in the document.
+func (idx *Index) TermQuery(field, text string) []Hit {
+	idx.rw.RLock()
+	defer idx.rw.RUnlock()
+
+	scores := map[string]int{}
+
+	for lang, analyzer := range idx.queryAnalyzers() {
+		for _, token := range analyzer.Tokenize(text) {
+			for _, terms := range idx.fields(field) {
+				for id, freq := range terms[token.Term] {
+					if idx.registry != nil && idx.langs[id] != lang {
+						continue
+					}
+
+					scores[id] += freq
+				}
+			}
+		}
+	}
+
+	return rankHits(scores)
+}
+
+// queryAnalyzers returns the Analyzer of each indexed language. The Analyzer
+// for the documents without a language is returned with an empty language.
+func (idx *Index) queryAnalyzers() map[string]*Analyzer {
+	analyzers := map[string]*Analyzer{"": idx.analyzerFor("")}
+
+	if idx.registry == nil {
+		return analyzers
+	}
+
+	for _, lang := range idx.langs {
+		if _, ok := analyzers[lang]; !ok {
+			analyzers[lang] = idx.analyzerFor(lang)
+		}
+	}
+
+	return analyzers
+}
+
+// PrefixQuery returns the documents whose field contains a term that starts
+// with the prefix. The prefix is folded but not stemmed, because a stemmed
+// prefix would no longer match the terms it is a prefix of. When field is
+// empty all fields are searched.
+//
+// The hits are ranked by the frequency of the matching terms in the document.
+func (idx *Index) PrefixQuery(field, prefix string) []Hit {
+	idx.rw.RLock()
+	defer idx.rw.RUnlock()
+
+	prefix = idx.analyzerFor("").fold(prefix)
+	if prefix == "" {
+		return []Hit{}
+	}
+
+	scores := map[string]int{}
+
+	for _, terms := range idx.fields(field) {
+		for term, docs := range terms {
+			if !strings.HasPrefix(term, prefix) {
+				continue
+			}
+
+			for id, freq := range docs {
+				scores[id] += freq
+			}
+		}
+	}
+
+	return rankHits(scores)
+}
+
+// fields returns the postings of the field or of all fields when it is empty.
+func (idx *Index) fields(field string) []map[string]map[string]int {
+	if field != "" {
+		return []map[string]map[string]int{idx.postings[field]}
+	}
+
+	all := make([]map[string]map[string]int, 0, len(idx.postings))
+	for _, terms := range idx.postings {
+		all = append(all, terms)
+	}
+
+	return all
+}
+
+// rankHits returns the hits sorted by descending score. Hits with the same
+// score are sorted by ID, so the order is stable.
+func rankHits(scores map[string]int) []Hit {
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+
+		return hits[i].ID < hits[j].ID
+	})
+
+	return hits
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestIndex(t *testing.T, options ...IndexOption) *Index {
+	t.Helper()
+
+	idx, err := NewIndex(options...)
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+
+	idx.Add("1", map[string]string{
+		"title":       "Het Nationaal Archief",
+		"description": "Archieven van de Staten-Generaal en het archief van de VOC",
+	})
+	idx.Add("2", map[string]string{
+		"title":       "Archief van de Gemeente Leiden",
+		"description": "Notariële archieven",
+	})
+	idx.Add("3", map[string]string{
+		"title": "Kaarten en tekeningen",
+	})
+
+	return idx
+}
+
+func TestIndex_TermQuery(t *testing.T) {
+	dutch, err := NewAnalyzer(WithStemmer("dutch"))
+	if err != nil {
+		t.Fatalf("NewAnalyzer() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		options []IndexOption
+		field   string
+		text    string
+		want    []Hit
+	}{
+		{"single field", nil, "title", "archief", []Hit{{"1", 1}, {"2", 1}}},
+		{"all fields ranked by frequency", nil, "", "ARCHIEF", []Hit{{"1", 2}, {"2", 1}}},
+		{"folded", nil, "description", "notariele", []Hit{{"2", 1}}},
+		{"multiple terms", nil, "title", "kaarten leiden", []Hit{{"2", 1}, {"3", 1}}},
+		{"stemmed", []IndexOption{WithIndexAnalyzer(dutch)}, "title", "kaart", []Hit{{"3", 1}}},
+		{"not stemmed", nil, "title", "kaart", []Hit{}},
+		{"unknown field", nil, "creator", "archief", []Hit{}},
+		{"no match", nil, "", "rijksmuseum", []Hit{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			idx := newTestIndex(t, tt.options...)
+
+			got := idx.TermQuery(tt.field, tt.text)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Index.TermQuery() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIndex_PrefixQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		field  string
+		prefix string
+		want   []Hit
+	}{
+		{"single field", "title", "arch", []Hit{{"1", 1}, {"2", 1}}},
+		{"all fields", "", "Arch", []Hit{{"1", 3}, {"2", 2}}},
+		{"folded", "", "notarië", []Hit{{"2", 1}}},
+		{"empty prefix", "", "", []Hit{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			idx := newTestIndex(t)
+
+			got := idx.PrefixQuery(tt.field, tt.prefix)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Index.PrefixQuery() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIndex_replaceAndDelete(t *testing.T) {
+	idx := newTestIndex(t)
+
+	idx.Add("1", map[string]string{"title": "Kaart van Leiden"})
+
+	if diff := cmp.Diff([]Hit{{"2", 1}}, idx.TermQuery("", "archief")); diff != "" {
+		t.Errorf("Index.TermQuery() after replace mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]Hit{{"1", 1}, {"2", 1}}, idx.TermQuery("title", "leiden")); diff != "" {
+		t.Errorf("Index.TermQuery() after replace mismatch (-want +got):\n%s", diff)
+	}
+
+	idx.Delete("1")
+	idx.Delete("unknown")
+
+	if got := idx.Len(); got != 2 {
+		t.Errorf("Index.Len() = %d, want 2", got)
+	}
+
+	if diff := cmp.Diff([]Hit{{"2", 1}}, idx.TermQuery("title", "leiden")); diff != "" {
+		t.Errorf("Index.TermQuery() after delete mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIndex_AddWithLanguage(t *testing.T) {
+	reg, err := NewLanguageAnalyzerRegistry()
+	if err != nil {
+		t.Fatalf("NewLanguageAnalyzerRegistry() error = %v", err)
+	}
+
+	idx, err := NewIndex(WithAnalyzerRegistry(reg))
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+
+	idx.AddWithLanguage("nl", "dut", map[string]string{"title": "Kaarten van Leiden"})
+	idx.AddWithLanguage("de", "ger", map[string]string{"title": "Karten von Berlin"})
+	idx.Add("none", map[string]string{"title": "Kaarten"})
+
+	tests := []struct {
+		name string
+		text string
+		want []Hit
+	}{
+		{"dutch stem", "kaart", []Hit{{"nl", 1}}},
+		{"german stem", "Karten", []Hit{{"de", 1}}},
+		{"dutch stem and fallback", "kaarten", []Hit{{"nl", 1}, {"none", 1}}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, idx.TermQuery("title", tt.text)); diff != "" {
+				t.Errorf("Index.TermQuery(%q) mismatch (-want +got):\n%s", tt.text, diff)
+			}
+		})
+	}
+
+	// the language is removed with the document
+	idx.Delete("nl")
+
+	if got := idx.TermQuery("title", "kaart"); len(got) != 0 {
+		t.Errorf("Index.TermQuery() after Delete = %v, want no hits", got)
+	}
+}
@@ -16,6 +16,7 @@ package memory
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/delving/hub3/ikuzo/domain"
@@ -43,22 +44,95 @@ func NewNameSpaceStore() *NameSpaceStore {
 // Len returns the number of stored namespaces.
 // Alternatives Base or Prefixes don't count towards the total.
 func (ms *NameSpaceStore) Len() int {
+	ms.RLock()
+	defer ms.RUnlock()
+
 	return len(ms.namespaces)
 }
 
-// Set stores the NameSpace in the Store
+// Set stores the NameSpace in the Store.
+// The validation error is returned when the NameSpace is not valid.
 func (ms *NameSpaceStore) Set(ns *domain.NameSpace) error {
 	if ns == nil {
 		return fmt.Errorf("cannot store empty namespace")
 	}
 
-	// this implementation of Delete can never return an error
-	_ = ms.Delete(ns)
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	ms.setLocked(ns)
+
+	return nil
+}
+
+// SetBatch stores all the namespaces under a single lock.
+// Nothing is stored when one of the namespaces is not valid.
+func (ms *NameSpaceStore) SetBatch(namespaces []*domain.NameSpace) error {
+	for _, ns := range namespaces {
+		if ns == nil {
+			return fmt.Errorf("cannot store empty namespace")
+		}
+
+		if err := ns.Validate(); err != nil {
+			return err
+		}
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	for _, ns := range namespaces {
+		ms.setLocked(ns)
+	}
+
+	return nil
+}
+
+// SetUnique stores the NameSpace like Set, but returns a
+// *domain.DuplicateEntryError when one of its prefixes or base-URIs is
+// linked to another NameSpace. The check and the update are done under a
+// single lock.
+func (ms *NameSpaceStore) SetUnique(ns *domain.NameSpace) error {
+	if ns == nil {
+		return fmt.Errorf("cannot store empty namespace")
+	}
+
+	if err := ns.Validate(); err != nil {
+		return err
+	}
 
-	// if you lock earlier you get a deadlock
 	ms.Lock()
 	defer ms.Unlock()
 
+	id := ns.GetID()
+
+	for _, prefix := range ns.Prefixes() {
+		if other, ok := ms.prefix2base[prefix]; ok && other.GetID() != id {
+			return &domain.DuplicateEntryError{Prefix: prefix, Base: ns.Base, Existing: other}
+		}
+	}
+
+	for _, base := range ns.BaseURIs() {
+		if other, ok := ms.base2prefix[base]; ok && other.GetID() != id {
+			return &domain.DuplicateEntryError{Prefix: ns.Prefix, Base: base, Existing: other}
+		}
+	}
+
+	ms.setLocked(ns)
+
+	return nil
+}
+
+// setLocked stores the NameSpace. The caller must hold the write lock.
+func (ms *NameSpaceStore) setLocked(ns *domain.NameSpace) {
+	ms.deleteLocked(ns)
+
+	id := ns.GetID()
+
 	for _, prefix := range ns.Prefixes() {
 		ms.prefix2base[prefix] = ns
 	}
@@ -67,10 +141,7 @@ func (ms *NameSpaceStore) Set(ns *domain.NameSpace) error {
 		ms.base2prefix[base] = ns
 	}
 
-	id := ns.GetID()
 	ms.namespaces[id] = ns
-
-	return nil
 }
 
 // Delete removes a NameSpace from the store
@@ -78,12 +149,17 @@ func (ms *NameSpaceStore) Delete(ns *domain.NameSpace) error {
 	ms.Lock()
 	defer ms.Unlock()
 
+	ms.deleteLocked(ns)
+
+	return nil
+}
+
+// deleteLocked removes the NameSpace. The caller must hold the write lock.
+func (ms *NameSpaceStore) deleteLocked(ns *domain.NameSpace) {
 	id := ns.GetID()
 
-	_, ok := ms.namespaces[id]
-	if ok {
-		delete(ms.namespaces, id)
-	}
+	delete(ms.namespaces, id)
+
 	// drop all prefixes
 	for _, p := range ns.Prefixes() {
 		_, ok := ms.prefix2base[p]
@@ -99,8 +175,6 @@ func (ms *NameSpaceStore) Delete(ns *domain.NameSpace) error {
 			delete(ms.base2prefix, b)
 		}
 	}
-
-	return nil
 }
 
 // GetWithPrefix returns a NameSpace from the store if the prefix is found.
@@ -129,9 +203,12 @@ func (ms *NameSpaceStore) GetWithBase(base string) (*domain.NameSpace, error) {
 	return ns, nil
 }
 
-// List returns a list of all the stored NameSpace objects.
+// List returns a list of all the stored NameSpace objects sorted by prefix and base.
 // An error is only returned when the underlying datastructure is unavailable.
 func (ms *NameSpaceStore) List() ([]*domain.NameSpace, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
 	namespaces := []*domain.NameSpace{}
 	for _, ns := range ms.namespaces {
 		if ns != nil {
@@ -139,5 +216,14 @@ func (ms *NameSpaceStore) List() ([]*domain.NameSpace, error) {
 		}
 	}
 
+	// map iteration is random, so sort to return the same order on each call
+	sort.Slice(namespaces, func(i, j int) bool {
+		if namespaces[i].Prefix != namespaces[j].Prefix {
+			return namespaces[i].Prefix < namespaces[j].Prefix
+		}
+
+		return namespaces[i].Base < namespaces[j].Base
+	})
+
 	return namespaces, nil
 }
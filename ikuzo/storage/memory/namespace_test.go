@@ -15,7 +15,10 @@
 package memory
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/delving/hub3/ikuzo/domain"
@@ -160,3 +163,115 @@ func TestGetFromNameSpaceStore(t *testing.T) {
 	is.NoErr(err)
 	is.Equal(len(namespaces), 2)
 }
+
+func TestNameSpaceStoreSetValidates(t *testing.T) {
+	is := is.New(t)
+
+	store := NewNameSpaceStore()
+
+	err := store.Set(&domain.NameSpace{Prefix: "dc_terms", Base: "http://purl.org/dc/terms/"})
+	is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+	is.Equal(store.Len(), 0) // invalid namespaces are not stored
+}
+
+func TestNameSpaceStoreSetBatch(t *testing.T) {
+	is := is.New(t)
+
+	store := NewNameSpaceStore()
+
+	err := store.SetBatch([]*domain.NameSpace{
+		{Prefix: "dc", Base: "http://purl.org/dc/elements/1.1/"},
+		{Prefix: "dc_terms", Base: "http://purl.org/dc/terms/"},
+	})
+	is.True(errors.Is(err, domain.ErrNameSpaceNotValid))
+	is.Equal(store.Len(), 0) // nothing is stored when one namespace is not valid
+
+	err = store.SetBatch([]*domain.NameSpace{
+		{Prefix: "dc", Base: "http://purl.org/dc/elements/1.1/"},
+		{Prefix: "dcterms", Base: "http://purl.org/dc/terms/"},
+	})
+	is.NoErr(err)
+	is.Equal(store.Len(), 2)
+}
+
+func TestNameSpaceStoreListOrder(t *testing.T) {
+	is := is.New(t)
+
+	store := NewNameSpaceStore()
+
+	for _, ns := range []*domain.NameSpace{
+		{Prefix: "rdf", Base: "http://www.w3.org/1999/02/22-rdf-syntax-ns#"},
+		{Prefix: "dc", Base: "http://purl.org/dc/elements/1.1/"},
+		{Prefix: "skos", Base: "http://www.w3.org/2004/02/skos/core#"},
+		{Prefix: "dc", Base: "http://example.com/dc/"},
+		{Prefix: "edm", Base: "http://www.europeana.eu/schemas/edm/"},
+	} {
+		is.NoErr(store.Set(ns))
+	}
+
+	first, err := store.List()
+	is.NoErr(err)
+
+	got := []string{}
+	for _, ns := range first {
+		got = append(got, ns.Prefix+" "+ns.Base)
+	}
+
+	is.Equal(got, []string{
+		"dc http://example.com/dc/",
+		"dc http://purl.org/dc/elements/1.1/",
+		"edm http://www.europeana.eu/schemas/edm/",
+		"rdf http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+		"skos http://www.w3.org/2004/02/skos/core#",
+	})
+
+	for i := 0; i < 10; i++ {
+		namespaces, err := store.List()
+		is.NoErr(err)
+		is.Equal(namespaces, first) // repeated calls return the same order
+	}
+}
+
+// TestNameSpaceStoreConcurrency must be run with -race to prove the store is safe
+// for concurrent use.
+func TestNameSpaceStoreConcurrency(t *testing.T) {
+	store := NewNameSpaceStore()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		i := i
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				ns := &domain.NameSpace{
+					Prefix: fmt.Sprintf("ns%d", i),
+					Base:   fmt.Sprintf("http://example.com/%d/", i),
+				}
+
+				if err := store.Set(ns); err != nil {
+					t.Errorf("unexpected error: %#v", err)
+				}
+
+				_, _ = store.GetWithPrefix(ns.Prefix)
+				_, _ = store.GetWithBase(ns.Base)
+				_, _ = store.List()
+				_ = store.Len()
+
+				if err := store.Delete(ns); err != nil {
+					t.Errorf("unexpected error: %#v", err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if store.Len() != 0 {
+		t.Errorf("memoryStore should be empty; got %d", store.Len())
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package elastictest provides utilities for testing code that uses an
+// Elasticsearch client without a running Elasticsearch cluster.
+package elastictest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// NewClient starts a httptest.Server with the handler and returns an
+// elastic.Client that is connected to it. The server is closed when the test
+// and its subtests complete.
+func NewClient(t testing.TB, handler http.Handler) *elastic.Client {
+	t.Helper()
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	client, err := elastic.NewClient(
+		elastic.SetURL(ts.URL),
+		elastic.SetSniff(false),
+		elastic.SetHealthcheck(false),
+	)
+	if err != nil {
+		t.Fatalf("unable to create mock elastic client; %s", err)
+	}
+
+	return client
+}
+
+// NewResponseClient returns an elastic.Client like NewClient that answers
+// every request with the status and the JSON response body.
+func NewResponseClient(t testing.TB, status int, response string) *elastic.Client {
+	t.Helper()
+
+	return NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+}
@@ -0,0 +1,141 @@
+package ginger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// batch buffers PostHookJobs until they are posted.
+type batch struct {
+	size     int
+	interval time.Duration
+	jobs     []*PostHookJob
+	timer    *time.Timer
+	sync.Mutex
+}
+
+// PostError is returned when records could not be posted or deleted.
+// Subjects identifies the records that were not stored or deleted by the
+// endpoint. The HubID is used for deleted records without a subject.
+type PostError struct {
+	Subjects []string
+	Err      error
+}
+
+func newPostError(jobs []*PostHookJob, err error) *PostError {
+	subjects := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		subject := job.item.Subject
+		if subject == "" {
+			subject = job.item.HubID
+		}
+
+		subjects = append(subjects, subject)
+	}
+
+	return &PostError{Subjects: subjects, Err: err}
+}
+
+// joinPostErrors adds the subjects of err to failed when it is a *PostError.
+// Other errors are returned unchanged.
+func joinPostErrors(failed *PostError, err error) (*PostError, error) {
+	if err == nil {
+		return failed, nil
+	}
+
+	postErr, ok := err.(*PostError)
+	if !ok {
+		return failed, err
+	}
+
+	if failed == nil {
+		return postErr, nil
+	}
+
+	failed.Subjects = append(failed.Subjects, postErr.Subjects...)
+
+	return failed, nil
+}
+
+func (e *PostError) Error() string {
+	return fmt.Sprintf("unable to post %d records [%s]; %s", len(e.Subjects), strings.Join(e.Subjects, ", "), e.Err)
+}
+
+func (e *PostError) Unwrap() error {
+	return e.Err
+}
+
+// addToBatch adds the jobs to the batch and posts all full batches.
+// The first job of a new batch starts the timer for the flush interval.
+func (ph *PostHook) addToBatch(ctx context.Context, jobs []*PostHookJob) error {
+	ph.batch.Lock()
+
+	ph.batch.jobs = append(ph.batch.jobs, jobs...)
+
+	full := [][]*PostHookJob{}
+	for len(ph.batch.jobs) >= ph.batch.size {
+		full = append(full, ph.batch.jobs[:ph.batch.size])
+		ph.batch.jobs = ph.batch.jobs[ph.batch.size:]
+	}
+
+	switch {
+	case len(ph.batch.jobs) == 0 && ph.batch.timer != nil:
+		ph.batch.timer.Stop()
+		ph.batch.timer = nil
+	case len(ph.batch.jobs) != 0 && ph.batch.timer == nil:
+		ph.batch.timer = time.AfterFunc(ph.batch.interval, func() {
+			if err := ph.Flush(context.Background()); err != nil {
+				log.Error().Err(err).Str("svc", "posthook").Msg("unable to flush posthook batch")
+			}
+		})
+	}
+
+	ph.batch.Unlock()
+
+	return ph.postBatches(ctx, full)
+}
+
+// Flush posts the records that are buffered in the batch.
+func (ph *PostHook) Flush(ctx context.Context) error {
+	ph.batch.Lock()
+
+	jobs := ph.batch.jobs
+	ph.batch.jobs = nil
+
+	if ph.batch.timer != nil {
+		ph.batch.timer.Stop()
+		ph.batch.timer = nil
+	}
+
+	ph.batch.Unlock()
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	return ph.postBatches(ctx, [][]*PostHookJob{jobs})
+}
+
+// postBatches posts each batch. A failed batch does not stop the other batches
+// from being posted. The returned *PostError contains the subjects of all failed batches.
+func (ph *PostHook) postBatches(ctx context.Context, batches [][]*PostHookJob) error {
+	var failed *PostError
+
+	for _, jobs := range batches {
+		var err error
+		if failed, err = joinPostErrors(failed, ph.postJobs(ctx, jobs)); err != nil {
+			return err
+		}
+	}
+
+	if failed == nil {
+		return nil
+	}
+
+	return failed
+}
@@ -0,0 +1,220 @@
+package ginger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/service/x/bulk"
+)
+
+// batchServer records the number of records of each posted batch.
+type batchServer struct {
+	m       sync.Mutex
+	batches []int
+	status  int
+}
+
+func (bs *batchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var graphs []interface{}
+	_ = json.NewDecoder(r.Body).Decode(&graphs)
+
+	bs.m.Lock()
+	bs.batches = append(bs.batches, len(graphs))
+	bs.m.Unlock()
+
+	if bs.status != 0 {
+		w.WriteHeader(bs.status)
+	}
+}
+
+func (bs *batchServer) posted() []int {
+	bs.m.Lock()
+	defer bs.m.Unlock()
+
+	return append([]int{}, bs.batches...)
+}
+
+func testPostHookItems(n int) []*bulk.PostHookItem {
+	items := []*bulk.PostHookItem{}
+
+	for i := 0; i < n; i++ {
+		item := testPostHookItem()
+		item.Subject = fmt.Sprintf("%s%d", item.Subject, i)
+		items = append(items, item)
+	}
+
+	return items
+}
+
+func TestPostHook_Publish_batch(t *testing.T) {
+	bs := &batchServer{}
+
+	ts := httptest.NewServer(bs)
+	defer ts.Close()
+
+	ph, err := NewPostHook("hub3", ts.URL, "", SetBatch(2, time.Hour))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	if err := ph.Publish(context.Background(), testPostHookItems(1)...); err != nil {
+		t.Fatalf("PostHook.Publish() unexpected error = %v", err)
+	}
+
+	if got := bs.posted(); len(got) != 0 {
+		t.Fatalf("PostHook.Publish() should buffer an incomplete batch; posted %v", got)
+	}
+
+	if err := ph.Publish(context.Background(), testPostHookItems(4)...); err != nil {
+		t.Fatalf("PostHook.Publish() unexpected error = %v", err)
+	}
+
+	if got := bs.posted(); fmt.Sprint(got) != "[2 2]" {
+		t.Errorf("PostHook.Publish() posted batches %v, want [2 2]", got)
+	}
+
+	if err := ph.Flush(context.Background()); err != nil {
+		t.Fatalf("PostHook.Flush() unexpected error = %v", err)
+	}
+
+	if got := bs.posted(); fmt.Sprint(got) != "[2 2 1]" {
+		t.Errorf("PostHook.Flush() posted batches %v, want [2 2 1]", got)
+	}
+}
+
+func TestPostHook_Publish_batchInterval(t *testing.T) {
+	bs := &batchServer{}
+
+	ts := httptest.NewServer(bs)
+	defer ts.Close()
+
+	ph, err := NewPostHook("hub3", ts.URL, "", SetBatch(10, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	if err := ph.Publish(context.Background(), testPostHookItems(3)...); err != nil {
+		t.Fatalf("PostHook.Publish() unexpected error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(bs.posted()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if got := bs.posted(); fmt.Sprint(got) != "[3]" {
+		t.Errorf("PostHook batch interval posted batches %v, want [3]", got)
+	}
+}
+
+func TestPostHook_Publish_batchFailure(t *testing.T) {
+	bs := &batchServer{status: http.StatusBadGateway}
+
+	ts := httptest.NewServer(bs)
+	defer ts.Close()
+
+	ph, err := NewPostHook("hub3", ts.URL, "", SetBatch(1, time.Hour))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	items := testPostHookItems(2)
+
+	err = ph.Publish(context.Background(), items...)
+
+	var postErr *PostError
+	if !errors.As(err, &postErr) {
+		t.Fatalf("PostHook.Publish() error = %v, want *PostError", err)
+	}
+
+	if len(postErr.Subjects) != 2 || postErr.Subjects[0] != items[0].Subject || postErr.Subjects[1] != items[1].Subject {
+		t.Errorf("PostError.Subjects = %v, want the subjects of both failed batches", postErr.Subjects)
+	}
+
+	if got := bs.posted(); len(got) != 2 {
+		t.Errorf("a failed batch should not stop the other batches; posted %v", got)
+	}
+}
+
+func TestPostHook_Publish_batchDeletes(t *testing.T) {
+	var (
+		m       sync.Mutex
+		methods []string
+		queries []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		methods = append(methods, r.Method)
+
+		if r.Method == http.MethodDelete {
+			queries = append(queries, r.URL.Query().Get("collection")+":"+strings.Join(r.URL.Query()["hubID"], ","))
+		}
+
+		if r.URL.Query().Get("collection") == "spec2" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	ph, err := NewPostHook("hub3", ts.URL, "", SetRetries(0), SetBatch(5, time.Hour))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	items := append(testPostHookItems(1),
+		&bulk.PostHookItem{Deleted: true, DatasetID: "spec1", HubID: "hub3_spec1_1"},
+		&bulk.PostHookItem{Deleted: true, DatasetID: "spec2", HubID: "hub3_spec2_1"},
+		&bulk.PostHookItem{Deleted: true, DatasetID: "spec1", HubID: "hub3_spec1_2"},
+	)
+
+	if err := ph.Publish(context.Background(), items...); err != nil {
+		t.Fatalf("PostHook.Publish() unexpected error = %v", err)
+	}
+
+	if len(methods) != 0 {
+		t.Fatalf("PostHook.Publish() should buffer the deleted records; sent %v", methods)
+	}
+
+	err = ph.Flush(context.Background())
+
+	var postErr *PostError
+	if !errors.As(err, &postErr) {
+		t.Fatalf("PostHook.Flush() error = %v, want *PostError", err)
+	}
+
+	if fmt.Sprint(postErr.Subjects) != "[hub3_spec2_1]" {
+		t.Errorf("PostError.Subjects = %v, want [hub3_spec2_1]", postErr.Subjects)
+	}
+
+	if fmt.Sprint(methods) != "[POST DELETE DELETE]" {
+		t.Errorf("PostHook.Flush() sent %v, want [POST DELETE DELETE]", methods)
+	}
+
+	if fmt.Sprint(queries) != "[spec1:hub3_spec1_1,hub3_spec1_2 spec2:hub3_spec2_1]" {
+		t.Errorf("PostHook.Flush() deleted %v, want the records grouped by dataset", queries)
+	}
+}
+
+func TestSetBatch_invalid(t *testing.T) {
+	options := []Option{
+		SetBatch(0, time.Second),
+		SetBatch(1, 0),
+	}
+
+	for _, option := range options {
+		if _, err := NewPostHook("hub3", "http://localhost", "", option); err == nil {
+			t.Errorf("NewPostHook() expected an error for an invalid batch option")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ginger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/delving/hub3/hub3/fragments"
+	r "github.com/kiivihal/rdf2go"
+)
+
+const ebuCore = "urn:ebu:metadata-schema:ebuCore_2014"
+
+// GraphCleaner cleans a triple before the graph is posted.
+//
+// When the GraphCleaner handles the triple, it adds the cleaned version(s) to g
+// and returns true. The remaining GraphCleaners are then skipped for this triple.
+// When no GraphCleaner handles the triple it is added to g unchanged.
+type GraphCleaner func(g *fragments.SortedGraph, t *r.Triple) bool
+
+// defaultGraphCleaners are always applied before the GraphCleaners
+// that are added with AddGraphCleaners.
+var defaultGraphCleaners = []GraphCleaner{cleanEbuCore, cleanDates}
+
+// cleanGraph applies the cleaners in order to each triple of the graph
+// and returns the cleaned graph.
+func cleanGraph(g *fragments.SortedGraph, cleaners []GraphCleaner) *fragments.SortedGraph {
+	clean := &fragments.SortedGraph{}
+
+	for _, t := range g.Triples() {
+		var handled bool
+
+		for _, cleaner := range cleaners {
+			if cleaner(clean, t) {
+				handled = true
+				break
+			}
+		}
+
+		if !handled {
+			clean.Add(t)
+		}
+	}
+
+	return clean
+}
+
+// cleanEbuCore rewrites the ebuCore URN predicates to the ebucore namespace.
+func cleanEbuCore(g *fragments.SortedGraph, t *r.Triple) bool {
+	uri := predicateURI(t)
+	if !strings.HasPrefix(uri, ebuCore) {
+		return false
+	}
+
+	uri = strings.TrimLeft(uri, ebuCore)
+	uri = strings.TrimLeft(uri, "/")
+
+	g.AddTriple(
+		t.Subject,
+		r.NewResource(fmt.Sprintf("http://www.ebu.ch/metadata/ontologies/ebucore/ebucore#%s", uri)),
+		t.Object,
+	)
+
+	return true
+}
+
+// cleanDates stores the values of the date predicates under their 'Raw' predicate.
+func cleanDates(g *fragments.SortedGraph, t *r.Triple) bool {
+	uri := predicateURI(t)
+	if _, ok := dateFields[uri]; !ok {
+		return false
+	}
+
+	// todo add code to cleanup the date formatting
+	// TODO also add the original
+	g.AddTriple(t.Subject, r.NewResource(cleanDateURI(uri)), t.Object)
+
+	return true
+}
+
+func predicateURI(t *r.Triple) string {
+	p, ok := t.Predicate.(*r.Resource)
+	if !ok {
+		return ""
+	}
+
+	return p.URI
+}
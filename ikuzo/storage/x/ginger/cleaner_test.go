@@ -0,0 +1,99 @@
+package ginger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/delving/hub3/hub3/fragments"
+	r "github.com/kiivihal/rdf2go"
+)
+
+func Test_cleanGraph(t *testing.T) {
+	subject := r.NewResource("http://data.hub3.org/resource/aggregation/spec1/123")
+
+	dropInternal := func(g *fragments.SortedGraph, t *r.Triple) bool {
+		return strings.HasPrefix(predicateURI(t), "http://internal/")
+	}
+
+	tests := []struct {
+		name      string
+		cleaners  []GraphCleaner
+		predicate string
+		want      []string
+	}{
+		{
+			"unchanged",
+			defaultGraphCleaners,
+			"http://purl.org/dc/elements/1.1/title",
+			[]string{"http://purl.org/dc/elements/1.1/title"},
+		},
+		{
+			"ebuCore",
+			defaultGraphCleaners,
+			"urn:ebu:metadata-schema:ebuCore_2014/hasMimeType",
+			[]string{"http://www.ebu.ch/metadata/ontologies/ebucore/ebucore#hasMimeType"},
+		},
+		{
+			"dates",
+			defaultGraphCleaners,
+			"http://purl.org/dc/terms/created",
+			[]string{"http://purl.org/dc/terms/createdRaw"},
+		},
+		{
+			"custom cleaner drops triple",
+			append(defaultGraphCleaners, dropInternal),
+			"http://internal/secret",
+			[]string{},
+		},
+		{
+			"custom cleaner does not handle triple",
+			append(defaultGraphCleaners, dropInternal),
+			"http://purl.org/dc/elements/1.1/title",
+			[]string{"http://purl.org/dc/elements/1.1/title"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			g := &fragments.SortedGraph{}
+			g.AddTriple(subject, r.NewResource(tt.predicate), r.NewLiteral("value"))
+
+			got := []string{}
+			for _, triple := range cleanGraph(g, tt.cleaners).Triples() {
+				got = append(got, predicateURI(triple))
+			}
+
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("cleanGraph() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddGraphCleaners(t *testing.T) {
+	var calls int
+
+	counter := func(g *fragments.SortedGraph, t *r.Triple) bool {
+		calls++
+		return false
+	}
+
+	ph, err := NewPostHook("hub3", "http://localhost", "", AddGraphCleaners(counter))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	if len(ph.cleaners) != len(defaultGraphCleaners)+1 {
+		t.Errorf("AddGraphCleaners() should keep the default cleaners; got %d cleaners", len(ph.cleaners))
+	}
+
+	if _, err := NewPostHookJob(testPostHookItem(), ph.cleaners...); err != nil {
+		t.Fatalf("NewPostHookJob() unexpected error = %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("GraphCleaner calls = %d, want 1", calls)
+	}
+}
@@ -0,0 +1,274 @@
+package ginger
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/service/x/bulk"
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var deadLetterBucket = []byte("deadletters")
+
+// DeadLetter is a record that could not be posted after all retries were exhausted.
+type DeadLetter struct {
+	ID      uint64    `json:"id"`
+	Subject string    `json:"subject"`
+	Spec    string    `json:"spec"`
+	HubID   string    `json:"hubID"`
+	URL     string    `json:"url"`
+	Error   string    `json:"error"`
+	Failed  time.Time `json:"failed"`
+	// Graph is the cleaned graph serialized as JSON-LD
+	Graph string `json:"graph"`
+}
+
+// DeadLetterQueue persists failed PostHookJobs in BoltDB so they can be replayed
+// with PostHook.RetryDeadLetters. A single queue can be shared by all PostHooks.
+type DeadLetterQueue struct {
+	db *bolt.DB
+}
+
+// NewDeadLetterQueue opens or creates the BoltDB database at path.
+func NewDeadLetterQueue(path string) (*DeadLetterQueue, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open dead-letter queue %s; %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, bucketErr := tx.CreateBucketIfNotExists(deadLetterBucket)
+		return bucketErr
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to create dead-letter bucket; %w", err)
+	}
+
+	return &DeadLetterQueue{db: db}, nil
+}
+
+// Add stores the letters and assigns their ID.
+func (q *DeadLetterQueue) Add(letters ...*DeadLetter) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(deadLetterBucket)
+
+		for _, letter := range letters {
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			letter.ID = id
+
+			v, err := json.Marshal(letter)
+			if err != nil {
+				return err
+			}
+
+			if err := b.Put(itob(id), v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// List returns the letters for the posthook URL in the order they failed.
+// When url is empty all letters are returned.
+func (q *DeadLetterQueue) List(url string) ([]*DeadLetter, error) {
+	letters := []*DeadLetter{}
+
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadLetterBucket).ForEach(func(k, v []byte) error {
+			var letter DeadLetter
+			if err := json.Unmarshal(v, &letter); err != nil {
+				return err
+			}
+
+			if url == "" || letter.URL == url {
+				letters = append(letters, &letter)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return letters, nil
+}
+
+// Remove deletes the letters in a single transaction.
+func (q *DeadLetterQueue) Remove(ids ...uint64) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(deadLetterBucket)
+
+		for _, id := range ids {
+			if err := b.Delete(itob(id)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Shutdown closes the BoltDB database.
+func (q *DeadLetterQueue) Shutdown(ctx context.Context) error {
+	return q.db.Close()
+}
+
+// HandleList renders the dead letters as JSON. The optional 'url' query
+// parameter limits the letters to a single posthook.
+func (q *DeadLetterQueue) HandleList(w http.ResponseWriter, r *http.Request) {
+	letters, err := q.List(r.URL.Query().Get("url"))
+	if err != nil {
+		log.Error().Err(err).Str("svc", "posthook").Msg("unable to list dead letters")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	render.JSON(w, r, letters)
+}
+
+// itob returns an 8-byte big endian representation of v so the keys sort by ID.
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+
+	return b
+}
+
+// SetDeadLetterQueue stores the records that could not be posted in the queue.
+// Without a queue failed records are only logged.
+func SetDeadLetterQueue(q *DeadLetterQueue) Option {
+	return func(ph *PostHook) error {
+		ph.deadLetters = q
+		return nil
+	}
+}
+
+// addDeadLetters stores the jobs that could not be posted.
+func (ph *PostHook) addDeadLetters(jobs []*PostHookJob, postErr error) {
+	if ph.deadLetters == nil {
+		return
+	}
+
+	letters := make([]*DeadLetter, 0, len(jobs))
+
+	for _, job := range jobs {
+		letters = append(letters, &DeadLetter{
+			Subject: job.item.Subject,
+			Spec:    job.item.DatasetID,
+			HubID:   job.item.HubID,
+			URL:     ph.endpoint,
+			Error:   postErr.Error(),
+			Failed:  time.Now(),
+			Graph:   job.Graph,
+		})
+	}
+
+	if err := ph.deadLetters.Add(letters...); err != nil {
+		log.Error().Err(err).
+			Str("svc", "posthook").
+			Str("url", ph.endpoint).
+			Int("records", len(letters)).
+			Msg("unable to store posthook dead letters")
+	}
+}
+
+// RetryDeadLetters posts the dead letters of the PostHook one by one.
+// The letters that are stored by the endpoint are removed from the queue in a
+// single transaction. The returned *PostError contains the subjects of the
+// letters that failed again; they remain in the queue.
+func (ph *PostHook) RetryDeadLetters(ctx context.Context) error {
+	if ph.deadLetters == nil {
+		return nil
+	}
+
+	letters, err := ph.deadLetters.List(ph.endpoint)
+	if err != nil {
+		return err
+	}
+
+	var (
+		replayed []uint64
+		failed   []*PostHookJob
+		lastErr  error
+	)
+
+	for _, letter := range letters {
+		if ctx.Err() != nil {
+			lastErr = ctx.Err()
+			break
+		}
+
+		job, jobErr := letter.job(ph.orgID)
+		if jobErr != nil {
+			log.Error().Err(jobErr).Str("svc", "posthook").Uint64("id", letter.ID).
+				Str("subject", letter.Subject).Msg("unable to decode dead letter")
+
+			continue
+		}
+
+		payload, payloadErr := ph.payload([]*PostHookJob{job})
+		if payloadErr == nil {
+			payloadErr = ph.PostWithContext(ctx, payload)
+		}
+
+		if payloadErr != nil {
+			failed = append(failed, job)
+			lastErr = payloadErr
+
+			continue
+		}
+
+		replayed = append(replayed, letter.ID)
+	}
+
+	if err := ph.deadLetters.Remove(replayed...); err != nil {
+		return fmt.Errorf("unable to remove replayed dead letters; %w", err)
+	}
+
+	log.Info().
+		Str("svc", "posthook").
+		Str("url", ph.endpoint).
+		Int("replayed", len(replayed)).
+		Int("failed", len(failed)).
+		Msg("retried posthook dead letters")
+
+	if lastErr != nil {
+		return newPostError(failed, lastErr)
+	}
+
+	return nil
+}
+
+// job restores the PostHookJob from the stored graph.
+func (letter *DeadLetter) job(orgID string) (*PostHookJob, error) {
+	job := &PostHookJob{
+		item: &bulk.PostHookItem{
+			Subject:   letter.Subject,
+			OrgID:     orgID,
+			DatasetID: letter.Spec,
+			HubID:     letter.HubID,
+		},
+		Graph: letter.Graph,
+	}
+
+	if err := json.Unmarshal([]byte(letter.Graph), &job.jsonld); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
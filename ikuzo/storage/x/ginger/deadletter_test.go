@@ -0,0 +1,128 @@
+package ginger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPostHook_RetryDeadLetters(t *testing.T) {
+	var down int32 = 1
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	dlq, err := NewDeadLetterQueue(filepath.Join(t.TempDir(), "deadletters.db"))
+	if err != nil {
+		t.Fatalf("NewDeadLetterQueue() unexpected error = %v", err)
+	}
+	defer dlq.Shutdown(context.Background())
+
+	ph, err := NewPostHook("hub3", ts.URL, "", SetRetries(0), SetDeadLetterQueue(dlq))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	item := testPostHookItem()
+
+	err = ph.Publish(context.Background(), item)
+	if err == nil {
+		t.Fatalf("PostHook.Publish() expected error when the endpoint is down")
+	}
+
+	letters, err := dlq.List(ts.URL)
+	if err != nil {
+		t.Fatalf("DeadLetterQueue.List() unexpected error = %v", err)
+	}
+
+	if len(letters) != 1 {
+		t.Fatalf("DeadLetterQueue.List() got %d letters, want 1", len(letters))
+	}
+
+	letter := letters[0]
+	if letter.Subject != item.Subject || letter.Spec != item.DatasetID || letter.URL != ts.URL || letter.Error == "" {
+		t.Errorf("DeadLetterQueue.List() unexpected letter %#v", letter)
+	}
+
+	// the letters can be inspected through the endpoint
+	rec := httptest.NewRecorder()
+	dlq.HandleList(rec, httptest.NewRequest(http.MethodGet, "/api/posthook/deadletters", nil))
+
+	var listed []*DeadLetter
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("DeadLetterQueue.HandleList() invalid json; %s", err)
+	}
+
+	if len(listed) != 1 || listed[0].ID != letter.ID {
+		t.Errorf("DeadLetterQueue.HandleList() = %s, want letter %d", rec.Body.String(), letter.ID)
+	}
+
+	// the letters remain in the queue while the endpoint is down
+	var postErr *PostError
+
+	err = ph.RetryDeadLetters(context.Background())
+	if !errors.As(err, &postErr) || len(postErr.Subjects) != 1 {
+		t.Errorf("PostHook.RetryDeadLetters() error = %v, want *PostError with 1 subject", err)
+	}
+
+	if letters, _ = dlq.List(ts.URL); len(letters) != 1 {
+		t.Errorf("DeadLetterQueue.List() got %d letters, want 1", len(letters))
+	}
+
+	// replayed letters are removed from the queue
+	atomic.StoreInt32(&down, 0)
+
+	if err := ph.RetryDeadLetters(context.Background()); err != nil {
+		t.Errorf("PostHook.RetryDeadLetters() unexpected error = %v", err)
+	}
+
+	if letters, _ = dlq.List(""); len(letters) != 0 {
+		t.Errorf("DeadLetterQueue.List() got %d letters, want 0", len(letters))
+	}
+}
+
+func TestDeadLetterQueue_List(t *testing.T) {
+	dlq, err := NewDeadLetterQueue(filepath.Join(t.TempDir(), "deadletters.db"))
+	if err != nil {
+		t.Fatalf("NewDeadLetterQueue() unexpected error = %v", err)
+	}
+	defer dlq.Shutdown(context.Background())
+
+	err = dlq.Add(
+		&DeadLetter{Subject: "1", URL: "http://a", Failed: time.Now()},
+		&DeadLetter{Subject: "2", URL: "http://b", Failed: time.Now()},
+		&DeadLetter{Subject: "3", URL: "http://a", Failed: time.Now()},
+	)
+	if err != nil {
+		t.Fatalf("DeadLetterQueue.Add() unexpected error = %v", err)
+	}
+
+	letters, err := dlq.List("http://a")
+	if err != nil {
+		t.Fatalf("DeadLetterQueue.List() unexpected error = %v", err)
+	}
+
+	if len(letters) != 2 || letters[0].Subject != "1" || letters[1].Subject != "3" {
+		t.Errorf("DeadLetterQueue.List() returned the wrong letters: %#v", letters)
+	}
+
+	if err := dlq.Remove(letters[0].ID); err != nil {
+		t.Fatalf("DeadLetterQueue.Remove() unexpected error = %v", err)
+	}
+
+	if letters, _ = dlq.List(""); len(letters) != 2 {
+		t.Errorf("DeadLetterQueue.List() got %d letters after Remove, want 2", len(letters))
+	}
+}
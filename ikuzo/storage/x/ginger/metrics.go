@@ -1,10 +1,55 @@
 package ginger
 
 import (
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Results of a posthook delivery in the posthook_posts_total metric.
+const (
+	resultAttempted = "attempted"
+	resultSucceeded = "succeeded"
+	resultFailed    = "failed"
 )
 
+var (
+	postHookPosts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "posthook_posts_total",
+			Help: "How many posthook deliveries are attempted, succeeded or failed, partitioned by URL and result.",
+		},
+		[]string{"url", "result"},
+	)
+
+	postHookLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "posthook_request_duration_milliseconds",
+			Help:    "How long the posthook requests took, partitioned by URL and status code.",
+			Buckets: []float64{100, 300, 1200, 5000, 15000},
+		},
+		[]string{"url", "code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(postHookPosts, postHookLatency)
+}
+
+// observeRequest records the latency of a single posthook request.
+// The code is 'error' when no response was received.
+func observeRequest(url string, statusCode int, start time.Time) {
+	code := "error"
+	if statusCode != 0 {
+		code = strconv.Itoa(statusCode)
+	}
+
+	postHookLatency.WithLabelValues(url, code).
+		Observe(float64(time.Since(start)) / float64(time.Millisecond))
+}
+
 type PostHookCounter struct {
 	ToIndex           int  `json:"toIndex"`
 	ToDelete          int  `json:"toDelete"`
@@ -0,0 +1,81 @@
+package ginger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestPostHook_metrics(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	ph, err := NewPostHook("hub3", ts.URL, "", SetRetryDelay(time.Millisecond), SetRetries(1))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	// first delivery succeeds after one retry
+	if err := ph.PostWithContext(context.Background(), []byte("[]")); err != nil {
+		t.Fatalf("PostHook.PostWithContext() unexpected error = %v", err)
+	}
+
+	// second delivery fails when the retries are exhausted
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if err := ph.PostWithContext(context.Background(), []byte("[]")); err == nil {
+		t.Fatalf("PostHook.PostWithContext() expected error")
+	}
+
+	counts := map[string]float64{
+		resultAttempted: 2,
+		resultSucceeded: 1,
+		resultFailed:    1,
+	}
+
+	for result, want := range counts {
+		got := testutil.ToFloat64(postHookPosts.WithLabelValues(ts.URL, result))
+		if got != want {
+			t.Errorf("posthook_posts_total{result=%q} = %v, want %v", result, got, want)
+		}
+	}
+
+	requests := map[string]uint64{
+		"200": 1,
+		"500": 3,
+	}
+
+	for code, want := range requests {
+		var m dto.Metric
+
+		histogram, ok := postHookLatency.WithLabelValues(ts.URL, code).(prometheus.Histogram)
+		if !ok {
+			t.Fatalf("posthook_request_duration_milliseconds should be a histogram")
+		}
+
+		if err := histogram.Write(&m); err != nil {
+			t.Fatalf("unable to write histogram; %s", err)
+		}
+
+		if got := m.GetHistogram().GetSampleCount(); got != want {
+			t.Errorf("posthook_request_duration_milliseconds{code=%q} count = %d, want %d", code, got, want)
+		}
+	}
+}
@@ -1,18 +1,59 @@
 package ginger
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/delving/hub3/ikuzo/service/x/bulk"
-	"github.com/parnurzeal/gorequest"
+	r "github.com/kiivihal/rdf2go"
 	"github.com/rs/zerolog/log"
 )
 
+// Supported serialization formats of the PostHook payload
+const (
+	FormatJSONLD   = "jsonld"
+	FormatNTriples = "ntriples"
+	FormatTurtle   = "turtle"
+)
+
+// SignatureHeader holds the HMAC-SHA256 signature of the request payload
+// when a secret is configured.
+const SignatureHeader = "X-Hub-Signature"
+
+var formatContentTypes = map[string]string{
+	FormatJSONLD:   "application/json-ld; charset=utf-8",
+	FormatNTriples: "application/n-triples; charset=utf-8",
+	FormatTurtle:   "text/turtle; charset=utf-8",
+}
+
+const (
+	defaultRetries    = 3
+	defaultRetryDelay = 5 * time.Second
+)
+
+// defaultRetryStatusCodes are the response status codes that are retried by default.
+var defaultRetryStatusCodes = []int{http.StatusBadRequest, http.StatusInternalServerError}
+
+// defaultDeleteStatusCodes are the response status codes of a delete that are
+// treated as success by default. 404 and 410 mean that the dataset or record
+// was already deleted, so replaying a delete is idempotent.
+var defaultDeleteStatusCodes = []int{
+	http.StatusOK, http.StatusAccepted, http.StatusNoContent,
+	http.StatusNotFound, http.StatusGone,
+}
+
 // compile time check to see if full interface is implemented
 var _ bulk.PostHookService = (*PostHook)(nil)
 
@@ -29,19 +70,171 @@ type PostHook struct {
 	excludedDataSets []string
 	apiKey           string
 	gauge            PostHookGauge
+	client           *http.Client
+	retries          int
+	retryDelay       time.Duration
+	retryStatusCodes []int
+	deleteCodes      []int
+	format           string
+	secret           string
+	cleaners         []GraphCleaner
+	batch            batch
+	deadLetters      *DeadLetterQueue
+	gzip             bool
 }
 
-func NewPostHook(orgID, endpoint, apiKey string, excludedDataSets ...string) *PostHook {
-	return &PostHook{
+// Option configures the PostHook.
+type Option func(*PostHook) error
+
+func NewPostHook(orgID, endpoint, apiKey string, options ...Option) (*PostHook, error) {
+	ph := &PostHook{
 		orgID:            orgID,
 		endpoint:         endpoint,
-		excludedDataSets: excludedDataSets,
 		apiKey:           apiKey,
+		client:           &http.Client{},
+		retries:          defaultRetries,
+		retryDelay:       defaultRetryDelay,
+		retryStatusCodes: defaultRetryStatusCodes,
+		deleteCodes:      defaultDeleteStatusCodes,
+		format:           FormatJSONLD,
+		cleaners:         defaultGraphCleaners,
 		gauge: PostHookGauge{
 			Created:  time.Now(),
 			Counters: make(map[string]*PostHookCounter),
 		},
 	}
+
+	for _, option := range options {
+		if err := option(ph); err != nil {
+			return nil, err
+		}
+	}
+
+	return ph, nil
+}
+
+// SetExcludedDataSets sets the datasets that are not published to the endpoint.
+func SetExcludedDataSets(datasetIDs ...string) Option {
+	return func(ph *PostHook) error {
+		ph.excludedDataSets = datasetIDs
+		return nil
+	}
+}
+
+// SetRetries sets how often a failed post is retried. Zero disables retrying.
+// The default is 3 retries.
+func SetRetries(retries int) Option {
+	return func(ph *PostHook) error {
+		if retries < 0 {
+			return fmt.Errorf("posthook retries must not be negative: %d", retries)
+		}
+
+		ph.retries = retries
+
+		return nil
+	}
+}
+
+// SetRetryDelay sets the delay before the first retry. The default is 5 seconds.
+//
+// The delay between retries increases exponentially and is randomized by 50%
+// to avoid that all failed posts are retried at the same time.
+func SetRetryDelay(delay time.Duration) Option {
+	return func(ph *PostHook) error {
+		if delay <= 0 {
+			return fmt.Errorf("posthook retry delay must be positive: %s", delay)
+		}
+
+		ph.retryDelay = delay
+
+		return nil
+	}
+}
+
+// SetRetryStatusCodes sets the response status codes that are retried.
+// Network errors are always retried. The default codes are 400 and 500.
+func SetRetryStatusCodes(statusCodes ...int) Option {
+	return func(ph *PostHook) error {
+		ph.retryStatusCodes = statusCodes
+		return nil
+	}
+}
+
+// SetDeleteStatusCodes sets the response status codes that are treated as a
+// successful delete. The default codes are 200, 202, 204, 404 and 410.
+// Other status codes are returned as an error by Publish.
+func SetDeleteStatusCodes(statusCodes ...int) Option {
+	return func(ph *PostHook) error {
+		ph.deleteCodes = statusCodes
+		return nil
+	}
+}
+
+// SetFormat sets the serialization format of the posted records.
+// The supported formats are 'jsonld' (default), 'ntriples' and 'turtle'.
+func SetFormat(format string) Option {
+	return func(ph *PostHook) error {
+		if _, ok := formatContentTypes[format]; !ok {
+			return fmt.Errorf("unsupported posthook format: %q", format)
+		}
+
+		ph.format = format
+
+		return nil
+	}
+}
+
+// SetSecret enables signing of the requests with the shared secret.
+//
+// The SignatureHeader of a POST contains 'sha256=' followed by the hex encoded
+// HMAC-SHA256 of the exact body that is sent. For a DELETE the encoded query
+// string, e.g. 'api_key=key&collection=spec', is signed.
+func SetSecret(secret string) Option {
+	return func(ph *PostHook) error {
+		ph.secret = secret
+		return nil
+	}
+}
+
+// SetGzip compresses the posted records with gzip and sets the
+// 'Content-Encoding: gzip' header. The signature of SetSecret is calculated
+// on the uncompressed payload.
+func SetGzip(enabled bool) Option {
+	return func(ph *PostHook) error {
+		ph.gzip = enabled
+		return nil
+	}
+}
+
+// AddGraphCleaners adds GraphCleaners that are applied in order after the
+// default cleaners for ebuCore and date predicates.
+func AddGraphCleaners(cleaners ...GraphCleaner) Option {
+	return func(ph *PostHook) error {
+		ph.cleaners = append(append([]GraphCleaner{}, ph.cleaners...), cleaners...)
+		return nil
+	}
+}
+
+// SetBatch buffers the records and posts them in batches of size records.
+// The deleted records are buffered in the same batch and dropped with a list
+// of hubIDs per dataset. A batch that is not full is posted when the interval
+// has expired since the first record was added to it. Batching is disabled by
+// default, so each call to Publish posts its records directly.
+func SetBatch(size int, interval time.Duration) Option {
+	return func(ph *PostHook) error {
+		if size < 1 {
+			return fmt.Errorf("posthook batch size must be at least 1: %d", size)
+		}
+
+		if interval <= 0 {
+			return fmt.Errorf("posthook batch interval must be positive: %s", interval)
+		}
+
+		ph.batch.size = size
+		ph.batch.interval = interval
+
+		return nil
+	}
 }
 
 func (ph *PostHook) OrgID() string {
@@ -49,6 +242,36 @@ func (ph *PostHook) OrgID() string {
 }
 
 func (ph *PostHook) DropDataset(dataset string, revision int) (resp *http.Response, err error) {
+	q := url.Values{}
+	q.Add("collection", dataset)
+
+	if revision > 0 {
+		q.Add("rev", fmt.Sprintf("%d", revision))
+	}
+
+	return ph.delete(q)
+}
+
+// DropRecord removes a single record of the dataset from the endpoint.
+func (ph *PostHook) DropRecord(dataset, hubID string) (resp *http.Response, err error) {
+	return ph.DropRecords(dataset, hubID)
+}
+
+// DropRecords removes the records of the dataset from the endpoint in a single
+// request. The hubIDs are sent as a list of 'hubID' query parameters.
+func (ph *PostHook) DropRecords(dataset string, hubIDs ...string) (resp *http.Response, err error) {
+	q := url.Values{}
+	q.Add("collection", dataset)
+
+	for _, hubID := range hubIDs {
+		q.Add("hubID", hubID)
+	}
+
+	return ph.delete(q)
+}
+
+// delete sends a DELETE request with the query parameters to the endpoint.
+func (ph *PostHook) delete(params url.Values) (*http.Response, error) {
 	req, err := http.NewRequest("DELETE", ph.endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -56,15 +279,15 @@ func (ph *PostHook) DropDataset(dataset string, revision int) (resp *http.Respon
 
 	q := req.URL.Query()
 	q.Add("api_key", ph.apiKey)
-	q.Add("collection", dataset)
 
-	if revision > 0 {
-		q.Add("rev", fmt.Sprintf("%d", revision))
+	for k, v := range params {
+		q[k] = v
 	}
 
 	req.URL.RawQuery = q.Encode()
 
 	req.Header.Set("Content-Type", "application/json")
+	ph.sign(req, []byte(req.URL.RawQuery))
 
 	var netClient = &http.Client{
 		Timeout: time.Second * 15,
@@ -87,38 +310,38 @@ func (ph *PostHook) Valid(datasetID string) bool {
 	return true
 }
 
-func (ph *PostHook) Publish(items ...*bulk.PostHookItem) error {
+// Publish posts the records and drops the deleted records and datasets.
+//
+// When batching is enabled the records and the deleted records are buffered
+// and only sent when the batch is full or the flush interval expires. See
+// SetBatch. The deleted datasets are always dropped directly.
+func (ph *PostHook) Publish(ctx context.Context, items ...*bulk.PostHookItem) error {
 	jobs := []*PostHookJob{}
+	dropped := map[string]bool{}
 
 	for _, item := range items {
+		if item.Deleted && item.HubID != "" {
+			jobs = append(jobs, &PostHookJob{item: item})
+			continue
+		}
+
 		if item.Deleted {
-			resp, err := ph.DropDataset(item.DatasetID, item.Revision)
-			if err != nil {
-				log.Error().Err(err).Str("datasetID", item.DatasetID).Msg("unable to drop posthook dataset")
-				return err
+			// a dataset only needs to be dropped once per revision
+			key := fmt.Sprintf("%s/%d", item.DatasetID, item.Revision)
+			if dropped[key] {
+				continue
 			}
 
-			if resp.StatusCode > 299 {
-				defer resp.Body.Close()
-				body, readErr := ioutil.ReadAll(resp.Body)
-
-				if readErr != nil {
-					log.Error().Err(err).Str("datasetID", item.DatasetID).
-						Msg("unable to read posthook body")
-				}
+			dropped[key] = true
 
-				log.Error().Err(err).
-					Str("body", string(body)).
-					Int("revision", item.Revision).
-					Int("status_code", resp.StatusCode).
-					Str("datasetID", item.DatasetID).
-					Msg("unable to drop posthook dataset")
+			if err := ph.dropDataset(item); err != nil {
+				return err
 			}
 
 			continue
 		}
 
-		ph, err := NewPostHookJob(item)
+		ph, err := NewPostHookJob(item, ph.cleaners...)
 		if err != nil {
 			return err
 		}
@@ -130,69 +353,355 @@ func (ph *PostHook) Publish(items ...*bulk.PostHookItem) error {
 		return nil
 	}
 
-	request := gorequest.New()
+	if ph.batch.size > 0 {
+		return ph.addToBatch(ctx, jobs)
+	}
+
+	return ph.postJobs(ctx, jobs)
+}
+
+func (ph *PostHook) dropDataset(item *bulk.PostHookItem) error {
+	resp, err := ph.DropDataset(item.DatasetID, item.Revision)
+
+	return ph.checkDelete(resp, err, item.DatasetID, item.Revision, nil)
+}
+
+// dropRecords drops the deleted records of the jobs with a single request per
+// dataset. The returned *PostError contains the subjects of the records that
+// were not deleted.
+func (ph *PostHook) dropRecords(jobs []*PostHookJob) error {
+	var failed *PostError
+
+	for _, spec := range jobSpecs(jobs) {
+		dropped := []*PostHookJob{}
+		hubIDs := []string{}
+
+		for _, job := range jobs {
+			if job.item.DatasetID == spec {
+				dropped = append(dropped, job)
+				hubIDs = append(hubIDs, job.item.HubID)
+			}
+		}
+
+		resp, err := ph.DropRecords(spec, hubIDs...)
+		if err := ph.checkDelete(resp, err, spec, 0, hubIDs); err != nil {
+			failed, _ = joinPostErrors(failed, newPostError(dropped, err))
+		}
+	}
+
+	if failed == nil {
+		return nil
+	}
+
+	return failed
+}
+
+// checkDelete returns an error when the delete response status is not one of
+// the configured delete status codes.
+func (ph *PostHook) checkDelete(resp *http.Response, err error, spec string, revision int, hubIDs []string) error {
+	if err != nil {
+		log.Error().Err(err).
+			Str("svc", "posthook").
+			Str("url", ph.endpoint).
+			Str("spec", spec).
+			Strs("hubIDs", hubIDs).
+			Msg("unable to delete from posthook")
+
+		return err
+	}
+	defer resp.Body.Close()
+
+	if containsInt(ph.deleteCodes, resp.StatusCode) {
+		if resp.StatusCode > 299 {
+			log.Debug().
+				Str("svc", "posthook").
+				Str("url", ph.endpoint).
+				Int("status", resp.StatusCode).
+				Str("spec", spec).
+				Strs("hubIDs", hubIDs).
+				Msg("posthook delete was already applied")
+		}
+
+		return nil
+	}
+
+	body, readErr := ioutil.ReadAll(resp.Body)
+	if readErr != nil {
+		log.Error().Err(readErr).Str("svc", "posthook").Str("spec", spec).
+			Msg("unable to read posthook body")
+	}
+
+	log.Error().
+		Str("svc", "posthook").
+		Str("url", ph.endpoint).
+		Str("body", string(body)).
+		Int("revision", revision).
+		Int("status", resp.StatusCode).
+		Str("spec", spec).
+		Strs("hubIDs", hubIDs).
+		Msg("unable to delete from posthook")
+
+	return fmt.Errorf("unable to delete %s from endpoint %s (status %d)", spec, ph.endpoint, resp.StatusCode)
+}
+
+// postJobs sends the jobs in order. Consecutive records are posted in a single
+// request and consecutive deleted records are dropped in a single request per
+// dataset. The returned *PostError contains the subjects of all the jobs that
+// were not stored or deleted.
+func (ph *PostHook) postJobs(ctx context.Context, jobs []*PostHookJob) error {
+	var failed *PostError
+
+	for len(jobs) > 0 {
+		end := 1
+		for end < len(jobs) && jobs[end].item.Deleted == jobs[0].item.Deleted {
+			end++
+		}
+
+		var err error
+
+		if jobs[0].item.Deleted {
+			err = ph.dropRecords(jobs[:end])
+		} else {
+			err = ph.postRecords(ctx, jobs[:end])
+		}
+
+		if failed, err = joinPostErrors(failed, err); err != nil {
+			return err
+		}
+
+		jobs = jobs[end:]
+	}
+
+	if failed == nil {
+		return nil
+	}
+
+	return failed
+}
+
+// postRecords posts the records of the jobs in a single request.
+// The returned *PostError contains the subjects of the jobs that were not stored.
+func (ph *PostHook) postRecords(ctx context.Context, jobs []*PostHookJob) error {
+	payload, err := ph.payload(jobs)
+	if err != nil {
+		return newPostError(jobs, err)
+	}
+
+	if err := ph.PostWithContext(ctx, payload); err != nil {
+		postErr := newPostError(jobs, err)
+
+		log.Error().Err(err).
+			Str("svc", "posthook").
+			Str("url", ph.endpoint).
+			Strs("subjects", postErr.Subjects).
+			Strs("specs", jobSpecs(jobs)).
+			Msg("unable to store posthook items")
+		log.Debug().Str("svc", "posthook").Str("format", ph.format).Bytes("payload", payload).Msg("posthook payload")
+
+		ph.addDeadLetters(jobs, err)
+
+		return postErr
+	}
+
+	log.Info().
+		Str("svc", "posthook").
+		Str("url", ph.endpoint).
+		Strs("specs", jobSpecs(jobs)).
+		Int("bulkItems", len(jobs)).
+		Msg("Stored posthook items for ginger")
+
+	return nil
+}
+
+// jobSpecs returns the unique datasets of the jobs.
+func jobSpecs(jobs []*PostHookJob) []string {
+	specs := []string{}
 
-	bulkGraphs := []interface{}{}
 	for _, job := range jobs {
-		// gauge.Queue(ph)
-		bulkGraphs = append(bulkGraphs, job.jsonld)
+		if !containsString(specs, job.item.DatasetID) {
+			specs = append(specs, job.item.DatasetID)
+		}
 	}
 
-	graphsAsJSON, err := json.Marshal(bulkGraphs)
+	return specs
+}
+
+// payload serializes the jobs in the format of the PostHook.
+// JSON-LD jobs are posted as a JSON array of graphs. The RDF formats are concatenated.
+func (ph *PostHook) payload(jobs []*PostHookJob) ([]byte, error) {
+	if ph.format == FormatJSONLD {
+		bulkGraphs := []interface{}{}
+		for _, job := range jobs {
+			bulkGraphs = append(bulkGraphs, job.jsonld)
+		}
+
+		return json.Marshal(bulkGraphs)
+	}
+
+	var buf bytes.Buffer
+
+	for _, job := range jobs {
+		b, err := job.BytesFormat(ph.format)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(b)
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// PostWithContext sends the body to the endpoint.
+// Network errors and the configured status codes are retried with an exponential backoff.
+// When ctx is cancelled the running request and the remaining retries are aborted.
+func (ph *PostHook) PostWithContext(ctx context.Context, body []byte) error {
+	data := body
+
+	if ph.gzip {
+		var err error
+
+		data, err = gzipBytes(body)
+		if err != nil {
+			return fmt.Errorf("unable to gzip posthook payload; %w", err)
+		}
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = ph.retryDelay
+	b.Multiplier = 2
+	b.MaxElapsedTime = 0
+
+	operation := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ph.endpoint, bytes.NewReader(data))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+
+		q := req.URL.Query()
+		q.Add("api_key", ph.apiKey)
+		req.URL.RawQuery = q.Encode()
+
+		req.Header.Set("Content-Type", formatContentTypes[ph.format])
+
+		if ph.gzip {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+
+		ph.sign(req, body)
+
+		start := time.Now()
+
+		resp, err := ph.client.Do(req)
+		if err != nil {
+			observeRequest(ph.endpoint, 0, start)
+			return err
+		}
+		defer resp.Body.Close()
+
+		observeRequest(ph.endpoint, resp.StatusCode, start)
+
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		err = fmt.Errorf("unable to save to endpoint %s (status %d);\n %s", ph.endpoint, resp.StatusCode, respBody)
+
+		log.Warn().
+			Str("svc", "posthook").
+			Str("url", ph.endpoint).
+			Int("status", resp.StatusCode).
+			Msg("posthook request failed")
+
+		if !ph.isRetryStatus(resp.StatusCode) {
+			return backoff.Permanent(err)
+		}
+
+		return err
+	}
+
+	notify := func(err error, next time.Duration) {
+		log.Warn().Err(err).Str("svc", "posthook").Str("url", ph.endpoint).Dur("retryIn", next).Msg("retrying posthook")
+	}
+
+	postHookPosts.WithLabelValues(ph.endpoint, resultAttempted).Inc()
+
+	// nolint:gosec // retries is validated to be non-negative
+	err := backoff.RetryNotify(
+		operation,
+		backoff.WithContext(backoff.WithMaxRetries(b, uint64(ph.retries)), ctx),
+		notify,
+	)
 	if err != nil {
+		postHookPosts.WithLabelValues(ph.endpoint, resultFailed).Inc()
 		return err
 	}
 
-	rsp, body, errs := request.Post(ph.endpoint).
-		Set("Content-Type", "application/json-ld; charset=utf-8").
-		Query(fmt.Sprintf("api_key=%s", ph.apiKey)).
-		Type("text").
-		Send(string(graphsAsJSON)).
-		End()
-
-	//fmt.Printf("jsonld: %s\n", json)
-	// log.Printf("post-response: %#v -> %#v\n %#v", rsp, body, errs)
-	if errs != nil || rsp.StatusCode != http.StatusOK {
-		// log.Error().Str("apiKey", ph.apiKey).Msgf("post-response: %#v -> %#v\n %#v", rsp, body, errs)
-		// log.Error().Msgf("Unable to store: %#v\n", errs)
-		log.Error().Msgf("JSON-LD: %s\n", graphsAsJSON)
-		// log.Error().Msgf("bulk: %s\n", bulk)
-		// for _, job := range jobs {
-		// err := gauge.Error(job)
-		// if err != nil {
-		// return err
-		// }
-		// }
-
-		return fmt.Errorf("unable to save to endpoint %s;\n %s", ph.endpoint, body)
-	}
-
-	log.Info().Str("svc", "posthook").Int("bulkItems", len(bulkGraphs)).Msg("Stored posthook items for ginger")
-	// for _, job := range jobs {
-	// err := gauge.Done(job)
-	// if err != nil {
-	// return err
-	// }
-	// }
+	postHookPosts.WithLabelValues(ph.endpoint, resultSucceeded).Inc()
 
 	return nil
 }
 
-// NewPostHookJob creates a new PostHookJob and populates the rdf2go Graph
-func NewPostHookJob(item *bulk.PostHookItem) (*PostHookJob, error) {
+func gzipBytes(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// sign sets the SignatureHeader when a secret is configured.
+func (ph *PostHook) sign(req *http.Request, payload []byte) {
+	if ph.secret == "" {
+		return
+	}
+
+	req.Header.Set(SignatureHeader, Signature(ph.secret, payload))
+}
+
+// Signature returns the value of the SignatureHeader for the payload.
+// Downstream services can use it to verify the signature of a request.
+func Signature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (ph *PostHook) isRetryStatus(statusCode int) bool {
+	return containsInt(ph.retryStatusCodes, statusCode)
+}
+
+// NewPostHookJob creates a new PostHookJob and populates the rdf2go Graph.
+// The graph is cleaned by the GraphCleaners. When none are given the default
+// cleaners for ebuCore and date predicates are applied.
+func NewPostHookJob(item *bulk.PostHookItem, cleaners ...GraphCleaner) (*PostHookJob, error) {
 	ph := &PostHookJob{
 		item: item,
 	}
 
+	if len(cleaners) == 0 {
+		cleaners = defaultGraphCleaners
+	}
+
 	if !ph.item.Deleted {
 		// setup the cleanup
-		err := ph.parseJSONLD()
+		err := ph.parseJSONLD(cleaners)
 		if err != nil {
 			return nil, err
 		}
 
 		ph.addNarthexDefaults(ph.item.HubID)
-		ph.cleanPostHookGraph()
 		// log.Info().Msgf("ph.jsonld %#v", ph.jsonld)
 
 		err = ph.updateJSONLD()
@@ -204,6 +713,44 @@ func NewPostHookJob(item *bulk.PostHookItem) (*PostHookJob, error) {
 	return ph, nil
 }
 
+// Bytes returns the cleaned graph as flat JSON-LD.
+func (ph *PostHookJob) Bytes() []byte {
+	return []byte(ph.Graph)
+}
+
+// String returns the cleaned graph as flat JSON-LD.
+func (ph *PostHookJob) String() string {
+	return ph.Graph
+}
+
+// BytesFormat returns the cleaned graph serialized in the format.
+// The supported formats are 'jsonld', 'ntriples' and 'turtle'.
+func (ph *PostHookJob) BytesFormat(format string) ([]byte, error) {
+	switch format {
+	case FormatJSONLD:
+		return ph.Bytes(), nil
+	case FormatNTriples, FormatTurtle:
+	default:
+		return nil, fmt.Errorf("unsupported posthook format: %q", format)
+	}
+
+	g := r.NewGraph("")
+	if err := g.Parse(strings.NewReader(ph.Graph), "application/ld+json"); err != nil {
+		return nil, fmt.Errorf("unable to parse posthook JSON-LD; %w", err)
+	}
+
+	if format == FormatNTriples {
+		return []byte(g.String()), nil
+	}
+
+	var buf bytes.Buffer
+	if err := g.Serialize(&buf, "text/turtle"); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 func (ph *PostHookJob) updateJSONLD() error {
 	b, err := json.Marshal(ph.jsonld)
 	if err != nil {
@@ -215,8 +762,8 @@ func (ph *PostHookJob) updateJSONLD() error {
 	return nil
 }
 
-func (ph *PostHookJob) parseJSONLD() error {
-	jsonld, err := ph.item.Graph.GenerateJSONLD()
+func (ph *PostHookJob) parseJSONLD(cleaners []GraphCleaner) error {
+	jsonld, err := cleanGraph(ph.item.Graph, cleaners).GenerateJSONLD()
 	if err != nil {
 		return err
 	}
@@ -226,42 +773,14 @@ func (ph *PostHookJob) parseJSONLD() error {
 	return nil
 }
 
-// cleanPostHookGraph applies post hook clean actions to the graph
-func (ph *PostHookJob) cleanPostHookGraph() {
-	cleanMap := []map[string]interface{}{}
-
-	for _, rsc := range ph.jsonld {
-		cleanEntry := make(map[string]interface{})
-
-		ebuCore := "urn:ebu:metadata-schema:ebuCore_2014"
-
-		for uri, v := range rsc {
-			if strings.HasPrefix(uri, ebuCore) {
-				uri = strings.TrimLeft(uri, ebuCore)
-				uri = strings.TrimLeft(uri, "/")
-				uri = fmt.Sprintf("http://www.ebu.ch/metadata/ontologies/ebucore/ebucore#%s", uri)
-			}
-
-			var dateURI string
-
-			if _, ok := dateFields[uri]; ok {
-				dateURI = cleanDateURI(uri)
-			}
-
-			if dateURI != "" {
-				// todo add code to cleanup the date formatting
-				// TODO also add the original
-				cleanEntry[dateURI] = v
-			} else {
-				// insert the uri original URI and raw value
-				cleanEntry[uri] = v
-			}
+func containsInt(s []int, e int) bool {
+	for _, a := range s {
+		if a == e {
+			return true
 		}
-
-		cleanMap = append(cleanMap, cleanEntry)
 	}
 
-	ph.jsonld = cleanMap
+	return false
 }
 
 func containsString(s []string, e string) bool {
@@ -275,7 +794,8 @@ func containsString(s []string, e string) bool {
 }
 
 func (ph *PostHookJob) addNarthexDefaults(hubID string) {
-	parts := strings.Split(hubID, "_")
+	// the localID is everything after the spec and can contain underscores
+	parts := strings.SplitN(hubID, "_", 3)
 	localID := parts[2]
 	subject := ph.item.Subject + "/about"
 
@@ -1,5 +1,509 @@
 package ginger
 
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/ikuzo/service/x/bulk"
+	r "github.com/kiivihal/rdf2go"
+)
+
+func TestPostHook_PostWithContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   []Option
+		responses []int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{
+			"success",
+			nil,
+			[]int{http.StatusOK},
+			1,
+			false,
+		},
+		{
+			"retry default status code",
+			nil,
+			[]int{http.StatusInternalServerError, http.StatusOK},
+			2,
+			false,
+		},
+		{
+			"no retry for other status codes",
+			nil,
+			[]int{http.StatusBadGateway, http.StatusOK},
+			1,
+			true,
+		},
+		{
+			"configured status codes",
+			[]Option{SetRetryStatusCodes(http.StatusBadGateway)},
+			[]int{http.StatusBadGateway, http.StatusOK},
+			2,
+			false,
+		},
+		{
+			"retries exhausted",
+			[]Option{SetRetries(2)},
+			[]int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			3,
+			true,
+		},
+		{
+			"retries disabled",
+			[]Option{SetRetries(0)},
+			[]int{http.StatusInternalServerError, http.StatusOK},
+			1,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				call := atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tt.responses[call-1])
+			}))
+			defer ts.Close()
+
+			options := append([]Option{SetRetryDelay(time.Millisecond)}, tt.options...)
+
+			ph, err := NewPostHook("hub3", ts.URL, "secret", options...)
+			if err != nil {
+				t.Fatalf("NewPostHook() unexpected error = %v", err)
+			}
+
+			err = ph.PostWithContext(context.Background(), []byte("[]"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PostHook.PostWithContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("PostHook.PostWithContext() calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPostHook_PostWithContext_cancel(t *testing.T) {
+	var calls int32
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		cancel()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	ph, err := NewPostHook("hub3", ts.URL, "", SetRetryDelay(time.Hour))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	done := make(chan error)
+
+	go func() {
+		done <- ph.PostWithContext(ctx, []byte("[]"))
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("PostHook.PostWithContext() should not wait for the retry delay after cancel")
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("PostHook.PostWithContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("PostHook.PostWithContext() calls = %d, want 1", got)
+	}
+}
+
+func testPostHookItem() *bulk.PostHookItem {
+	subject := "http://data.hub3.org/resource/aggregation/spec1/123"
+
+	g := &fragments.SortedGraph{}
+	g.AddTriple(
+		r.NewResource(subject),
+		r.NewResource("http://purl.org/dc/elements/1.1/title"),
+		r.NewLiteral("Boerderij"),
+	)
+
+	return &bulk.PostHookItem{
+		Graph:     g,
+		Subject:   subject,
+		OrgID:     "hub3",
+		DatasetID: "spec1",
+		HubID:     "hub3_spec1_123",
+	}
+}
+
+func TestNewPostHookJob_localID(t *testing.T) {
+	item := testPostHookItem()
+	item.HubID = "hub3_spec1_local_with_underscores"
+
+	job, err := NewPostHookJob(item)
+	if err != nil {
+		t.Fatalf("NewPostHookJob() unexpected error = %v", err)
+	}
+
+	if !strings.Contains(job.String(), `"local_with_underscores"`) {
+		t.Errorf("NewPostHookJob() localId should keep the underscores; got %s", job.String())
+	}
+}
+
+func TestPostHookJob_BytesFormat(t *testing.T) {
+	job, err := NewPostHookJob(testPostHookItem())
+	if err != nil {
+		t.Fatalf("NewPostHookJob() unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		contains string
+		wantErr  bool
+	}{
+		{"jsonld", FormatJSONLD, `"http://purl.org/dc/elements/1.1/title":[{"@value":"Boerderij"}]`, false},
+		{
+			"ntriples",
+			FormatNTriples,
+			`<http://data.hub3.org/resource/aggregation/spec1/123> <http://purl.org/dc/elements/1.1/title> "Boerderij"^^<http://www.w3.org/2001/XMLSchema#string> .`,
+			false,
+		},
+		{"turtle", FormatTurtle, `<http://purl.org/dc/elements/1.1/title> "Boerderij"`, false},
+		{"unknown format", "rdfxml", "", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := job.BytesFormat(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PostHookJob.BytesFormat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !strings.Contains(string(got), tt.contains) {
+				t.Errorf("PostHookJob.BytesFormat() = %s, should contain %s", got, tt.contains)
+			}
+		})
+	}
+
+	if job.String() != string(job.Bytes()) || !json.Valid(job.Bytes()) {
+		t.Errorf("PostHookJob.Bytes() should return the JSON-LD graph; got %s", job.Bytes())
+	}
+}
+
+func TestPostHook_Publish_format(t *testing.T) {
+	tests := []struct {
+		name            string
+		format          string
+		wantContentType string
+		wantBody        string
+	}{
+		{"default jsonld", "", "application/json-ld; charset=utf-8", `[[{`},
+		{"turtle", FormatTurtle, "text/turtle; charset=utf-8", `<http://purl.org/dc/elements/1.1/title> "Boerderij"`},
+		{"ntriples", FormatNTriples, "application/n-triples; charset=utf-8", `"Boerderij"^^<http://www.w3.org/2001/XMLSchema#string> .`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var contentType, body string
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				b, _ := ioutil.ReadAll(r.Body)
+				body = string(b)
+			}))
+			defer ts.Close()
+
+			options := []Option{}
+			if tt.format != "" {
+				options = append(options, SetFormat(tt.format))
+			}
+
+			ph, err := NewPostHook("hub3", ts.URL, "secret", options...)
+			if err != nil {
+				t.Fatalf("NewPostHook() unexpected error = %v", err)
+			}
+
+			if err := ph.Publish(context.Background(), testPostHookItem()); err != nil {
+				t.Fatalf("PostHook.Publish() unexpected error = %v", err)
+			}
+
+			if contentType != tt.wantContentType {
+				t.Errorf("PostHook.Publish() Content-Type = %s, want %s", contentType, tt.wantContentType)
+			}
+
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("PostHook.Publish() body = %s, should contain %s", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		payload string
+		want    string
+	}{
+		{
+			"body",
+			"It's a Secret to Everybody",
+			"Hello, World!",
+			"sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		},
+		{
+			"delete query",
+			"secret",
+			"api_key=key&collection=spec1&rev=2",
+			"sha256=8b03f2b1f8019829a6e0d0163a805fc2ccbd968dadc9902378582678d803a59a",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Signature(tt.secret, []byte(tt.payload)); got != tt.want {
+				t.Errorf("Signature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostHook_sign(t *testing.T) {
+	var postSignature, postBody, deleteSignature, deleteQuery string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			b, _ := ioutil.ReadAll(r.Body)
+			postBody = string(b)
+			postSignature = r.Header.Get(SignatureHeader)
+		case http.MethodDelete:
+			deleteQuery = r.URL.RawQuery
+			deleteSignature = r.Header.Get(SignatureHeader)
+		}
+	}))
+	defer ts.Close()
+
+	ph, err := NewPostHook("hub3", ts.URL, "key", SetSecret("secret"))
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	if err := ph.Publish(context.Background(), testPostHookItem()); err != nil {
+		t.Fatalf("PostHook.Publish() unexpected error = %v", err)
+	}
+
+	if want := Signature("secret", []byte(postBody)); postSignature != want {
+		t.Errorf("PostHook.Publish() signature = %s, want %s", postSignature, want)
+	}
+
+	resp, err := ph.DropDataset("spec1", 2)
+	if err != nil {
+		t.Fatalf("PostHook.DropDataset() unexpected error = %v", err)
+	}
+	resp.Body.Close()
+
+	if deleteQuery != "api_key=key&collection=spec1&rev=2" {
+		t.Errorf("PostHook.DropDataset() query = %s", deleteQuery)
+	}
+
+	if want := "sha256=8b03f2b1f8019829a6e0d0163a805fc2ccbd968dadc9902378582678d803a59a"; deleteSignature != want {
+		t.Errorf("PostHook.DropDataset() signature = %s, want %s", deleteSignature, want)
+	}
+}
+
+func TestPostHook_gzip(t *testing.T) {
+	tests := []struct {
+		name         string
+		gzip         bool
+		wantEncoding string
+	}{
+		{"gzip enabled", true, "gzip"},
+		{"gzip disabled", false, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				encoding, signature string
+				body                []byte
+			)
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				encoding = r.Header.Get("Content-Encoding")
+				signature = r.Header.Get(SignatureHeader)
+
+				reader := r.Body
+				if encoding == "gzip" {
+					zr, err := gzip.NewReader(r.Body)
+					if err != nil {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					defer zr.Close()
+
+					reader = zr
+				}
+
+				body, _ = ioutil.ReadAll(reader)
+			}))
+			defer ts.Close()
+
+			ph, err := NewPostHook("hub3", ts.URL, "key", SetSecret("secret"), SetGzip(tt.gzip), SetRetries(0))
+			if err != nil {
+				t.Fatalf("NewPostHook() unexpected error = %v", err)
+			}
+
+			payload := []byte(`[{"@id": "http://data.hub3.org/resource/aggregation/spec1/123"}]`)
+
+			if err := ph.PostWithContext(context.Background(), payload); err != nil {
+				t.Fatalf("PostHook.PostWithContext() unexpected error = %v", err)
+			}
+
+			if encoding != tt.wantEncoding {
+				t.Errorf("PostHook.PostWithContext() Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+
+			if string(body) != string(payload) {
+				t.Errorf("PostHook.PostWithContext() body = %s, want %s", body, payload)
+			}
+
+			// the signature is calculated on the uncompressed payload
+			if want := Signature("secret", payload); signature != want {
+				t.Errorf("PostHook.PostWithContext() signature = %s, want %s", signature, want)
+			}
+		})
+	}
+}
+
+func TestPostHook_Publish_deleted(t *testing.T) {
+	var queries []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			queries = append(queries, r.URL.RawQuery)
+		}
+	}))
+	defer ts.Close()
+
+	ph, err := NewPostHook("hub3", ts.URL, "key")
+	if err != nil {
+		t.Fatalf("NewPostHook() unexpected error = %v", err)
+	}
+
+	err = ph.Publish(
+		context.Background(),
+		&bulk.PostHookItem{Deleted: true, DatasetID: "spec1", HubID: "hub3_spec1_123"},
+		&bulk.PostHookItem{Deleted: true, DatasetID: "spec1", Revision: 2},
+		&bulk.PostHookItem{Deleted: true, DatasetID: "spec1", Revision: 2},
+		&bulk.PostHookItem{Deleted: true, DatasetID: "spec2", HubID: "hub3_spec2_1"},
+		&bulk.PostHookItem{Deleted: true, DatasetID: "spec1", HubID: "hub3_spec1_456"},
+	)
+	if err != nil {
+		t.Fatalf("PostHook.Publish() unexpected error = %v", err)
+	}
+
+	// the deleted records are dropped with a single request per dataset
+	want := []string{
+		"api_key=key&collection=spec1&rev=2",
+		"api_key=key&collection=spec1&hubID=hub3_spec1_123&hubID=hub3_spec1_456",
+		"api_key=key&collection=spec2&hubID=hub3_spec2_1",
+	}
+
+	if strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("PostHook.Publish() delete queries = %v, want %v", queries, want)
+	}
+}
+
+func TestPostHook_Publish_deleteStatusCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		status  int
+		wantErr bool
+	}{
+		{"no content", nil, http.StatusNoContent, false},
+		{"already deleted", nil, http.StatusNotFound, false},
+		{"gone", nil, http.StatusGone, false},
+		{"server error", nil, http.StatusInternalServerError, true},
+		{"configured status codes", []Option{SetDeleteStatusCodes(http.StatusNoContent)}, http.StatusNotFound, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			ph, err := NewPostHook("hub3", ts.URL, "key", tt.options...)
+			if err != nil {
+				t.Fatalf("NewPostHook() unexpected error = %v", err)
+			}
+
+			items := []*bulk.PostHookItem{
+				{Deleted: true, DatasetID: "spec1", HubID: "hub3_spec1_123"},
+				{Deleted: true, DatasetID: "spec1", Revision: 2},
+			}
+
+			for _, item := range items {
+				err = ph.Publish(context.Background(), item)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("PostHook.Publish() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPostHook_invalidOptions(t *testing.T) {
+	options := []Option{
+		SetRetries(-1),
+		SetRetryDelay(0),
+		SetFormat("rdfxml"),
+	}
+
+	for _, option := range options {
+		if _, err := NewPostHook("hub3", "http://localhost", "", option); err == nil {
+			t.Errorf("NewPostHook() expected an error for an invalid option")
+		}
+	}
+}
+
 // . "github.com/onsi/ginkgo"
 // . "github.com/onsi/gomega"
 
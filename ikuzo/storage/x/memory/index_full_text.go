@@ -433,6 +433,8 @@ func (ti *TextIndex) search(query *search.QueryTerm, hits *search.Matches) error
 	return nil
 }
 
+// Encode writes the TextIndex as GOB to w. The configuration of the analyzer
+// is written after the index, so it can be restored by DecodeTextIndex.
 func (ti *TextIndex) Encode(w io.Writer) error {
 	e := gob.NewEncoder(w)
 
@@ -441,9 +443,16 @@ func (ti *TextIndex) Encode(w io.Writer) error {
 		return fmt.Errorf("unable to marshall TextIndex to GOB; %w", err)
 	}
 
+	err = e.Encode(ti.a.Config())
+	if err != nil {
+		return fmt.Errorf("unable to marshall TextIndex analyzer to GOB; %w", err)
+	}
+
 	return nil
 }
 
+// DecodeTextIndex reads a TextIndex that is written by Encode. The zero value
+// analyzer is used for input that was written without its configuration.
 func DecodeTextIndex(r io.Reader) (*TextIndex, error) {
 	var ti TextIndex
 
@@ -454,5 +463,19 @@ func DecodeTextIndex(r io.Reader) (*TextIndex, error) {
 		return nil, err
 	}
 
+	var cfg search.AnalyzerConfig
+
+	err = d.Decode(&cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to decode TextIndex analyzer; %w", err)
+	}
+
+	a, err := search.NewAnalyzerFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to restore TextIndex analyzer; %w", err)
+	}
+
+	ti.a = *a
+
 	return &ti, nil
 }
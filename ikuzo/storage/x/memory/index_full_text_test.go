@@ -17,6 +17,7 @@ package memory
 
 import (
 	"bytes"
+	"encoding/gob"
 	"errors"
 	"testing"
 
@@ -798,11 +799,56 @@ func TestTextIndexSerialization(t *testing.T) {
 	newTi, err := DecodeTextIndex(&buf)
 	is.NoErr(err)
 
-	if diff := cmp.Diff(ti, newTi, cmp.AllowUnexported(TextIndex{}, search.Vector{})); diff != "" {
+	if diff := cmp.Diff(ti, newTi, textIndexCmpOptions...); diff != "" {
 		t.Errorf("TextIndex serialization = mismatch (-want +got):\n%s", diff)
 	}
 }
 
+// textIndexCmpOptions compare the analyzer of a TextIndex by its configuration.
+var textIndexCmpOptions = []cmp.Option{
+	cmp.AllowUnexported(TextIndex{}, search.Vector{}),
+	cmp.Transformer("AnalyzerConfig", func(a search.Analyzer) search.AnalyzerConfig {
+		return a.Config()
+	}),
+}
+
+func TestTextIndexSerialization_analyzer(t *testing.T) {
+	is := is.New(t)
+
+	a, err := search.NewAnalyzer(
+		search.WithStopwords([]string{"de"}),
+		search.WithStemmer("dutch"),
+	)
+	is.NoErr(err)
+
+	ti := NewTextIndex()
+	ti.a = *a
+
+	err = ti.AppendString("De kaarten van de archieven")
+	is.NoErr(err)
+
+	var buf bytes.Buffer
+
+	err = ti.Encode(&buf)
+	is.NoErr(err)
+
+	newTi, err := DecodeTextIndex(&buf)
+	is.NoErr(err)
+	is.Equal(newTi.a.Config(), a.Config())
+
+	if diff := cmp.Diff(ti, newTi, textIndexCmpOptions...); diff != "" {
+		t.Errorf("TextIndex serialization = mismatch (-want +got):\n%s", diff)
+	}
+
+	// an index that was encoded without the analyzer uses the zero value
+	buf.Reset()
+	is.NoErr(gob.NewEncoder(&buf).Encode(ti))
+
+	newTi, err = DecodeTextIndex(&buf)
+	is.NoErr(err)
+	is.Equal(newTi.a.Config(), search.AnalyzerConfig{})
+}
+
 func TestTextIndex_setDocID(t *testing.T) {
 	type args struct {
 		docID []int
@@ -16,14 +16,18 @@ package ikuzo
 
 import (
 	"context"
+	"fmt"
 	"sync"
 )
 
 // WorkerService is the interface for background processes.
-// The service needs to gracefully shutdown all goroutines when the context is
-// canceled.
+//
+// Start is called once when the Server starts listening. The service must stop
+// all its goroutines when the context is canceled. Shutdown is called during
+// the graceful shutdown of the Server and must return before its context is
+// done.
 type WorkerService interface {
-	Start(ctx context.Context, wg *sync.WaitGroup)
+	Start(ctx context.Context) error
 	Shutdown
 }
 
@@ -32,6 +36,8 @@ type workerPool struct {
 	ctx context.Context
 	// wg tracks the number of goroutines
 	wg *sync.WaitGroup
+	// services are the registered WorkerServices
+	services []WorkerService
 }
 
 func newWorkerPool(ctx context.Context) *workerPool {
@@ -40,3 +46,19 @@ func newWorkerPool(ctx context.Context) *workerPool {
 		wg:  &sync.WaitGroup{},
 	}
 }
+
+// register adds the WorkerServices to the pool.
+func (wp *workerPool) register(services ...WorkerService) {
+	wp.services = append(wp.services, services...)
+}
+
+// start starts all registered WorkerServices with the context of the pool.
+func (wp *workerPool) start() error {
+	for _, svc := range wp.services {
+		if err := svc.Start(wp.ctx); err != nil {
+			return fmt.Errorf("unable to start worker service %T; %w", svc, err)
+		}
+	}
+
+	return nil
+}